@@ -2,13 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/frquxl/go-atelier/pkg/engine"
 	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/traverse"
 	"github.com/frquxl/go-atelier/pkg/util"
 	"github.com/spf13/cobra"
 )
@@ -22,11 +24,19 @@ var artistCmd = &cobra.Command{
 var artistInitCmd = &cobra.Command{
 	Use:   "init <artist-name>",
 	Short: "Initialize a new artist studio",
-	Long:  `Initialize a new artist studio within the existing atelier as a Git submodule.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Initialize a new artist studio within the existing atelier as a Git submodule.
+
+With --template the artist is generated from the named template pack; by default a pack named
+artist-<name> is used if there is one (e.g. artist-sketch), else artist-default. Packs are found in
+the atelier's templates/ directory, in ~/.config/atelier/templates and among the embedded ones; see
+'atelier template list'. Template parameters are set with --set key=value or asked for.
+
+With --forge, matching remote repositories are created for the artist (and its example canvas),
+pushed to as origin, and recorded in .gitmodules instead of relative ./artist-<name> URLs.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Check if we're in an atelier directory
-		if _, err := os.Stat(".atelier"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindAtelier) {
 			listAvailableAteliers()
 			return fmt.Errorf("not in an atelier directory. See available ateliers above")
 		}
@@ -45,7 +55,24 @@ var artistInitCmd = &cobra.Command{
 			return fmt.Errorf("could not get current working directory: %w", err)
 		}
 
-		if err = engine.CreateArtist(atelierPath, artistName, canvasName); err != nil {
+		f, err := forgeFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		opts, err := templateOptions(cmd)
+		if err != nil {
+			return err
+		}
+		uses := []engine.TemplateUse{{Template: opts.Template, Kind: marker.KindArtist, DirName: "artist-" + artistName}}
+		if canvasName != "" {
+			uses = append(uses, engine.TemplateUse{Kind: marker.KindCanvas, DirName: "canvas-" + canvasName})
+		}
+		if err := engine.CheckParams(atelierPath, opts.Params, uses...); err != nil {
+			return err
+		}
+		opts.Forge = f
+		if err = engine.CreateArtist(atelierPath, artistName, canvasName, opts); err != nil {
 			return err // Error is already formatted and cleanup is handled by the engine
 		}
 
@@ -61,7 +88,7 @@ var artistDeleteCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Check if we're in an atelier directory
-		if _, err := os.Stat(".atelier"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindAtelier) {
 			listAvailableAteliers()
 			return fmt.Errorf("not in an atelier directory. See available ateliers above")
 		}
@@ -76,6 +103,16 @@ var artistDeleteCmd = &cobra.Command{
 
 		artistPath := filepath.Join(atelierPath, artistFullName)
 
+		var opts engine.DeleteOptions
+		if deleteRemote, _ := cmd.Flags().GetBool("delete-remote"); deleteRemote {
+			if opts.Forge, err = forgeFromFlags(cmd); err != nil {
+				return err
+			}
+			if opts.Forge == nil {
+				return fmt.Errorf("--delete-remote needs a forge; pass --forge or set ATELIER_FORGE")
+			}
+		}
+
 		// Check for uncommitted changes in the artist
 		hasUncommitted, err := gitutil.IsPathDirty(atelierPath, artistFullName)
 		if err != nil {
@@ -90,7 +127,12 @@ var artistDeleteCmd = &cobra.Command{
 
 		// First confirmation prompt
 		confirmMessage := fmt.Sprintf("Are you sure you want to delete artist '%s'? This will delete the artist's directory and all its contents, and remove it from Git tracking.", artistFullName)
-		if !util.Confirm(confirmMessage) {
+		if opts.Forge != nil {
+			confirmMessage += fmt.Sprintf(" The remote repositories of the artist and its canvases on %s will be deleted as well.", opts.Forge.Name())
+		}
+		if ok, err := util.Confirm(confirmMessage); err != nil {
+			return fmt.Errorf("deletion of artist %s not confirmed: %w", artistFullName, err)
+		} else if !ok {
 			fmt.Println("Artist deletion cancelled.")
 			return nil
 		}
@@ -106,7 +148,7 @@ var artistDeleteCmd = &cobra.Command{
 				warnings = append(warnings, "unpushed commits")
 			}
 			warningMsg += strings.Join(warnings, " and ")
-			warningMsg += ". Deleting will permanently lose these changes."
+			warningMsg += ". Once the deletion leaves the operation log, these changes are lost for good."
 
 			fmt.Println(warningMsg)
 
@@ -116,13 +158,15 @@ var artistDeleteCmd = &cobra.Command{
 
 			// Second confirmation
 			confirmMessage2 := fmt.Sprintf("Are you absolutely sure you want to delete artist '%s' despite the %s?", artistFullName, strings.Join(warnings, " and "))
-			if !util.Confirm(confirmMessage2) {
+			if ok, err := util.Confirm(confirmMessage2); err != nil {
+				return fmt.Errorf("deletion of artist %s not confirmed: %w", artistFullName, err)
+			} else if !ok {
 				fmt.Println("Artist deletion cancelled.")
 				return nil
 			}
 		}
 
-		if err = engine.DeleteArtist(atelierPath, artistFullName); err != nil {
+		if err = engine.DeleteArtist(atelierPath, artistFullName, opts); err != nil {
 			return err
 		}
 
@@ -131,43 +175,110 @@ var artistDeleteCmd = &cobra.Command{
 	},
 }
 
+var artistCloneCmd = &cobra.Command{
+	Use:   "clone <source> [artist-name]",
+	Short: "Clone an artist from a Git URL or local path",
+	Long: `Clones an existing artist repository into the current atelier as a Git submodule, recording the
+source URL in .gitmodules and initializing its canvases. The .artist file is written or repaired to
+describe its place in this atelier. The artist name is derived from the source unless given.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		source := args[0]
+		var artistName string
+		if len(args) == 2 {
+			artistName = args[1]
+		}
+
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+
+		artistDirName, err := engine.CloneArtistFromSource(atelierPath, source, artistName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Artist '%s' cloned into atelier '%s' successfully!\n", artistDirName, filepath.Base(atelierPath))
+		return nil
+	},
+}
+
+var artistRenameCmd = &cobra.Command{
+	Use:   "rename <artist-full-name> <new-artist-name>",
+	Short: "Rename an artist in place",
+	Long:  `Renames an artist within the atelier, keeping its Git history. The directory, its .gitmodules entry, its Git directory and the .artist and .canvas files of the artist and all its canvases are updated and committed.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		artistFullName := args[0]
+		newArtistName := args[1]
+
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+
+		if err := engine.RenameArtist(atelierPath, artistFullName, newArtistName); err != nil {
+			return err
+		}
+
+		fmt.Printf("Artist '%s' renamed to '%s' successfully!\n", artistFullName, newArtistName)
+		return nil
+	},
+}
+
+var artistMoveCmd = &cobra.Command{
+	Use:   "move <artist-full-name> <path-to-other-atelier>",
+	Short: "Move an artist to another atelier",
+	Long:  `Moves an artist and all its canvases into another atelier, keeping their Git history. The artist is detached from the current atelier, registered in the target atelier, its .artist and .canvas files are rewritten and both ateliers commit the change.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		artistFullName := args[0]
+		targetAtelierPath := args[1]
+
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+		if targetAtelierPath, err = filepath.Abs(targetAtelierPath); err != nil {
+			return err
+		}
+
+		if err := engine.MoveArtistToAtelier(atelierPath, artistFullName, targetAtelierPath); err != nil {
+			return err
+		}
+
+		fmt.Printf("Artist '%s' moved to atelier '%s' successfully!\n", artistFullName, filepath.Base(targetAtelierPath))
+		return nil
+	},
+}
+
 var artistPushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push changes using the git push engine",
 	Long:  `Push changes at the artist level, recursing into all canvases.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check if we're in an artist directory
-		if _, err := os.Stat(".artist"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindArtist) {
 			return fmt.Errorf("not in an artist directory")
 		}
 
-		// Get the directory of the executable
-		execPath, err := os.Executable()
-		if err != nil {
-			return fmt.Errorf("could not get executable path: %w", err)
-		}
-		execDir := filepath.Dir(execPath)
-		scriptPath := filepath.Join(execDir, "pkg/push-engine/push-engine.sh")
+		return runPush(cmd)
+	},
+}
 
-		// Build command arguments
-		execArgs := []string{scriptPath}
-		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
-			execArgs = append(execArgs, "--dry-run")
-		}
-		if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
-			execArgs = append(execArgs, "--quiet")
-		}
-		if force, _ := cmd.Flags().GetBool("force"); force {
-			execArgs = append(execArgs, "--force")
+var artistSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Pull remote changes using the sync engine",
+	Long:  `Sync the artist and all of its canvases with their remotes.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in an artist directory
+		if !marker.Exists(".", marker.KindArtist) {
+			return fmt.Errorf("not in an artist directory")
 		}
 
-		// Execute the push engine
-		command := exec.Command("bash", execArgs...)
-		command.Stdout = os.Stdout
-		command.Stderr = os.Stderr
-		command.Env = append(os.Environ(), "ENGINE_ASSUME_YES=true", "AUTO_COMMIT_DEFAULT=true")
-
-		return command.Run()
+		return runSync(cmd)
 	},
 }
 
@@ -200,63 +311,70 @@ func listAvailableAteliers() {
 func showCanvasStatuses(atelierPath, artistFullName string) {
 	artistPath := filepath.Join(atelierPath, artistFullName)
 
-	entries, err := os.ReadDir(artistPath)
+	artist, err := traverse.Build(artistPath, marker.KindArtist)
 	if err != nil {
 		fmt.Printf("  Error reading artist directory: %v\n", err)
 		return
 	}
+	if len(artist.Children) == 0 {
+		fmt.Println("  No canvases found in this artist.")
+		return
+	}
 
-	hasCanvases := false
-	for _, entry := range entries {
-		if entry.IsDir() && strings.HasPrefix(entry.Name(), "canvas-") {
-			hasCanvases = true
-			canvasName := entry.Name()
-			canvasPath := filepath.Join(artistPath, canvasName)
-
-			// Check for uncommitted changes
-			hasUncommitted, err := gitutil.IsPathDirty(artistPath, canvasName)
-			if err != nil {
-				fmt.Printf("  %s: error checking status - %v\n", canvasName, err)
-				continue
-			}
+	opts := traverse.Options{Jobs: traverse.DefaultJobs, Out: os.Stdout}
+	traverse.Run(artist, opts, func(canvas *traverse.Repo, out io.Writer) error {
+		if canvas.Level != marker.KindCanvas {
+			return nil
+		}
+		canvasName := canvas.Name()
 
-			// Check for unpushed changes
-			hasUnpushed, err := gitutil.HasUnpushedCommits(canvasPath)
-			if err != nil {
-				fmt.Printf("  %s: error checking status - %v\n", canvasName, err)
-				continue
-			}
+		// Check for uncommitted changes
+		hasUncommitted, err := gitutil.IsPathDirty(artistPath, canvasName)
+		if err != nil {
+			fmt.Fprintf(out, "  %s: error checking status - %v\n", canvasName, err)
+			return err
+		}
 
-			// Build status message
-			statusParts := []string{}
-			if hasUncommitted {
-				statusParts = append(statusParts, "uncommitted changes")
-			}
-			if hasUnpushed {
-				statusParts = append(statusParts, "unpushed commits")
-			}
+		// Check for unpushed changes
+		hasUnpushed, err := gitutil.HasUnpushedCommits(canvas.Path)
+		if err != nil {
+			fmt.Fprintf(out, "  %s: error checking status - %v\n", canvasName, err)
+			return err
+		}
 
-			if len(statusParts) > 0 {
-				statusMsg := strings.Join(statusParts, " and ")
-				fmt.Printf("  %s: %s\n", canvasName, statusMsg)
-			} else {
-				fmt.Printf("  %s: clean\n", canvasName)
-			}
+		// Build status message
+		statusParts := []string{}
+		if hasUncommitted {
+			statusParts = append(statusParts, "uncommitted changes")
+		}
+		if hasUnpushed {
+			statusParts = append(statusParts, "unpushed commits")
 		}
-	}
 
-	if !hasCanvases {
-		fmt.Println("  No canvases found in this artist.")
-	}
+		if len(statusParts) > 0 {
+			statusMsg := strings.Join(statusParts, " and ")
+			fmt.Fprintf(out, "  %s: %s\n", canvasName, statusMsg)
+		} else {
+			fmt.Fprintf(out, "  %s: clean\n", canvasName)
+		}
+		return nil
+	})
 }
 
 func init() {
-	artistPushCmd.Flags().Bool("dry-run", false, "Show what would be pushed without pushing")
-	artistPushCmd.Flags().Bool("quiet", false, "Suppress verbose output")
-	artistPushCmd.Flags().Bool("force", false, "Force push (use with caution)")
+	addPushFlags(artistPushCmd)
+	addSyncFlags(artistSyncCmd)
+	addProvisionFlags(artistInitCmd)
+	addForgeFlags(artistDeleteCmd)
+	artistDeleteCmd.Flags().Bool("delete-remote", false, "Also delete the remote repositories of the artist and its canvases on the forge")
 	artistInitCmd.Flags().Bool("with-canvas", false, "Create a default example canvas with the artist")
+	addTemplateFlags(artistInitCmd, "Template pack for the artist (default: artist-<name> if it exists, else artist-default)")
 	RootCmd.AddCommand(artistCmd)
 	artistCmd.AddCommand(artistInitCmd)
 	artistCmd.AddCommand(artistDeleteCmd)
+	artistCmd.AddCommand(artistCloneCmd)
+	artistCmd.AddCommand(artistRenameCmd)
+	artistCmd.AddCommand(artistMoveCmd)
 	artistCmd.AddCommand(artistPushCmd)
+	artistCmd.AddCommand(artistSyncCmd)
 }
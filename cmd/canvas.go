@@ -3,12 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/frquxl/go-atelier/pkg/engine"
 	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/templates"
 	"github.com/frquxl/go-atelier/pkg/util"
 	"github.com/spf13/cobra"
 )
@@ -22,11 +23,19 @@ var canvasCmd = &cobra.Command{
 var canvasInitCmd = &cobra.Command{
 	Use:   "init <canvas-name>",
 	Short: "Initialize a new canvas",
-	Long:  `Initialize a new canvas within the current artist workspace as a Git submodule. Must be run from an artist directory.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Initialize a new canvas within the current artist workspace as a Git submodule. Must be run from an artist directory.
+
+With --template the canvas is generated from the named template pack; by default a pack named
+canvas-<name> is used if there is one, else canvas. Packs are found in the atelier's templates/
+directory, in ~/.config/atelier/templates and among the embedded ones; see 'atelier template list'.
+Template parameters are set with --set key=value or asked for.
+
+With --forge, a matching remote repository is created first, the canvas is pushed to it as origin,
+and the remote URL is recorded in .gitmodules instead of the relative ./canvas-<name> URL.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Check if we're in an artist directory
-		if _, err := os.Stat(".artist"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindArtist) {
 			listAvailableArtists()
 			return fmt.Errorf("not in an artist directory. See available artists above")
 		}
@@ -39,7 +48,25 @@ var canvasInitCmd = &cobra.Command{
 			return fmt.Errorf("could not get current working directory: %w", err)
 		}
 
-		if err = engine.CreateCanvas(artistPath, canvasName); err != nil {
+		f, err := forgeFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		opts, err := templateOptions(cmd)
+		if err != nil {
+			return err
+		}
+		atelierPath, err := marker.FindRoot(artistPath)
+		if err != nil {
+			return err
+		}
+		use := engine.TemplateUse{Template: opts.Template, Kind: marker.KindCanvas, DirName: "canvas-" + canvasName}
+		if err := engine.CheckParams(atelierPath, opts.Params, use); err != nil {
+			return err
+		}
+		opts.Forge = f
+		if err = engine.CreateCanvas(artistPath, canvasName, opts); err != nil {
 			return err // Error is already formatted and cleanup is handled by the engine
 		}
 
@@ -55,7 +82,7 @@ var canvasDeleteCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Check if we're in an artist directory
-		if _, err := os.Stat(".artist"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindArtist) {
 			listAvailableArtists()
 			return fmt.Errorf("not in an artist directory. See available artists above")
 		}
@@ -70,6 +97,16 @@ var canvasDeleteCmd = &cobra.Command{
 
 		canvasPath := filepath.Join(artistPath, canvasFullName)
 
+		var opts engine.DeleteOptions
+		if deleteRemote, _ := cmd.Flags().GetBool("delete-remote"); deleteRemote {
+			if opts.Forge, err = forgeFromFlags(cmd); err != nil {
+				return err
+			}
+			if opts.Forge == nil {
+				return fmt.Errorf("--delete-remote needs a forge; pass --forge or set ATELIER_FORGE")
+			}
+		}
+
 		// Check for uncommitted changes in the canvas
 		hasUncommitted, err := gitutil.IsPathDirty(artistPath, canvasFullName)
 		if err != nil {
@@ -84,7 +121,12 @@ var canvasDeleteCmd = &cobra.Command{
 
 		// First confirmation prompt
 		confirmMessage := fmt.Sprintf("Are you sure you want to delete canvas '%s'? This will delete the canvas's directory and all its contents, and remove it from Git tracking.", canvasFullName)
-		if !util.Confirm(confirmMessage) {
+		if opts.Forge != nil {
+			confirmMessage += fmt.Sprintf(" Its remote repository on %s will be deleted as well.", opts.Forge.Name())
+		}
+		if ok, err := util.Confirm(confirmMessage); err != nil {
+			return fmt.Errorf("deletion of canvas %s not confirmed: %w", canvasFullName, err)
+		} else if !ok {
 			fmt.Println("Canvas deletion cancelled.")
 			return nil
 		}
@@ -100,19 +142,21 @@ var canvasDeleteCmd = &cobra.Command{
 				warnings = append(warnings, "unpushed commits")
 			}
 			warningMsg += strings.Join(warnings, " and ")
-			warningMsg += ". Deleting will permanently lose these changes."
+			warningMsg += ". Once the deletion leaves the operation log, these changes are lost for good."
 
 			fmt.Println(warningMsg)
 
 			// Second confirmation
 			confirmMessage2 := fmt.Sprintf("Are you absolutely sure you want to delete canvas '%s' despite the %s?", canvasFullName, strings.Join(warnings, " and "))
-			if !util.Confirm(confirmMessage2) {
+			if ok, err := util.Confirm(confirmMessage2); err != nil {
+				return fmt.Errorf("deletion of canvas %s not confirmed: %w", canvasFullName, err)
+			} else if !ok {
 				fmt.Println("Canvas deletion cancelled.")
 				return nil
 			}
 		}
 
-		if err = engine.DeleteCanvas(artistPath, canvasFullName); err != nil {
+		if err = engine.DeleteCanvas(artistPath, canvasFullName, opts); err != nil {
 			return err
 		}
 
@@ -122,15 +166,36 @@ var canvasDeleteCmd = &cobra.Command{
 }
 
 var canvasMoveCmd = &cobra.Command{
-	Use:   "move <canvas-full-name> <new-artist-full-name>",
+	Use:   "move <canvas-full-name> <new-artist-full-name|path-to-artist>",
 	Short: "Move a canvas from one artist to another.",
-	Long:  `Moves a canvas from its current artist to another, updating Git submodules and internal paths accordingly.`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Moves a canvas from its current artist to another, updating Git submodules and internal paths accordingly.
+
+If the second argument is a path (e.g. ../atelier-other/artist-monet), the canvas is moved into that artist
+of another atelier: it is detached from its current artist, registered in the target artist, its .canvas file
+is rewritten and both artists and both ateliers commit the change.`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		canvasFullName := args[0]
 		newArtistFullName := args[1]
 
-		if err := engine.MoveCanvas(canvasFullName, newArtistFullName); err != nil {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+
+		if strings.ContainsRune(newArtistFullName, os.PathSeparator) {
+			targetArtistPath, err := filepath.Abs(newArtistFullName)
+			if err != nil {
+				return err
+			}
+			if err := engine.MoveCanvasToArtist(atelierPath, canvasFullName, targetArtistPath); err != nil {
+				return err
+			}
+			fmt.Printf("Canvas '%s' moved to '%s' successfully!\n", canvasFullName, newArtistFullName)
+			return nil
+		}
+
+		if err := engine.MoveCanvas(atelierPath, canvasFullName, newArtistFullName); err != nil {
 			return err
 		}
 
@@ -140,10 +205,14 @@ var canvasMoveCmd = &cobra.Command{
 }
 
 var canvasCloneCmd = &cobra.Command{
-	Use:   "clone <canvas-full-name> <target-artist-full-name> [new-canvas-name]",
-	Short: "Clone a canvas to another artist.",
-	Long:  `Clones a canvas from its current artist to another artist, creating a copy with proper Git submodule relationships. Optionally specify a new name for the cloned canvas.`,
-	Args:  cobra.RangeArgs(2, 3),
+	Use:   "clone <canvas-full-name|source> <target-artist-full-name> [new-canvas-name]",
+	Short: "Clone a canvas to another artist, or from a Git URL or local path.",
+	Long: `Clones a canvas from its current artist to another artist, creating a copy with proper Git submodule relationships. Optionally specify a new name for the cloned canvas.
+
+If the first argument is a Git URL (https://, ssh://, git@host:path) or a filesystem path (/, ./, ../, ~),
+the repository is cloned into the target artist as a submodule with that URL recorded in .gitmodules,
+and its .canvas file is written or repaired. The canvas name is derived from the source unless given.`,
+	Args: cobra.RangeArgs(2, 3),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		canvasFullName := args[0]
 		targetArtistFullName := args[1]
@@ -153,7 +222,25 @@ var canvasCloneCmd = &cobra.Command{
 			newCanvasName = args[2]
 		}
 
-		if err := engine.CloneCanvas(canvasFullName, targetArtistFullName, newCanvasName); err != nil {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+
+		if engine.IsRepoSource(canvasFullName) {
+			targetArtistPath := filepath.Join(atelierPath, targetArtistFullName)
+			if !marker.Exists(targetArtistPath, marker.KindArtist) {
+				return fmt.Errorf("target artist %s does not exist", targetArtistFullName)
+			}
+			canvasDirName, err := engine.CloneCanvasFromSource(targetArtistPath, canvasFullName, newCanvasName)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Canvas '%s' cloned into artist '%s' successfully!\n", canvasDirName, targetArtistFullName)
+			return nil
+		}
+
+		if err := engine.CloneCanvas(atelierPath, canvasFullName, targetArtistFullName, newCanvasName); err != nil {
 			return err
 		}
 
@@ -166,43 +253,93 @@ var canvasCloneCmd = &cobra.Command{
 	},
 }
 
+var canvasRenameCmd = &cobra.Command{
+	Use:   "rename <canvas-full-name> <new-canvas-name>",
+	Short: "Rename a canvas in place.",
+	Long:  `Renames a canvas within its artist, keeping its Git history. The directory, its .gitmodules entry, its Git directory and its .canvas file are updated and the change is committed in the artist and the atelier.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		canvasFullName := args[0]
+		newCanvasName := args[1]
+
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+
+		if err := engine.RenameCanvas(atelierPath, canvasFullName, newCanvasName); err != nil {
+			return err
+		}
+
+		fmt.Printf("Canvas '%s' renamed to '%s' successfully!\n", canvasFullName, newCanvasName)
+		return nil
+	},
+}
+
+var canvasSaveTemplateCmd = &cobra.Command{
+	Use:   "save-template <canvas-full-name> <template-name>",
+	Short: "Save a canvas as a template pack to create new canvases from.",
+	Long: `Saves the files git tracks in the canvas as the template pack <template-name> in the user's template
+directory, $XDG_CONFIG_HOME/atelier/templates or ~/.config/atelier/templates. Files matched by the
+canvas's .gitignore, its .canvas marker, git metadata and the agent files the CLI writes are left out.
+
+The directory names of the canvas, its artist and its atelier in the files and paths are replaced
+with {{ .Canvas }}, {{ .Artist }} and {{ .Atelier }}, so new canvases get their own names; a name
+that is part of a longer one, as in canvas-api-v2, is kept. With --bare-name the canvas name without
+prefix, e.g. api, is replaced with {{ .Name }} too. Binary files are copied as they are. Review the pack, e.g. with 'atelier template render <template-name>', then create
+canvases from it:
+
+  atelier canvas save-template canvas-api service
+  atelier canvas init billing --template service`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return fmt.Errorf("could not find atelier root: %w", err)
+		}
+		var opts templates.SaveOptions
+		opts.Force, _ = cmd.Flags().GetBool("force")
+		opts.BareName, _ = cmd.Flags().GetBool("bare-name")
+		pack, err := engine.SaveCanvasTemplate(atelierPath, args[0], args[1], opts)
+		if err != nil {
+			return err
+		}
+		manifest, err := pack.Manifest()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved %d files of %s as template '%s' in %s.\n", len(manifest.Files), args[0], pack.Name, pack.Dir)
+		fmt.Printf("Create a canvas from it with 'atelier canvas init <canvas-name> --template %s'.\n", pack.Name)
+		return nil
+	},
+}
+
 var canvasPushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push changes using the git push engine",
 	Long:  `Push changes at the canvas level.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check if we're in a canvas directory
-		if _, err := os.Stat(".canvas"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindCanvas) {
 			return fmt.Errorf("not in a canvas directory")
 		}
 
-		// Get the directory of the executable
-		execPath, err := os.Executable()
-		if err != nil {
-			return fmt.Errorf("could not get executable path: %w", err)
-		}
-		execDir := filepath.Dir(execPath)
-		scriptPath := filepath.Join(execDir, "pkg/push-engine/push-engine.sh")
+		return runPush(cmd)
+	},
+}
 
-		// Build command arguments
-		execArgs := []string{scriptPath}
-		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
-			execArgs = append(execArgs, "--dry-run")
-		}
-		if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
-			execArgs = append(execArgs, "--quiet")
-		}
-		if force, _ := cmd.Flags().GetBool("force"); force {
-			execArgs = append(execArgs, "--force")
+var canvasSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Pull remote changes using the sync engine",
+	Long:  `Sync the canvas with its remote.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in a canvas directory
+		if !marker.Exists(".", marker.KindCanvas) {
+			return fmt.Errorf("not in a canvas directory")
 		}
 
-		// Execute the push engine
-		command := exec.Command("bash", execArgs...)
-		command.Stdout = os.Stdout
-		command.Stderr = os.Stderr
-		command.Env = append(os.Environ(), "ENGINE_ASSUME_YES=true", "AUTO_COMMIT_DEFAULT=true")
-
-		return command.Run()
+		return runSync(cmd)
 	},
 }
 
@@ -233,13 +370,21 @@ func listAvailableArtists() {
 }
 
 func init() {
-	canvasPushCmd.Flags().Bool("dry-run", false, "Show what would be pushed without pushing")
-	canvasPushCmd.Flags().Bool("quiet", false, "Suppress verbose output")
-	canvasPushCmd.Flags().Bool("force", false, "Force push (use with caution)")
+	addPushFlags(canvasPushCmd)
+	addSyncFlags(canvasSyncCmd)
+	addProvisionFlags(canvasInitCmd)
+	addTemplateFlags(canvasInitCmd, "Template pack for the canvas (default: canvas-<name> if it exists, else canvas)")
+	addForgeFlags(canvasDeleteCmd)
+	canvasDeleteCmd.Flags().Bool("delete-remote", false, "Also delete the canvas's remote repository on the forge")
 	RootCmd.AddCommand(canvasCmd)
 	canvasCmd.AddCommand(canvasInitCmd)
 	canvasCmd.AddCommand(canvasDeleteCmd)
 	canvasCmd.AddCommand(canvasPushCmd)
+	canvasCmd.AddCommand(canvasSyncCmd)
 	canvasCmd.AddCommand(canvasMoveCmd)
 	canvasCmd.AddCommand(canvasCloneCmd)
+	canvasCmd.AddCommand(canvasRenameCmd)
+	canvasSaveTemplateCmd.Flags().Bool("force", false, "Replace an existing template of the same name")
+	canvasSaveTemplateCmd.Flags().Bool("bare-name", false, "Also replace the canvas name without its prefix with {{ .Name }}")
+	canvasCmd.AddCommand(canvasSaveTemplateCmd)
 }
\ No newline at end of file
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/contextmerge"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+	"github.com/spf13/cobra"
+)
+
+// preCommitHook is the pre-commit hook installed by 'check layout --install-hook' and 'guard install'.
+// The hook is skipped when the CLI is not on the PATH, e.g. for collaborators who do not use it.
+const preCommitHook = `#!/bin/sh
+# Installed by atelier-cli: blocks commits that add secrets or change protected template sections.
+command -v atelier-cli >/dev/null 2>&1 || exit 0
+atelier-cli guard scan || exit 1
+exec atelier-cli check layout --hook
+`
+
+// preCommitHookSignature identifies hooks installed by the CLI, which may be overwritten.
+const preCommitHookSignature = "# Installed by atelier-cli"
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the atelier for problems",
+}
+
+var checkLayoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Detect changes to protected template sections of README.md, AGENTS.md and Makefile",
+	Long: `Checks the README.md, AGENTS.md and Makefile of the atelier, its artists and their canvases for
+changes to the sections the templates declare as protected, e.g. '<!-- atelier:begin structure protected -->'
+in Markdown or '# atelier:begin help protected' in a Makefile. A protected section drifts when it matches
+neither the current template nor the version recorded when the file was generated; the command lists
+the drift and fails if there is any.
+
+With --fix the drifted sections are restored from the template; text outside them is kept.
+With --install-hook a git pre-commit hook is installed in every repository that blocks commits
+introducing drift or secrets (bypass it with 'git commit --no-verify').
+Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if hook, _ := cmd.Flags().GetBool("hook"); hook {
+			return runLayoutHook()
+		}
+
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+		tree, err := traverse.Build(atelierPath, marker.KindAtelier)
+		if err != nil {
+			return err
+		}
+		fix, _ := cmd.Flags().GetBool("fix")
+		installHook, _ := cmd.Flags().GetBool("install-hook")
+
+		drifted := 0
+		results := traverse.Run(tree, traverse.Options{Jobs: 1, Out: os.Stdout}, func(repo *traverse.Repo, out io.Writer) error {
+			name, _ := filepath.Rel(atelierPath, repo.Path)
+			if name == "." {
+				name = repo.Name()
+			}
+			if installHook {
+				installed, err := installPreCommitHook(repo.Path)
+				switch {
+				case err != nil:
+					fmt.Fprintf(out, "%s: failed to install hook: %v\n", name, err)
+					return err
+				case installed:
+					fmt.Fprintf(out, "%s: pre-commit hook installed\n", name)
+				default:
+					fmt.Fprintf(out, "%s: has its own pre-commit hook, skipped; add 'atelier-cli check layout --hook' to it\n", name)
+				}
+				return nil
+			}
+
+			if fix {
+				fixed, err := contextmerge.FixLayout(repo.Path, repo.Level)
+				for _, d := range fixed {
+					fmt.Fprintf(out, "%s/%s: restored\n", name, d)
+				}
+				return err
+			}
+			drifts, err := contextmerge.CheckLayout(repo.Path, repo.Level, nil)
+			for _, d := range drifts {
+				drifted++
+				fmt.Fprintf(out, "%s/%s\n", name, d)
+			}
+			return err
+		})
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+				if !installHook {
+					fmt.Printf("%s: %v\n", result.Repo.Name(), result.Err)
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d repositories could not be checked", failed)
+		}
+		if drifted > 0 {
+			return fmt.Errorf("%d protected sections drifted from the templates; run 'atelier check layout --fix' to restore them", drifted)
+		}
+		if !fix && !installHook {
+			fmt.Println("Protected sections match the templates.")
+		}
+		return nil
+	},
+}
+
+// runLayoutHook checks the staged files of the repository in the current directory, where git runs
+// hooks, and fails if a protected section drifted.
+func runLayoutHook() error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	kind, ok := marker.Detect(dir)
+	if !ok {
+		return nil
+	}
+	drifts, err := contextmerge.CheckLayout(dir, kind, func(file string) ([]byte, error) {
+		return gitutil.StagedFile(dir, file)
+	})
+	if err != nil || len(drifts) == 0 {
+		return err
+	}
+	for _, d := range drifts {
+		fmt.Fprintf(os.Stderr, "  %s\n", d)
+	}
+	return fmt.Errorf("commit blocked: protected template sections were changed; restore them with 'atelier check layout --fix' or commit with --no-verify")
+}
+
+// installPreCommitHook installs the pre-commit hook in the repository at dir. It reports false and
+// leaves the hook alone if the repository already has a pre-commit hook not installed by the CLI.
+func installPreCommitHook(dir string) (bool, error) {
+	path, err := gitutil.HookPath(dir, "pre-commit")
+	if err != nil {
+		return false, err
+	}
+	if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), preCommitHookSignature) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(preCommitHook), 0755); err != nil {
+		return false, fmt.Errorf("failed to write hook %s: %w", path, err)
+	}
+	return true, nil
+}
+
+func init() {
+	checkLayoutCmd.Flags().Bool("fix", false, "Restore drifted protected sections from the templates")
+	checkLayoutCmd.Flags().Bool("install-hook", false, "Install a git pre-commit hook that blocks drift in every repository")
+	checkLayoutCmd.Flags().Bool("hook", false, "Check the staged files of the current repository (used by the pre-commit hook)")
+	checkLayoutCmd.Flags().MarkHidden("hook")
+	checkLayoutCmd.MarkFlagsMutuallyExclusive("fix", "install-hook", "hook")
+	checkCmd.AddCommand(checkLayoutCmd)
+	RootCmd.AddCommand(checkCmd)
+}
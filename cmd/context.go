@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/contextmerge"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+	"github.com/spf13/cobra"
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the files generated from the templates (AGENTS.md, README.md, Makefile)",
+}
+
+var contextPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Merge template updates into AGENTS.md, README.md and Makefile across the atelier",
+	Long: `Updates the AGENTS.md, README.md and Makefile of the atelier, its artists and their canvases to
+the templates embedded in this version of the CLI.
+
+The templates mark the text they own with '<!-- atelier:begin <id> -->' and
+'<!-- atelier:end <id> -->' ('# atelier:begin <id>' in Makefiles). Each marked section is merged three ways against the version recorded in
+the marker file when the file was generated or last pulled:
+  - sections you did not edit are replaced by the template's version;
+  - sections you edited are kept if the template did not change them;
+  - sections changed on both sides get conflict markers to resolve by hand.
+Text outside the markers is never touched, and missing files are created from the template.
+The changes are left uncommitted; review them and commit, e.g. with 'atelier push'.
+Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+		tree, err := traverse.Build(atelierPath, marker.KindAtelier)
+		if err != nil {
+			return err
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		conflicts, changed := 0, 0
+		results := traverse.Run(tree, traverse.Options{Jobs: 1, Out: os.Stdout}, func(repo *traverse.Repo, out io.Writer) error {
+			name, _ := filepath.Rel(atelierPath, repo.Path)
+			if name == "." {
+				name = repo.Name()
+			}
+			files, err := contextmerge.Pull(repo.Path, repo.Level, dryRun)
+			for _, file := range files {
+				switch {
+				case file.Created:
+					changed++
+					fmt.Fprintf(out, "%s/%s: created from template\n", name, file.File)
+				case file.Skipped != "":
+					fmt.Fprintf(out, "%s/%s: skipped, %s\n", name, file.File, file.Skipped)
+				case file.Changed() || len(file.Kept)+len(file.Unmerged) > 0:
+					if file.Changed() {
+						changed++
+					}
+					conflicts += len(file.Conflicts) + len(file.Unmerged)
+					fmt.Fprintf(out, "%s/%s: %s\n", name, file.File, describeMerge(file.Result))
+				}
+			}
+			if err != nil {
+				fmt.Fprintf(out, "%s: failed: %v\n", name, err)
+			}
+			return err
+		})
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+		}
+		switch {
+		case changed == 0 && failed == 0 && conflicts == 0:
+			fmt.Println("Context files are up to date with the templates.")
+		case dryRun:
+			fmt.Printf("Dry run: %d files would change. Nothing was written.\n", changed)
+		case changed > 0:
+			fmt.Printf("%d files updated. Review the changes and commit them, e.g. with 'atelier push'.\n", changed)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d repositories failed to update", failed)
+		}
+		if conflicts > 0 && !dryRun {
+			return fmt.Errorf("%d sections have conflicts; resolve the <<<<<<< markers by hand", conflicts)
+		}
+		return nil
+	},
+}
+
+// describeMerge summarizes the sections a merge touched.
+func describeMerge(r *contextmerge.Result) string {
+	var parts []string
+	add := func(label string, ids []string) {
+		if len(ids) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", label, strings.Join(ids, ", ")))
+		}
+	}
+	add("updated", r.Updated)
+	add("added", r.Added)
+	add("removed", r.Removed)
+	add("kept local", r.Kept)
+	add("CONFLICT in", r.Conflicts)
+	add("unresolved conflict in", r.Unmerged)
+	return strings.Join(parts, "; ")
+}
+
+func init() {
+	contextPullCmd.Flags().Bool("dry-run", false, "Show what would change without writing any file")
+	contextCmd.AddCommand(contextPullCmd)
+	RootCmd.AddCommand(contextCmd)
+}
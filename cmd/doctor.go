@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/frquxl/go-atelier/pkg/doctor"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the environment and the consistency of the current atelier",
+	Long: `Checks the environment the CLI runs in: the git version and identity, the optional tools
+(make, gh, task-master), the CLI on the PATH and the template assets built into it.
+
+When run within an atelier, it then audits the atelier, its artists and their canvases:
+  - markers that are missing, in the legacy format or name other directories;
+  - .gitmodules entries without a directory or without a submodule in the index;
+  - submodules that are not checked out;
+  - artist and canvas directories that are not registered as submodules;
+  - orphaned git directories under .git/modules (those kept for 'atelier undo' excepted).
+
+Every problem is listed with a suggested fix. With --fix the fixes that can be applied safely are
+applied and the atelier is audited again; the repaired files are staged, not committed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		results := doctor.Environment()
+		printDoctorResults(results)
+
+		atelierPath, err := currentAtelierRoot()
+		if err == nil {
+			workspace, err := doctor.Workspace(atelierPath)
+			if err != nil {
+				return err
+			}
+			if fix {
+				workspace, err = applyDoctorFixes(atelierPath, workspace)
+				if err != nil {
+					return err
+				}
+			}
+			printDoctorResults(workspace)
+			results = append(results, workspace...)
+		}
+
+		failures, warnings, fixable := 0, 0, 0
+		for i := range results {
+			switch results[i].Status {
+			case doctor.StatusFail:
+				failures++
+			case doctor.StatusWarn:
+				warnings++
+			}
+			if results[i].Fixable() {
+				fixable++
+			}
+		}
+		if fixable > 0 && !fix {
+			fmt.Printf("Run 'atelier doctor --fix' to repair %d of the problems.\n", fixable)
+		}
+		if failures > 0 {
+			return fmt.Errorf("%d problems found", failures)
+		}
+		if warnings > 0 {
+			fmt.Printf("No problems found; %d warnings.\n", warnings)
+		} else {
+			fmt.Println("No problems found.")
+		}
+		return nil
+	},
+}
+
+// applyDoctorFixes applies the fixable results and audits the atelier again.
+func applyDoctorFixes(atelierPath string, results []doctor.Result) ([]doctor.Result, error) {
+	applied := 0
+	for i := range results {
+		if !results[i].Fixable() {
+			continue
+		}
+		if err := results[i].Apply(); err != nil {
+			fmt.Printf("  failed to fix %s: %s: %v\n", results[i].Scope, results[i].Message, err)
+			continue
+		}
+		fmt.Printf("  fixed %s: %s\n", results[i].Scope, results[i].Message)
+		applied++
+	}
+	if applied == 0 {
+		return results, nil
+	}
+	fmt.Println("Commit the repaired files, e.g. with 'atelier push'. Audit after fixing:")
+	return doctor.Workspace(atelierPath)
+}
+
+func printDoctorResults(results []doctor.Result) {
+	for _, r := range results {
+		fmt.Printf("  %-5s %s: %s\n", r.Status, r.Scope, r.Message)
+		if r.Fix != "" && r.Status != doctor.StatusOK {
+			fmt.Printf("        fix: %s\n", r.Fix)
+		}
+	}
+}
+
+func init() {
+	doctorCmd.Flags().Bool("fix", false, "Apply the fixes that can be applied safely")
+	RootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [--artist <name>] [--canvas-glob <pattern>] -- <command> [args...]",
+	Short: "Run a command in every canvas",
+	Long: `Runs a command in every canvas of the atelier, found by their .canvas marker files, and
+prefixes each line of output with artist/canvas. The canvases run in parallel, up to --jobs at a
+time, and the output of each canvas is printed as one block; --jobs 1 runs them one after another.
+A summary of the exit codes is printed at the end; the command fails if it failed in any canvas.
+Can be run from any directory within the atelier.
+
+Examples:
+  atelier exec -- git log -1 --oneline
+  atelier exec --artist picasso --jobs 4 -- make test
+  atelier exec --canvas-glob 'go-*' -- sh -c 'go vet ./... && go test ./...'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tree, selected, err := selectCanvases(cmd)
+		if err != nil {
+			return err
+		}
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		opts := traverse.Options{Jobs: jobs, Out: os.Stdout}
+		results := traverse.Run(tree, opts, func(repo *traverse.Repo, out io.Writer) error {
+			if !selected[repo] {
+				return nil
+			}
+			prefixed := &prefixWriter{w: out, prefix: "[" + canvasLabel(repo) + "] "}
+			defer prefixed.Close()
+
+			c := exec.Command(args[0], args[1:]...)
+			c.Dir = repo.Path
+			c.Stdout = prefixed
+			c.Stderr = prefixed
+			return c.Run()
+		})
+
+		fmt.Println("Exec summary:")
+		failed := 0
+		for _, result := range results {
+			if !selected[result.Repo] {
+				continue
+			}
+			var exitErr *exec.ExitError
+			switch {
+			case result.Err == nil:
+				fmt.Printf("  ok       %s\n", canvasLabel(result.Repo))
+			case errors.As(result.Err, &exitErr):
+				failed++
+				fmt.Printf("  exit %-3d %s\n", exitErr.ExitCode(), canvasLabel(result.Repo))
+			default:
+				failed++
+				fmt.Printf("  error    %s (%v)\n", canvasLabel(result.Repo), result.Err)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("command failed in %d of %d canvases", failed, len(selected))
+		}
+		return nil
+	},
+}
+
+// addCanvasFilterFlags registers the flags of commands that run in a selection of canvases.
+func addCanvasFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("artist", "", "Only run in the canvases of this artist")
+	cmd.Flags().String("canvas-glob", "", "Only run in canvases whose name matches this pattern, e.g. 'go-*'")
+	addJobsFlag(cmd)
+}
+
+// selectCanvases discovers the atelier containing the working directory and returns its tree
+// together with the canvases, identified by their .canvas marker, that match the filter flags.
+func selectCanvases(cmd *cobra.Command) (*traverse.Repo, map[*traverse.Repo]bool, error) {
+	atelierPath, err := currentAtelierRoot()
+	if err != nil {
+		return nil, nil, err
+	}
+	artist, _ := cmd.Flags().GetString("artist")
+	glob, _ := cmd.Flags().GetString("canvas-glob")
+
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid --canvas-glob %q: %w", glob, err)
+		}
+	}
+	if artist != "" {
+		artist = marker.KindArtist.DirName(artist)
+		if !marker.Exists(filepath.Join(atelierPath, artist), marker.KindArtist) {
+			return nil, nil, fmt.Errorf("artist '%s' not found in this atelier", artist)
+		}
+	}
+
+	tree, err := traverse.Build(atelierPath, marker.KindAtelier)
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := make(map[*traverse.Repo]bool)
+	for _, repo := range traverse.Walk(tree, traverse.TopDown) {
+		if repo.Level == marker.KindCanvas && marker.Exists(repo.Path, marker.KindCanvas) &&
+			(artist == "" || repo.Parent.Name() == artist) && matchCanvas(glob, repo.Name()) {
+			selected[repo] = true
+		}
+	}
+	if len(selected) == 0 {
+		return nil, nil, fmt.Errorf("no canvases match")
+	}
+	return tree, selected, nil
+}
+
+// matchCanvas reports whether the canvas directory name, or the name without its canvas- prefix, matches glob.
+func matchCanvas(glob, name string) bool {
+	if glob == "" {
+		return true
+	}
+	full, _ := filepath.Match(glob, name)
+	short, _ := filepath.Match(glob, strings.TrimPrefix(name, marker.KindCanvas.Prefix()))
+	return full || short
+}
+
+// canvasLabel returns artist/canvas for a canvas, e.g. artist-picasso/canvas-guernica.
+func canvasLabel(canvas *traverse.Repo) string {
+	return canvas.Parent.Name() + "/" + canvas.Name()
+}
+
+// prefixWriter writes every line it receives to w, starting with prefix.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		if !p.midLine {
+			if _, err := io.WriteString(p.w, p.prefix); err != nil {
+				return 0, err
+			}
+		}
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+		}
+		if _, err := p.w.Write(line); err != nil {
+			return 0, err
+		}
+		p.midLine = line[len(line)-1] != '\n'
+		b = b[len(line):]
+	}
+	return n, nil
+}
+
+// Close ends an unterminated last line.
+func (p *prefixWriter) Close() error {
+	if p.midLine {
+		p.midLine = false
+		_, err := io.WriteString(p.w, "\n")
+		return err
+	}
+	return nil
+}
+
+func init() {
+	addCanvasFilterFlags(execCmd)
+	// Leave flags after the command name to the command itself
+	execCmd.Flags().SetInterspersed(false)
+	RootCmd.AddCommand(execCmd)
+}
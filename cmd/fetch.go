@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/pushengine"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+	"github.com/spf13/cobra"
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Fetch every repository in the atelier from its remote",
+	Long: `Fetches the atelier, its artists and their canvases from their origin remote in parallel and
+reports how far each branch is behind its upstream. Nothing is merged or checked out.
+Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+		tree, err := traverse.Build(atelierPath, marker.KindAtelier)
+		if err != nil {
+			return err
+		}
+
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		opts := traverse.Options{Jobs: jobs, Out: os.Stdout}
+		results := traverse.Run(tree, opts, func(repo *traverse.Repo, out io.Writer) error {
+			name, _ := filepath.Rel(atelierPath, repo.Path)
+			if name == "." {
+				name = repo.Name()
+			}
+			if gitutil.RemoteURL(repo.Path, pushengine.DefaultRemote) == "" {
+				fmt.Fprintf(out, "%s: no remote, skipped\n", name)
+				return nil
+			}
+			if err := gitutil.Fetch(repo.Path, pushengine.DefaultRemote); err != nil {
+				fmt.Fprintf(out, "%s: failed: %v\n", name, err)
+				return err
+			}
+			_, behind, hasUpstream, err := gitutil.AheadBehind(repo.Path)
+			switch {
+			case err != nil || !hasUpstream:
+				fmt.Fprintf(out, "%s: fetched\n", name)
+			case behind > 0:
+				fmt.Fprintf(out, "%s: fetched, behind %d\n", name, behind)
+			default:
+				fmt.Fprintf(out, "%s: fetched, up to date\n", name)
+			}
+			return nil
+		})
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d repositories failed to fetch", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	addJobsFlag(fetchCmd)
+	RootCmd.AddCommand(fetchCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/forge"
+	"github.com/spf13/cobra"
+)
+
+// addForgeFlags registers the flags selecting the forge that hosts remote repositories.
+// Defaults come from ATELIER_FORGE, ATELIER_FORGE_OWNER and ATELIER_FORGE_URL.
+func addForgeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("forge", os.Getenv("ATELIER_FORGE"), "Forge hosting remote repositories: "+strings.Join(forge.Providers, ", "))
+	cmd.Flags().String("forge-owner", os.Getenv("ATELIER_FORGE_OWNER"), "User or organization owning the remote repositories (default: the token's user)")
+	cmd.Flags().String("forge-url", os.Getenv("ATELIER_FORGE_URL"), "Base URL of a self-hosted forge, or the directory of bare repositories for the local forge")
+	cmd.Flags().String("forge-protocol", "ssh", "Protocol of the origin URL: ssh or https")
+}
+
+// addProvisionFlags registers the forge flags of commands that create remote repositories.
+func addProvisionFlags(cmd *cobra.Command) {
+	addForgeFlags(cmd)
+	cmd.Flags().Bool("public", false, "Create public remote repositories instead of private ones")
+}
+
+// forgeFromFlags returns the forge selected by the flags registered with addForgeFlags, or nil if none is selected.
+func forgeFromFlags(cmd *cobra.Command) (forge.Forge, error) {
+	provider, _ := cmd.Flags().GetString("forge")
+	if provider == "" {
+		return nil, nil
+	}
+	owner, _ := cmd.Flags().GetString("forge-owner")
+	url, _ := cmd.Flags().GetString("forge-url")
+	protocol, _ := cmd.Flags().GetString("forge-protocol")
+	public, _ := cmd.Flags().GetBool("public")
+	return forge.New(forge.Config{Provider: provider, Owner: owner, URL: url, Public: public, Protocol: protocol})
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/guard"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/pushengine"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+	"github.com/spf13/cobra"
+)
+
+var guardCmd = &cobra.Command{
+	Use:   "guard",
+	Short: "Keep secrets out of the repositories and out of AI agent sessions",
+	Long: `Scans for credentials before they are committed or pushed, and denies AI agents access to
+credential files and to commands that print the environment.
+
+Secrets are found with patterns for common tokens and keys (AWS, GitHub, GitLab, Slack, Google,
+Stripe, API keys, private keys), with a high-entropy check for generic assignments such as
+'api_key = "..."' or API_TOKEN=... in env files, and by file name for .env files, keys and credential files. A line containing
+'atelier:allow-secret' is never reported. The push commands scan every roll-up commit and every
+unpushed commit and refuse to push findings unless --no-guard is given.`,
+}
+
+var guardScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Scan the staged changes of the current repository for secrets",
+	Long: `Scans the changes staged in the repository of the current directory, or with --unpushed the
+commits not yet pushed to origin, and fails if secrets are found. The pre-commit hook installed by
+'atelier guard install' runs this command.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		var findings []guard.Finding
+		if unpushed, _ := cmd.Flags().GetBool("unpushed"); unpushed {
+			findings, err = guard.ScanUnpushed(dir, pushengine.DefaultRemote)
+		} else {
+			findings, err = guard.ScanStaged(dir)
+		}
+		if err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			return &guard.FindingsError{Repo: filepath.Base(dir), Findings: findings}
+		}
+		return nil
+	},
+}
+
+var guardReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "List possible secrets in every canvas",
+	Long: `Scans the files of every canvas that a commit could include (tracked files and untracked files
+that are not ignored) and lists the findings per canvas. Secrets are shown redacted. Fails if there
+are findings. Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tree, selected, err := selectCanvases(cmd)
+		if err != nil {
+			return err
+		}
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		total, affected := 0, 0
+		opts := traverse.Options{Jobs: jobs, Out: os.Stdout}
+		results := traverse.Run(tree, opts, func(repo *traverse.Repo, out io.Writer) error {
+			if !selected[repo] {
+				return nil
+			}
+			findings, err := guard.ScanTree(repo.Path)
+			if err != nil {
+				fmt.Fprintf(out, "%s: failed: %v\n", canvasLabel(repo), err)
+				return err
+			}
+			if len(findings) == 0 {
+				fmt.Fprintf(out, "%s: clean\n", canvasLabel(repo))
+				return nil
+			}
+			fmt.Fprintf(out, "%s: %d findings\n", canvasLabel(repo), len(findings))
+			for _, f := range findings {
+				fmt.Fprintf(out, "  %s\n", f)
+			}
+			return &guard.FindingsError{Repo: canvasLabel(repo), Findings: findings}
+		})
+
+		failed := 0
+		for _, result := range results {
+			var fe *guard.FindingsError
+			if errors.As(result.Err, &fe) {
+				total += len(fe.Findings)
+				affected++
+			} else if result.Err != nil {
+				failed++
+			}
+		}
+		fmt.Printf("Guard report: %d findings in %d of %d canvases.\n", total, affected, len(selected))
+		if failed > 0 {
+			return fmt.Errorf("%d canvases could not be scanned", failed)
+		}
+		if total > 0 {
+			return fmt.Errorf("possible secrets found; remove them or mark intended lines with '%s'", guard.AllowPragma)
+		}
+		return nil
+	},
+}
+
+var guardInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Write agent ignore/deny files and install the pre-commit hook in every repository",
+	Long: `Updates the atelier, its artists and their canvases so that AI agents are denied access to
+credential files and to commands printing the environment, and commits are scanned for secrets:
+  - adds a guard section listing .env and credential files to ` + strings.Join(guard.IgnoreFiles, ", ") + `;
+  - adds the matching deny rules to ` + guard.SettingsFile + `;
+  - installs a git pre-commit hook running 'atelier guard scan' and 'atelier check layout'.
+Existing content of these files is kept, and pre-commit hooks of your own are not replaced.
+New repositories get the ignore and deny files automatically. Commit the changed files, e.g. with
+'atelier push'. Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+		tree, err := traverse.Build(atelierPath, marker.KindAtelier)
+		if err != nil {
+			return err
+		}
+
+		results := traverse.Run(tree, traverse.Options{Jobs: 1, Out: os.Stdout}, func(repo *traverse.Repo, out io.Writer) error {
+			name, _ := filepath.Rel(atelierPath, repo.Path)
+			if name == "." {
+				name = repo.Name()
+			}
+			changed, err := guard.WriteAgentFiles(repo.Path)
+			if err != nil {
+				fmt.Fprintf(out, "%s: failed: %v\n", name, err)
+				return err
+			}
+			if len(changed) > 0 {
+				fmt.Fprintf(out, "%s: updated %s\n", name, strings.Join(changed, ", "))
+			}
+			installed, err := installPreCommitHook(repo.Path)
+			switch {
+			case err != nil:
+				fmt.Fprintf(out, "%s: failed to install hook: %v\n", name, err)
+				return err
+			case !installed:
+				fmt.Fprintf(out, "%s: has its own pre-commit hook, skipped; add 'atelier-cli guard scan' to it\n", name)
+			}
+			return nil
+		})
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d repositories failed to update", failed)
+		}
+		fmt.Println("Guard installed. Commit the updated files, e.g. with 'atelier push'.")
+		return nil
+	},
+}
+
+func init() {
+	guardScanCmd.Flags().Bool("unpushed", false, "Scan the commits not yet pushed to origin instead of the staged changes")
+	addCanvasFilterFlags(guardReportCmd)
+	guardCmd.AddCommand(guardScanCmd, guardReportCmd, guardInstallCmd)
+	RootCmd.AddCommand(guardCmd)
+}
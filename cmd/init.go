@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/frquxl/go-atelier/pkg/marker"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +19,12 @@ var initCmd = &cobra.Command{
 	Short: "Initialize a new atelier workspace",
 	Long: `Initialize a new atelier workspace with 3-level Git submodule structure.
 Creates atelier-<atelier-name> as main repo, artist as submodule, canvas as submodule of artist.
-If no artist/canvas provided, defaults to 'van-gogh' and 'sunflowers'.`,
+If no artist/canvas provided, defaults to 'van-gogh' and 'sunflowers'.
+
+With --template the atelier is generated from the named template pack instead of the default
+'atelier' pack; see 'atelier template list'. Values given with --set are offered to the templates
+of the atelier, artist and canvas, and each must be declared by one of them; missing parameters are
+asked for.`,
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if len(args) == 0 {
 			return fmt.Errorf("atelier name is required")
@@ -40,26 +46,50 @@ If no artist/canvas provided, defaults to 'van-gogh' and 'sunflowers'.`,
 		}
 
 		// 1. Create the Atelier
-		atelierPath, err := engine.CreateAtelier(wd, atelierBaseName)
+		opts, err := templateOptions(cmd)
+		if err != nil {
+			return err
+		}
+		// The --set values are offered to every template used; each must be declared by one of them
+		uses := []engine.TemplateUse{
+			{Template: opts.Template, Kind: marker.KindAtelier},
+			{Kind: marker.KindArtist, DirName: "artist-" + artistName},
+			{Kind: marker.KindCanvas, DirName: "canvas-" + canvasName},
+		}
+		if createSketchArtist || createGalleryArtist {
+			uses = append(uses, engine.TemplateUse{Kind: marker.KindCanvas, DirName: "canvas-example"})
+		}
+		if createSketchArtist {
+			uses = append(uses, engine.TemplateUse{Kind: marker.KindArtist, DirName: "artist-sketch"})
+		}
+		if createGalleryArtist {
+			uses = append(uses, engine.TemplateUse{Kind: marker.KindArtist, DirName: "artist-gallery"})
+		}
+		if err := engine.CheckParams("", opts.Params, uses...); err != nil {
+			return err
+		}
+		atelierPath, err := engine.CreateAtelier(wd, atelierBaseName, opts)
 		if err != nil {
 			return err // Error is already formatted and cleanup is handled by the engine
 		}
 
 		// 2. Create the primary Artist and default Canvas
-		if err = engine.CreateArtist(atelierPath, artistName, canvasName); err != nil {
+		// The template chosen with --template is the atelier's; artists and canvases use their defaults
+		childOpts := engine.CreateOptions{Params: opts.Params, Prompt: opts.Prompt}
+		if err = engine.CreateArtist(atelierPath, artistName, canvasName, childOpts); err != nil {
 			return err // Error is already formatted and cleanup is handled by the engine
 		}
 
 		// 3. Create additional artists if flags are set
 		if createSketchArtist {
 			fmt.Println("Creating additional 'sketch' artist...")
-			if err := engine.CreateArtist(atelierPath, "sketch", "example"); err != nil {
+			if err := engine.CreateArtist(atelierPath, "sketch", "example", childOpts); err != nil {
 				return err
 			}
 		}
 		if createGalleryArtist {
 			fmt.Println("Creating additional 'gallery' artist...")
-			if err := engine.CreateArtist(atelierPath, "gallery", "example"); err != nil {
+			if err := engine.CreateArtist(atelierPath, "gallery", "example", childOpts); err != nil {
 				return err
 			}
 		}
@@ -79,4 +109,5 @@ func init() {
 	// Add flags for additional artists
 	initCmd.Flags().BoolVar(&createSketchArtist, "sketch", false, "Create a default 'sketch' artist workspace.")
 	initCmd.Flags().BoolVar(&createGalleryArtist, "gallery", false, "Create a default 'gallery' artist workspace.")
+	addTemplateFlags(initCmd, "Template pack for the atelier (default: atelier)")
 }
\ No newline at end of file
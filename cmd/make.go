@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/frquxl/go-atelier/pkg/junit"
+	"github.com/frquxl/go-atelier/pkg/makefile"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+	"github.com/spf13/cobra"
+)
+
+var makeCmd = &cobra.Command{
+	Use:   "make <target> [-- make-args...]",
+	Short: "Run a Makefile target in every canvas that defines it",
+	Long: `Runs 'make <target>' in every canvas whose Makefile documents the target with the
+'target: ## description' convention used by the help target of the templates. Canvases that do not
+define the target are skipped. Output is prefixed with artist/canvas, a summary of the results is
+printed at the end, and the command fails if the target failed in any canvas. With --junit the
+combined run is written as a JUnit XML report, one test case per canvas.
+Can be run from any directory within the atelier.
+
+Examples:
+  atelier make test
+  atelier make test --jobs 4 --junit test-results.xml
+  atelier make build --artist picasso -- VERBOSE=1`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, makeArgs := args[0], args[1:]
+		tree, selected, err := selectCanvases(cmd)
+		if err != nil {
+			return err
+		}
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		junitPath, _ := cmd.Flags().GetString("junit")
+
+		runs := make(map[*traverse.Repo]*makeRun, len(selected))
+		for repo := range selected {
+			runs[repo] = &makeRun{}
+		}
+
+		opts := traverse.Options{Jobs: jobs, Out: os.Stdout}
+		results := traverse.Run(tree, opts, func(repo *traverse.Repo, out io.Writer) error {
+			run := runs[repo]
+			if run == nil {
+				return nil
+			}
+			defined, err := makefile.Defines(filepath.Join(repo.Path, makefile.FileName), target)
+			if err != nil || !defined {
+				return err
+			}
+			run.ran = true
+
+			prefixed := &prefixWriter{w: out, prefix: "[" + canvasLabel(repo) + "] "}
+			defer prefixed.Close()
+			output := io.MultiWriter(prefixed, &run.output)
+
+			c := exec.Command("make", append([]string{target}, makeArgs...)...)
+			c.Dir = repo.Path
+			c.Stdout = output
+			c.Stderr = output
+			start := time.Now()
+			err = c.Run()
+			run.duration = time.Since(start)
+			return err
+		})
+
+		report := &junit.TestSuites{Name: "atelier make " + target}
+		var suite *junit.TestSuite
+		fmt.Printf("Make summary (%s):\n", target)
+		ran, failed := 0, 0
+		for _, result := range results {
+			run := runs[result.Repo]
+			if run == nil {
+				continue
+			}
+			artist := result.Repo.Parent.Name()
+			if suite == nil || suite.Name != artist {
+				if suite != nil {
+					report.Add(*suite)
+				}
+				suite = &junit.TestSuite{Name: artist}
+			}
+			testCase := junit.TestCase{Name: result.Repo.Name(), ClassName: artist, Time: junit.Seconds(run.duration)}
+
+			var exitErr *exec.ExitError
+			switch {
+			case !run.ran && result.Err == nil:
+				fmt.Printf("  skipped  %s (no %s target)\n", canvasLabel(result.Repo), target)
+				testCase.Skipped = &junit.Skipped{Message: "Makefile does not define " + target}
+			case result.Err == nil:
+				ran++
+				fmt.Printf("  ok       %s (%s)\n", canvasLabel(result.Repo), run.duration.Round(time.Millisecond))
+				testCase.SystemOut = &junit.Output{Text: run.output.String()}
+			case errors.As(result.Err, &exitErr):
+				ran++
+				failed++
+				fmt.Printf("  exit %-3d %s (%s)\n", exitErr.ExitCode(), canvasLabel(result.Repo), run.duration.Round(time.Millisecond))
+				testCase.Failure = &junit.Failure{Message: result.Err.Error(), Text: run.output.String()}
+			default:
+				ran++
+				failed++
+				fmt.Printf("  error    %s (%v)\n", canvasLabel(result.Repo), result.Err)
+				testCase.Failure = &junit.Failure{Message: result.Err.Error(), Text: run.output.String()}
+			}
+			suite.Add(testCase)
+		}
+		if suite != nil {
+			report.Add(*suite)
+		}
+
+		if junitPath != "" {
+			if err := report.WriteFile(junitPath); err != nil {
+				return fmt.Errorf("failed to write JUnit report: %w", err)
+			}
+			fmt.Printf("JUnit report written to %s\n", junitPath)
+		}
+		if ran == 0 {
+			return fmt.Errorf("no canvas defines the %s target", target)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%s failed in %d of %d canvases", target, failed, ran)
+		}
+		return nil
+	},
+}
+
+// makeRun records the run of the target in one canvas.
+type makeRun struct {
+	ran      bool
+	duration time.Duration
+	output   bytes.Buffer
+}
+
+func init() {
+	addCanvasFilterFlags(makeCmd)
+	makeCmd.Flags().String("junit", "", "Write the results as a JUnit XML report to this file")
+	RootCmd.AddCommand(makeCmd)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/frquxl/go-atelier/pkg/manifest"
+	"github.com/frquxl/go-atelier/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	manifestFile string
+	applyYes     bool
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show how the atelier differs from atelier.yaml",
+	Long: `Compares the artists, canvases, templates and remotes declared in atelier.yaml with the
+directory tree and prints the actions 'atelier apply' would take. Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, actions, err := loadPlan()
+		if err != nil {
+			return err
+		}
+		printPlan(actions)
+		return nil
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create, move and delete artists and canvases to match atelier.yaml",
+	Long: `Converges the atelier on atelier.yaml: creates missing artists and canvases, moves canvases
+between artists, deletes undeclared canvases and configures remotes, committing in the parent repositories.
+Artists that exist but are not declared are reported and left untouched.
+Deletions require confirmation unless --yes is given; the answer may be piped, and apply fails when the
+input ends without one. The whole apply is a single operation: a failing action rolls back the ones before
+it, and 'atelier undo' reverts it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, actions, err := loadPlan()
+		if err != nil {
+			return err
+		}
+		printPlan(actions)
+		if !hasChanges(actions) {
+			return nil
+		}
+
+		for _, action := range actions {
+			if action.Destructive() && !applyYes {
+				if ok, err := util.Confirm("The plan deletes canvases. Apply it?"); err != nil {
+					return fmt.Errorf("the plan deletes canvases and was not confirmed: %w; pass --yes to apply it without confirmation", err)
+				} else if !ok {
+					fmt.Println("Apply cancelled.")
+					return nil
+				}
+				break
+			}
+		}
+
+		fmt.Println()
+		if err := manifest.Apply(atelierPath, actions); err != nil {
+			return err
+		}
+		fmt.Println("Atelier matches the manifest.")
+		return nil
+	},
+}
+
+// loadPlan reads the manifest for the current atelier and computes the plan.
+func loadPlan() (string, []manifest.Action, error) {
+	atelierPath, err := currentAtelierRoot()
+	if err != nil {
+		return "", nil, err
+	}
+	path := manifestFile
+	if path == "" {
+		path = manifest.Path(atelierPath)
+	}
+	m, err := manifest.Load(path)
+	if err != nil {
+		return "", nil, err
+	}
+	actions, err := manifest.Plan(atelierPath, m)
+	if err != nil {
+		return "", nil, err
+	}
+	return atelierPath, actions, nil
+}
+
+// printPlan prints the actions of a plan, or that there are none.
+func printPlan(actions []manifest.Action) {
+	if !hasChanges(actions) {
+		fmt.Println("No changes. The atelier matches the manifest.")
+	}
+	for _, action := range actions {
+		fmt.Println(action.String())
+	}
+}
+
+func hasChanges(actions []manifest.Action) bool {
+	for _, action := range actions {
+		if action.Type != manifest.Unmanaged {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	for _, c := range []*cobra.Command{planCmd, applyCmd} {
+		c.Flags().StringVarP(&manifestFile, "file", "f", "", "Path to the manifest (default: <atelier>/atelier.yaml)")
+		RootCmd.AddCommand(c)
+	}
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "Apply deletions without asking for confirmation")
+}
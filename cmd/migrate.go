@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade marker files to the current schema",
+	Long: `Rewrites legacy newline-separated .atelier, .artist and .canvas marker files across the
+whole atelier using the current structured schema. Can be run from any directory within the atelier.
+Markers are also upgraded automatically whenever a command rewrites them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+
+		migrated, err := marker.MigrateTree(atelierPath)
+		for _, path := range migrated {
+			rel, _ := filepath.Rel(atelierPath, path)
+			fmt.Printf("  migrated: %s\n", rel)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(migrated) == 0 {
+			fmt.Println("All marker files already use the current schema.")
+			return nil
+		}
+		fmt.Printf("Migrated %d marker files. Commit them in their repositories (e.g. with 'atelier-cli push').\n", len(migrated))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+}
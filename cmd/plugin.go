@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/plugin"
+	"github.com/spf13/cobra"
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "List and install plugins providing additional commands",
+	Long: `Any executable named atelier-<name> in the plugins directory of the atelier or on the PATH becomes
+the command 'atelier <name>'. Plugins in the atelier take precedence over those on the PATH, and
+built-in commands take precedence over plugins. Arguments and flags are passed on unchanged, and the
+plugin runs in the current directory with these environment variables:
+  ATELIER_CLI          path of the running CLI, to call back into it
+  ATELIER_ROOT         atelier root directory; ATELIER_NAME is its name
+  ATELIER_ARTIST_PATH  artist directory, when run within an artist; ATELIER_ARTIST is its name
+  ATELIER_CANVAS_PATH  canvas directory, when run within a canvas; ATELIER_CANVAS is its name`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins available in the current directory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, _ := currentAtelierRoot()
+		plugins := plugin.Discover(atelierPath)
+		if len(plugins) == 0 {
+			fmt.Printf("No plugins found. Install one with 'atelier plugin install <path>' or put an %s<name> executable on the PATH.\n", plugin.Prefix)
+			return nil
+		}
+		for _, p := range plugins {
+			fmt.Printf("%-16s %-8s %s\n", p.Name, p.Source, p.Path)
+			if builtinCommand(p.Name) {
+				fmt.Printf("%-16s %-8s shadowed by the built-in command\n", "", "")
+			}
+			for _, path := range p.Shadowed {
+				fmt.Printf("%-16s %-8s shadows %s\n", "", "", path)
+			}
+		}
+		return nil
+	},
+}
+
+var pluginInstallCmd = &cobra.Command{
+	Use:   "install <local-path>",
+	Short: "Install a plugin into the plugins directory of the atelier",
+	Long: `Installs the executable at <local-path>, or builds the Go module at <local-path>, into the
+` + plugin.Dir + ` directory at the atelier root as ` + plugin.Prefix + `<name>. The name defaults to the file or
+directory name without the ` + plugin.Prefix + ` prefix and extension. Commit the plugin, e.g. with 'atelier push',
+to share it with everyone working on the atelier, or ignore the directory to keep it local.
+
+Examples:
+  atelier plugin install ./scripts/atelier-lint.sh
+  atelier plugin install --name vincent ../go-atelier/plugins/vincent-cli`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+		name, _ := cmd.Flags().GetString("name")
+		p, err := plugin.Install(atelierPath, args[0], name)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(atelierPath, p.Path)
+		fmt.Printf("Installed %s. Run it with 'atelier %s'.\n", rel, p.Name)
+		return nil
+	},
+}
+
+// builtinCommand reports whether name is a built-in command or an alias of one.
+func builtinCommand(name string) bool {
+	for _, c := range RootCmd.Commands() {
+		if c.Annotations["plugin"] == "" && (c.Name() == name || c.HasAlias(name)) {
+			return true
+		}
+	}
+	return name == "help" || name == "completion"
+}
+
+// AddPluginCommands registers a command for every plugin found from the current directory whose
+// name is not taken by a built-in command. It must run after all built-in commands are registered.
+func AddPluginCommands() {
+	atelierPath, _ := currentAtelierRoot()
+	for _, p := range plugin.Discover(atelierPath) {
+		if builtinCommand(p.Name) {
+			continue
+		}
+		RootCmd.AddCommand(&cobra.Command{
+			Use:                p.Name,
+			Short:              "Plugin " + p.Path,
+			Annotations:        map[string]string{"plugin": p.Path},
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				wd, err := os.Getwd()
+				if err != nil {
+					return fmt.Errorf("could not get current working directory: %w", err)
+				}
+				err = p.Run(plugin.ResolveContext(wd), args)
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					// The plugin reported its own error; pass on its exit status
+					os.Exit(exitErr.ExitCode())
+				}
+				return err
+			},
+		})
+	}
+}
+
+func init() {
+	pluginInstallCmd.Flags().String("name", "", "Command name of the plugin (default: derived from <local-path>)")
+	pluginCmd.AddCommand(pluginListCmd, pluginInstallCmd)
+	RootCmd.AddCommand(pluginCmd)
+}
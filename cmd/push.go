@@ -3,9 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/pushengine"
 	"github.com/spf13/cobra"
 )
 
@@ -15,43 +16,67 @@ var pushCmd = &cobra.Command{
 	Long:  `Push changes at the atelier level, recursing into all artists and canvases.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check if we're in an atelier directory
-		if _, err := os.Stat(".atelier"); os.IsNotExist(err) {
+		if !marker.Exists(".", marker.KindAtelier) {
 			return fmt.Errorf("not in an atelier directory")
 		}
+		return runPush(cmd)
+	},
+}
 
-		// Get the directory of the executable
-		execPath, err := os.Executable()
-		if err != nil {
-			return fmt.Errorf("could not get executable path: %w", err)
-		}
-		execDir := filepath.Dir(execPath)
-		scriptPath := filepath.Join(execDir, "pkg/push-engine/push-engine.sh")
+// addPushFlags registers the flags shared by the atelier, artist and canvas push commands.
+func addPushFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "Show what would be pushed without pushing")
+	cmd.Flags().Bool("quiet", false, "Suppress verbose output")
+	cmd.Flags().Bool("force", false, "Force push (use with caution)")
+	cmd.Flags().Bool("no-guard", false, "Do not scan commits for secrets before pushing")
+	addJobsFlag(cmd)
+}
 
-		// Build command arguments
-		execArgs := []string{scriptPath}
-		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
-			execArgs = append(execArgs, "--dry-run")
-		}
-		if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
-			execArgs = append(execArgs, "--quiet")
-		}
-		if force, _ := cmd.Flags().GetBool("force"); force {
-			execArgs = append(execArgs, "--force")
-		}
+// runPush runs the push engine in the current directory using the shared push flags.
+// AUTO_COMMIT_DEFAULT=false disables roll-up commits and AUTO_COMMIT_MESSAGE overrides their message.
+func runPush(cmd *cobra.Command) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("could not get current working directory: %w", err)
+	}
 
-		// Execute the push engine
-		command := exec.Command("bash", execArgs...)
-		command.Stdout = os.Stdout
-		command.Stderr = os.Stderr
-		command.Env = append(os.Environ(), "ENGINE_ASSUME_YES=true", "AUTO_COMMIT_DEFAULT=true")
+	opts := pushengine.Options{
+		AutoCommit:    os.Getenv("AUTO_COMMIT_DEFAULT") != "false",
+		CommitMessage: os.Getenv("AUTO_COMMIT_MESSAGE"),
+		Out:           os.Stderr,
+	}
+	opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	opts.Quiet, _ = cmd.Flags().GetBool("quiet")
+	opts.Force, _ = cmd.Flags().GetBool("force")
+	opts.NoGuard, _ = cmd.Flags().GetBool("no-guard")
+	opts.Jobs, _ = cmd.Flags().GetInt("jobs")
 
-		return command.Run()
-	},
+	result, err := pushengine.Push(wd, opts)
+	if result != nil {
+		printPushSummary(result, opts.Quiet)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d repositories failed to push", len(result.Failed))
+	}
+	return nil
+}
+
+func printPushSummary(result *pushengine.Result, quiet bool) {
+	if !quiet && len(result.Pushed) > 0 {
+		fmt.Println("Push summary:")
+		for _, repo := range result.Pushed {
+			fmt.Printf("  pushed: %s\n", filepath.Base(repo))
+		}
+	}
+	for _, failure := range result.Failed {
+		fmt.Printf("  failed: %s (%v)\n", filepath.Base(failure.Repo), failure.Err)
+	}
 }
 
 func init() {
-	pushCmd.Flags().Bool("dry-run", false, "Show what would be pushed without pushing")
-	pushCmd.Flags().Bool("quiet", false, "Suppress verbose output")
-	pushCmd.Flags().Bool("force", false, "Force push (use with caution)")
+	addPushFlags(pushCmd)
 	RootCmd.AddCommand(pushCmd)
 }
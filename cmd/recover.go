@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Roll back interrupted operations",
+	Long: `Every command that changes the atelier records its steps in a journal under .git/atelier/journal
+and rolls them back automatically when a step fails. If the process is interrupted, or a rollback cannot
+complete, the journal is left behind and further changes are refused until it is resolved.
+
+recover rolls back the pending operations, newest first. Use --list to only show them.
+Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			journals, err := engine.PendingJournals(atelierPath)
+			if err != nil {
+				return err
+			}
+			if len(journals) == 0 {
+				fmt.Println("No interrupted operations.")
+				return nil
+			}
+			for _, j := range journals {
+				fmt.Printf("  %s\n", j.Describe())
+			}
+			return nil
+		}
+
+		recovered, err := engine.Recover(atelierPath)
+		for _, j := range recovered {
+			fmt.Printf("  rolled back: %s\n", j.Describe())
+		}
+		if err != nil {
+			return err
+		}
+		if len(recovered) == 0 {
+			fmt.Println("No interrupted operations.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	recoverCmd.Flags().Bool("list", false, "List interrupted operations without rolling them back")
+	RootCmd.AddCommand(recoverCmd)
+}
@@ -1,6 +1,13 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/traverse"
 	"github.com/spf13/cobra"
 )
 
@@ -8,8 +15,33 @@ var RootCmd = &cobra.Command{
 	Use:   "atelier",
 	Short: "A metaphor-driven CLI for software project management",
 	Long:  `Atelier is a CLI tool that uses the atelier/artist/canvas metaphor to help manage software projects.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments and flags are valid once this runs, so later errors are not usage errors
+		cmd.SilenceUsage = true
+		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+			gitutil.Output = os.Stderr
+		}
+		backend, _ := cmd.Flags().GetString("git-backend")
+		return gitutil.SetBackend(backend)
+	},
 }
 
 func init() {
 	RootCmd.Version = Version
+	RootCmd.PersistentFlags().String("git-backend", os.Getenv("ATELIER_GIT_BACKEND"), "Git implementation to use: "+strings.Join(gitutil.Backends, ", "))
+	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "Show the output of git commands")
+}
+
+// addJobsFlag registers --jobs on commands that work on several repositories in parallel.
+func addJobsFlag(cmd *cobra.Command) {
+	cmd.Flags().IntP("jobs", "j", traverse.DefaultJobs, "Number of repositories to process in parallel")
+}
+
+// currentAtelierRoot returns the root of the atelier containing the current working directory.
+func currentAtelierRoot() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("could not get current working directory: %w", err)
+	}
+	return marker.FindRoot(wd)
 }
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/frquxl/go-atelier/pkg/status"
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of every repository in the atelier",
+	Long: `Walks the atelier, its artists and their canvases and reports for each repository the
+current branch, dirty files, commits ahead/behind its upstream, detached HEADs, missing remotes
+submodule pointers that differ from the commit recorded by the parent and submodules that
+are not checked out.
+Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		root, err := status.Collect(atelierPath, jobs)
+		if err != nil {
+			return err
+		}
+
+		if statusJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(root)
+		}
+		status.PrintTree(os.Stdout, root)
+		return nil
+	},
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the status tree as JSON")
+	addJobsFlag(statusCmd)
+	RootCmd.AddCommand(statusCmd)
+}
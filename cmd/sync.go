@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/syncengine"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Pull remote changes into the atelier, its artists and canvases",
+	Long: `Fetches every repository of the atelier and fast-forwards its branch to the upstream (or
+rebases onto it with --rebase). Repositories on a detached HEAD are checked out on their branch,
+submodules added upstream are checked out, and every parent commits the updated pointers of
+its children. Repositories that cannot be synced are reported and left as they were.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in an atelier directory
+		if !marker.Exists(".", marker.KindAtelier) {
+			return fmt.Errorf("not in an atelier directory")
+		}
+		return runSync(cmd)
+	},
+}
+
+// addSyncFlags registers the flags shared by the atelier, artist and canvas sync commands.
+func addSyncFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("rebase", false, "Rebase local commits onto the upstream when a fast-forward is not possible")
+	cmd.Flags().Bool("quiet", false, "Suppress verbose output")
+	addJobsFlag(cmd)
+}
+
+// runSync runs the sync engine in the current directory using the shared sync flags.
+func runSync(cmd *cobra.Command) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("could not get current working directory: %w", err)
+	}
+
+	opts := syncengine.Options{Out: os.Stderr}
+	opts.Rebase, _ = cmd.Flags().GetBool("rebase")
+	opts.Quiet, _ = cmd.Flags().GetBool("quiet")
+	opts.Jobs, _ = cmd.Flags().GetInt("jobs")
+
+	result, err := syncengine.Sync(wd, opts)
+	if err != nil {
+		return err
+	}
+	printSyncSummary(result, opts.Quiet)
+	if len(result.Conflicts) > 0 {
+		return fmt.Errorf("%d repositories could not be synced", len(result.Conflicts))
+	}
+	return nil
+}
+
+func printSyncSummary(result *syncengine.Result, quiet bool) {
+	if !quiet && len(result.Updated)+len(result.Committed) > 0 {
+		fmt.Println("Sync summary:")
+		for _, repo := range result.Updated {
+			fmt.Printf("  updated: %s\n", filepath.Base(repo))
+		}
+		for _, repo := range result.Committed {
+			fmt.Printf("  recorded pointers: %s\n", filepath.Base(repo))
+		}
+	}
+	for _, conflict := range result.Conflicts {
+		fmt.Printf("  conflict: %s (%v)\n", filepath.Base(conflict.Repo), conflict.Err)
+	}
+}
+
+func init() {
+	addSyncFlags(syncCmd)
+	RootCmd.AddCommand(syncCmd)
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/templates"
+	"github.com/frquxl/go-atelier/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage the template packs repositories are generated from",
+	Long: `A template pack is a directory of files that 'init', 'artist init' and 'canvas init' generate a
+repository from: README.md, AGENTS.md, Makefile, gitignore and geminiignore (or .gitignore and
+.geminiignore). Files a pack does not provide come from the embedded default template of the level.
+Packs are looked up by name in this order:
+  1. the templates/ directory at the root of the atelier;
+  2. the user's directory, $XDG_CONFIG_HOME/atelier/templates or ~/.config/atelier/templates;
+  3. the packs embedded in the CLI.
+A pack on disk named like an embedded one, e.g. templates/canvas, replaces it.
+
+The files are rendered with Go's text/template. Besides {{ .Atelier }}, {{ .Artist }} and
+{{ .Canvas }} (directory names), {{ .Name }} (name without prefix), {{ .Kind }}, {{ .Author }},
+{{ .Email }}, {{ .Date }} and {{ .Year }}, a pack can declare parameters in a template.yaml:
+
+  description: Python service
+  params:
+    - name: python_version
+      prompt: Python version
+      default: "3.12"
+    - name: license
+      choices: [MIT, Apache-2.0]
+      default: MIT
+
+Templates refer to them as {{ .Params.python_version }}. Parameters are asked for interactively,
+or taken from --set key=value, or default when the input is not a terminal. --set is rejected for a
+parameter no template used declares, and a parameter a pack does not declare renders as empty.
+
+The files: list of template.yaml replaces the default files with any files and directories of the
+pack, each with an optional dest (which may use the variables), mode (e.g. "0755"), optional: true
+and render: false. post: lists shell commands run in the new repository once its files are written.
+
+A pack with extends: <base> takes the files it lacks, the parameters, fragments and post-create
+commands from the base. fragments: [go, python] appends the files of each fragment, e.g. a gitignore
+or Makefile, to the pack's files of the same name; names may use the variables, and one that renders
+empty is skipped. Fragments are directories in templates/fragments at the atelier root or in the
+user's directory, or embedded: common, and go, python, node and rust, which the canvas pack picks
+by its language parameter.
+See the result with 'atelier template render <name>'.`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available template packs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, _ := currentAtelierRoot()
+		packs, err := templates.List(atelierPath)
+		if err != nil {
+			return err
+		}
+		for _, pack := range packs {
+			fmt.Printf("%-20s %-9s %s\n", pack.Name, pack.Source, pack.Dir)
+		}
+		return nil
+	},
+}
+
+var templateRenderCmd = &cobra.Command{
+	Use:   "render <name>",
+	Short: "Preview the files a template pack generates",
+	Long: `Prints the files the template pack <name> generates, with the packs it extends and its fragments
+merged in, followed by its post-create commands, which are not run. Nothing is written.
+
+The files are rendered for a repository named --name of the kind given with --kind, by default the
+kind the pack name suggests (atelier, artist-*, else canvas). Parameters take their defaults unless
+given with --set.
+
+Examples:
+  atelier template render canvas --set language=go
+  atelier template render artist-sketch --name picasso`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, _ := currentAtelierRoot()
+		pack, err := templates.Find(atelierPath, args[0])
+		if err != nil {
+			return err
+		}
+		kindFlag, _ := cmd.Flags().GetString("kind")
+		kind := marker.Kind(kindFlag)
+		if kind == "" {
+			kind = templates.KindOf(pack.Name)
+		}
+		if !slices.Contains(marker.Kinds, kind) {
+			return fmt.Errorf("invalid --kind %q; expected atelier, artist or canvas", kind)
+		}
+		params, err := templateParams(cmd)
+		if err != nil {
+			return err
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+		atelierName := "atelier-example"
+		if atelierPath != "" {
+			atelierName = filepath.Base(atelierPath)
+		}
+		var m *marker.Marker
+		switch kind {
+		case marker.KindAtelier:
+			m = marker.New(kind, kind.Prefix()+name, "", "")
+		case marker.KindArtist:
+			m = marker.New(kind, atelierName, kind.Prefix()+name, "")
+		default:
+			m = marker.New(kind, atelierName, "artist-example", kind.Prefix()+name)
+		}
+		wd, _ := os.Getwd()
+		values := templates.ValuesFor(m, wd)
+		if values.Params, err = pack.ResolveParams(params, values, nil); err != nil {
+			return err
+		}
+		outputs, err := pack.Generate(kind, values)
+		if err != nil {
+			return err
+		}
+		commands, err := pack.PostCommands(values)
+		if err != nil {
+			return err
+		}
+
+		for i, out := range outputs {
+			if i > 0 {
+				fmt.Println()
+			}
+			header := out.Path
+			if out.Mode != 0644 {
+				header += fmt.Sprintf(" (%04o)", out.Mode)
+			}
+			fmt.Printf("==> %s <==\n%s", header, out.Content)
+			if len(out.Content) > 0 && out.Content[len(out.Content)-1] != '\n' {
+				fmt.Println()
+			}
+		}
+		if len(commands) > 0 {
+			fmt.Println("\n==> post-create commands <==")
+			for _, command := range commands {
+				fmt.Println(command)
+			}
+		}
+		return nil
+	},
+}
+
+// addTemplateFlags registers the flags choosing the template pack of a new repository and its parameters.
+func addTemplateFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().String("template", "", usage)
+	cmd.Flags().StringArray("set", nil, "Set a template parameter, as key=value (repeatable)")
+}
+
+// templateOptions returns the create options for the template flags of cmd. Parameters without a
+// value are asked for when the standard input is a terminal.
+func templateOptions(cmd *cobra.Command) (engine.CreateOptions, error) {
+	var opts engine.CreateOptions
+	var err error
+	opts.Template, _ = cmd.Flags().GetString("template")
+	if opts.Params, err = templateParams(cmd); err != nil {
+		return opts, err
+	}
+	if util.IsInteractive() {
+		opts.Prompt = promptParam
+	}
+	return opts, nil
+}
+
+// templateParams returns the template parameters given with --set.
+func templateParams(cmd *cobra.Command) (map[string]string, error) {
+	sets, _ := cmd.Flags().GetStringArray("set")
+	if len(sets) == 0 {
+		return nil, nil
+	}
+	params := make(map[string]string, len(sets))
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q; expected key=value", set)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// promptParam asks for the value of p on the standard input; an empty answer takes the default.
+func promptParam(p templates.Param, def string) (string, error) {
+	question := p.Question()
+	if len(p.Choices) > 0 {
+		question += " (" + strings.Join(p.Choices, ", ") + ")"
+	}
+	if def != "" {
+		question += " [" + def + "]"
+	}
+	// Without an answer, e.g. at the end of the input, the default is taken
+	answer, err := util.Prompt(question)
+	if errors.Is(err, io.EOF) {
+		return def, nil
+	} else if err != nil {
+		return "", fmt.Errorf("no value for template parameter %s: %w", p.Name, err)
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+func init() {
+	templateRenderCmd.Flags().String("kind", "", "Kind of repository to render for: atelier, artist or canvas (default: from the name)")
+	templateRenderCmd.Flags().String("name", "example", "Name of the repository to render for, without prefix")
+	templateRenderCmd.Flags().StringArray("set", nil, "Set a template parameter, as key=value (repeatable)")
+	templateCmd.AddCommand(templateListCmd, templateRenderCmd)
+	RootCmd.AddCommand(templateCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the last structural operation",
+	Long: `Reverts the last completed init, delete, move, rename or clone. The operation log under .git/atelier/oplog
+records the commands that ran, the commits of every repository they touched before and after, and the paths
+they moved. Deleted artists and canvases are kept in its trash, so undoing a deletion restores them.
+
+The last ` + fmt.Sprint(engine.OperationLogLimit) + ` operations are kept; run undo repeatedly to step further back. Undo refuses when a
+repository has new commits since the operation, unless --force is given. Remote repositories created or
+deleted on a forge are not touched. A move to another atelier is logged in the atelier it started from only;
+undo it there to restore both ateliers.
+Can be run from any directory within the atelier.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atelierPath, err := currentAtelierRoot()
+		if err != nil {
+			return err
+		}
+
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			entries, err := engine.OperationLog(atelierPath)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				fmt.Println("No operations to undo.")
+				return nil
+			}
+			for i := len(entries) - 1; i >= 0; i-- {
+				fmt.Printf("  %s\n", entries[i].Describe())
+			}
+			return nil
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		j, err := engine.Undo(atelierPath, force)
+		if err != nil {
+			return err
+		}
+		if j == nil {
+			fmt.Println("No operations to undo.")
+			return nil
+		}
+		fmt.Printf("Undid %s.\n", j.Operation)
+		return nil
+	},
+}
+
+func init() {
+	undoCmd.Flags().Bool("list", false, "List the operations that can be undone, newest first")
+	undoCmd.Flags().Bool("force", false, "Undo even if repositories have new commits since the operation")
+	RootCmd.AddCommand(undoCmd)
+}
@@ -0,0 +1,329 @@
+// Package contextmerge keeps the AGENTS.md, README.md and Makefile files generated from the
+// templates up to date as the templates improve, without losing what users wrote in them.
+//
+// Template-owned text is wrapped in section markers, written as HTML comments in Markdown and as
+// # comments in Makefiles:
+//
+//	<!-- atelier:begin workflow -->
+//	...
+//	<!-- atelier:end workflow -->
+//
+// Everything outside the markers belongs to the user and is never changed. Marker files record a
+// hash of every template section as it was last written, which serves as the base of a three-way
+// merge between the file (ours) and the current template (theirs).
+//
+// A begin marker with the protected flag, e.g. "<!-- atelier:begin structure protected -->",
+// declares a section that must not be edited at all; see CheckLayout.
+package contextmerge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Files lists the generated files whose template sections are kept up to date.
+var Files = []string{"AGENTS.md", "README.md", "Makefile"}
+
+const (
+	markerPrefix  = "atelier:"
+	protectedFlag = "protected"
+	conflictBegin = "<<<<<<< local\n"
+)
+
+// chunk is either user text (id is empty) or a template section including its marker lines.
+type chunk struct {
+	id        string
+	protected bool
+	begin     string // Begin marker line, with its newline
+	content   string // Lines between the markers
+	end       string // End marker line, with its newline unless it ends the file
+	text      string // User text
+}
+
+func (c chunk) String() string {
+	if c.id == "" {
+		return c.text
+	}
+	return c.begin + c.content + c.end
+}
+
+// parse splits content into user text and template sections.
+func parse(content string) ([]chunk, error) {
+	var chunks []chunk
+	var text strings.Builder
+	var section *chunk
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		verb, id, flags := parseMarker(line)
+		if section != nil {
+			switch verb {
+			case "end":
+				if id != section.id {
+					return nil, fmt.Errorf("section %q is closed by the end marker of %q", section.id, id)
+				}
+				section.end = line
+				chunks = append(chunks, *section)
+				section = nil
+			case "begin":
+				return nil, fmt.Errorf("section %q starts inside section %q", id, section.id)
+			default:
+				section.content += line
+			}
+			continue
+		}
+		switch verb {
+		case "begin":
+			if text.Len() > 0 {
+				chunks = append(chunks, chunk{text: text.String()})
+				text.Reset()
+			}
+			section = &chunk{id: id, protected: slices.Contains(flags, protectedFlag), begin: line}
+		case "end":
+			return nil, fmt.Errorf("end marker of section %q without a begin marker", id)
+		default:
+			text.WriteString(line)
+		}
+	}
+	if section != nil {
+		return nil, fmt.Errorf("section %q has no end marker", section.id)
+	}
+	if text.Len() > 0 {
+		chunks = append(chunks, chunk{text: text.String()})
+	}
+	return chunks, nil
+}
+
+// parseMarker splits a section marker line, "<!-- atelier:<verb> <id> [flags] -->" or
+// "# atelier:<verb> <id> [flags]", into its parts. verb is empty if line is not a marker.
+func parseMarker(line string) (verb, id string, flags []string) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "<!--") && strings.HasSuffix(line, "-->"):
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "<!--"), "-->")
+	case strings.HasPrefix(line, "#"):
+		line = strings.TrimPrefix(line, "#")
+	default:
+		return "", "", nil
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], markerPrefix) {
+		return "", "", nil
+	}
+	verb = strings.TrimPrefix(fields[0], markerPrefix)
+	if verb != "begin" && verb != "end" {
+		return "", "", nil
+	}
+	return verb, fields[1], fields[2:]
+}
+
+// hash identifies the content of a section, ignoring line endings and surrounding blank lines.
+func hash(content string) string {
+	content = strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Hashes returns the hash of every template section in content, by section id.
+func Hashes(content string) (map[string]string, error) {
+	chunks, err := parse(content)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string)
+	for _, c := range chunks {
+		if c.id != "" {
+			hashes[c.id] = hash(c.content)
+		}
+	}
+	return hashes, nil
+}
+
+// Result describes the merge of one file.
+type Result struct {
+	Updated   []string // Sections replaced by the template's version
+	Added     []string // Sections new in the template
+	Removed   []string // Unmodified sections the template no longer has
+	Kept      []string // Locally modified sections the template no longer has
+	Conflicts []string // Sections changed both locally and in the template
+	Unmerged  []string // Sections still holding the conflict markers of an earlier merge
+}
+
+// Changed reports whether the merge changed anything.
+func (r *Result) Changed() bool {
+	return len(r.Updated)+len(r.Added)+len(r.Removed)+len(r.Conflicts) > 0
+}
+
+// Merge merges the template sections of theirs into ours. base holds the section hashes recorded
+// when the file was last generated or merged. For every section:
+//   - unchanged locally: the template's version is taken;
+//   - changed locally but not in the template: the local version is kept;
+//   - changed in both: the section gets conflict markers with both versions.
+//
+// Sections new in the template are inserted after the section that precedes them in the template,
+// unless the base shows the user deleted them. Text outside sections is kept as it is.
+func Merge(ours, theirs string, base map[string]string) (string, *Result, error) {
+	oursChunks, err := parse(ours)
+	if err != nil {
+		return "", nil, fmt.Errorf("local file: %w", err)
+	}
+	theirsChunks, err := parse(theirs)
+	if err != nil {
+		return "", nil, fmt.Errorf("template: %w", err)
+	}
+	template := make(map[string]chunk)
+	var order []string
+	for _, c := range theirsChunks {
+		if c.id != "" {
+			template[c.id] = c
+			order = append(order, c.id)
+		}
+	}
+
+	result := &Result{}
+	var merged []chunk
+	for _, c := range oursChunks {
+		if c.id == "" {
+			merged = append(merged, c)
+			continue
+		}
+		t, inTemplate := template[c.id]
+		baseHash, inBase := base[c.id]
+		modified := !inBase || hash(c.content) != baseHash
+		switch {
+		case strings.Contains(c.content, conflictBegin):
+			result.Unmerged = append(result.Unmerged, c.id)
+		case !inTemplate && !modified:
+			result.Removed = append(result.Removed, c.id)
+			merged = dropSeparator(merged)
+			continue
+		case !inTemplate:
+			result.Kept = append(result.Kept, c.id)
+		case hash(c.content) == hash(t.content):
+		case !modified:
+			c.content = t.content
+			result.Updated = append(result.Updated, c.id)
+		case inBase && hash(t.content) == baseHash:
+		default:
+			c.content = conflict(c.content, t.content)
+			result.Conflicts = append(result.Conflicts, c.id)
+		}
+		if inTemplate && c.begin != t.begin {
+			// Marker lines belong to the template, e.g. when it starts protecting a section.
+			c.begin, c.protected = t.begin, t.protected
+			if !slices.Contains(result.Updated, c.id) {
+				result.Updated = append(result.Updated, c.id)
+			}
+		}
+		merged = append(merged, c)
+	}
+
+	for i, id := range order {
+		if indexOf(merged, id) >= 0 {
+			continue
+		}
+		if _, deleted := base[id]; deleted {
+			continue
+		}
+		merged = insertSection(merged, template[id], order[:i])
+		result.Added = append(result.Added, id)
+	}
+
+	var out strings.Builder
+	for _, c := range merged {
+		out.WriteString(c.String())
+	}
+	return out.String(), result, nil
+}
+
+// conflict marks a section changed both locally and in the template.
+func conflict(ours, theirs string) string {
+	return conflictBegin + withNewline(ours) + "=======\n" + withNewline(theirs) + ">>>>>>> template\n"
+}
+
+// insertSection inserts a new section after the last of the preceding template sections present in
+// chunks, or before the first section if none of them is.
+func insertSection(chunks []chunk, section chunk, preceding []string) []chunk {
+	at := -1
+	for _, id := range preceding {
+		if i := indexOf(chunks, id); i > at {
+			at = i
+		}
+	}
+	section.end = withNewline(section.end)
+	if at < 0 {
+		for i, c := range chunks {
+			if c.id != "" {
+				return insert(chunks, i, section, chunk{text: "\n"})
+			}
+		}
+		if n := len(chunks); n > 0 {
+			chunks[n-1].text = withNewline(chunks[n-1].text)
+		}
+		return insert(chunks, len(chunks), chunk{text: "\n"}, section)
+	}
+	chunks[at].end = withNewline(chunks[at].end)
+	return insert(chunks, at+1, chunk{text: "\n"}, section)
+}
+
+func insert(chunks []chunk, at int, added ...chunk) []chunk {
+	return append(chunks[:at], append(added, chunks[at:]...)...)
+}
+
+// dropSeparator removes one blank line from the end of chunks, so that removing a section does not
+// leave two blank lines behind.
+func dropSeparator(chunks []chunk) []chunk {
+	n := len(chunks)
+	if n == 0 || chunks[n-1].id != "" {
+		return chunks
+	}
+	switch text := chunks[n-1].text; {
+	case text == "\n":
+		return chunks[:n-1]
+	case strings.HasSuffix(text, "\n\n"):
+		chunks[n-1].text = text[:len(text)-1]
+	}
+	return chunks
+}
+
+func indexOf(chunks []chunk, id string) int {
+	for i, c := range chunks {
+		if c.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// SetSection returns content with the section id set to body. A section content does not have yet
+// is appended with # markers, the comment syntax of ignore files and Makefiles.
+func SetSection(content, id, body string) (string, error) {
+	chunks, err := parse(content)
+	if err != nil {
+		return "", err
+	}
+	body = withNewline(body)
+	if i := indexOf(chunks, id); i >= 0 {
+		chunks[i].content = body
+		var out strings.Builder
+		for _, c := range chunks {
+			out.WriteString(c.String())
+		}
+		return out.String(), nil
+	}
+	if content != "" {
+		content = withNewline(content) + "\n"
+	}
+	return content + "# " + markerPrefix + "begin " + id + "\n" + body + "# " + markerPrefix + "end " + id + "\n", nil
+}
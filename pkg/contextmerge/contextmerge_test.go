@@ -0,0 +1,154 @@
+package contextmerge
+
+import (
+	"slices"
+	"testing"
+)
+
+func section(id, content string) string {
+	return "<!-- atelier:begin " + id + " -->\n" + content + "<!-- atelier:end " + id + " -->\n"
+}
+
+func TestMerge(t *testing.T) {
+	base := map[string]string{"a": hash("old a\n"), "b": hash("old b\n")}
+	tests := []struct {
+		name   string
+		ours   string
+		theirs string
+		base   map[string]string
+		want   string
+		check  func(r *Result) []string
+		expect []string
+	}{
+		{
+			name:   "unchanged section takes the template",
+			ours:   "# Title\n\n" + section("a", "old a\n"),
+			theirs: section("a", "new a\n"),
+			base:   base,
+			want:   "# Title\n\n" + section("a", "new a\n"),
+			check:  func(r *Result) []string { return r.Updated },
+			expect: []string{"a"},
+		},
+		{
+			name:   "local edit is kept when the template did not change",
+			ours:   section("a", "my a\n"),
+			theirs: section("a", "old a\n"),
+			base:   base,
+			want:   section("a", "my a\n"),
+			check:  func(r *Result) []string { return r.Updated },
+		},
+		{
+			name:   "edits on both sides conflict",
+			ours:   section("a", "my a\n"),
+			theirs: section("a", "new a\n"),
+			base:   base,
+			want:   section("a", "<<<<<<< local\nmy a\n=======\nnew a\n>>>>>>> template\n"),
+			check:  func(r *Result) []string { return r.Conflicts },
+			expect: []string{"a"},
+		},
+		{
+			name:   "section new in the template is inserted after its predecessor",
+			ours:   section("a", "old a\n") + "\nuser text\n",
+			theirs: section("a", "old a\n") + "\n" + section("c", "c\n"),
+			base:   base,
+			want:   section("a", "old a\n") + "\n" + section("c", "c\n") + "\nuser text\n",
+			check:  func(r *Result) []string { return r.Added },
+			expect: []string{"c"},
+		},
+		{
+			name:   "section the user deleted stays deleted",
+			ours:   section("a", "old a\n"),
+			theirs: section("a", "old a\n") + section("b", "new b\n"),
+			base:   base,
+			want:   section("a", "old a\n"),
+			check:  func(r *Result) []string { return r.Added },
+		},
+		{
+			name:   "unmodified section dropped from the template is removed",
+			ours:   section("a", "old a\n") + "\n" + section("b", "old b\n"),
+			theirs: section("a", "old a\n"),
+			base:   base,
+			want:   section("a", "old a\n"),
+			check:  func(r *Result) []string { return r.Removed },
+			expect: []string{"b"},
+		},
+		{
+			name:   "modified section dropped from the template is kept",
+			ours:   section("b", "my b\n"),
+			theirs: "",
+			base:   base,
+			want:   section("b", "my b\n"),
+			check:  func(r *Result) []string { return r.Kept },
+			expect: []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result, err := Merge(tt.ours, tt.theirs, tt.base)
+			if err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Merge =\n%s\nwant\n%s", got, tt.want)
+			}
+			if ids := tt.check(result); !slices.Equal(ids, tt.expect) {
+				t.Errorf("result = %v, want %v", ids, tt.expect)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, content := range []string{
+		"<!-- atelier:begin a -->\n",
+		"<!-- atelier:end a -->\n",
+		"<!-- atelier:begin a -->\n<!-- atelier:end b -->\n",
+		"<!-- atelier:begin a -->\n<!-- atelier:begin b -->\n",
+	} {
+		if _, err := parse(content); err == nil {
+			t.Errorf("parse(%q) succeeded, want an error", content)
+		}
+	}
+}
+
+func TestParseMarker(t *testing.T) {
+	tests := []struct {
+		line  string
+		verb  string
+		id    string
+		flags []string
+	}{
+		{"<!-- atelier:begin workflow -->\n", "begin", "workflow", []string{}},
+		{"# atelier:end targets\n", "end", "targets", []string{}},
+		{"<!-- atelier:begin structure protected -->", "begin", "structure", []string{"protected"}},
+		{"<!-- atelier:other x -->", "", "", nil},
+		{"# plain comment", "", "", nil},
+		{"atelier:begin x", "", "", nil},
+	}
+	for _, tt := range tests {
+		verb, id, flags := parseMarker(tt.line)
+		if verb != tt.verb || id != tt.id || !slices.Equal(flags, tt.flags) {
+			t.Errorf("parseMarker(%q) = %q, %q, %v; want %q, %q, %v", tt.line, verb, id, flags, tt.verb, tt.id, tt.flags)
+		}
+	}
+}
+
+func TestSetSection(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", "# atelier:begin guard\n.env\n# atelier:end guard\n"},
+		{"node_modules/", "node_modules/\n\n# atelier:begin guard\n.env\n# atelier:end guard\n"},
+		{"a\n# atelier:begin guard\nold\n# atelier:end guard\nb\n", "a\n# atelier:begin guard\n.env\n# atelier:end guard\nb\n"},
+	}
+	for _, tt := range tests {
+		got, err := SetSection(tt.content, "guard", ".env")
+		if err != nil {
+			t.Fatalf("SetSection(%q): %v", tt.content, err)
+		}
+		if got != tt.want {
+			t.Errorf("SetSection(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package contextmerge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/templates"
+)
+
+// Drift describes a protected template section that was changed by hand.
+type Drift struct {
+	File    string
+	Section string // Empty when the problem concerns the whole file
+	Problem string // "modified", "removed", "file missing" or a description of broken markers
+}
+
+func (d Drift) String() string {
+	if d.Section == "" {
+		return fmt.Sprintf("%s: %s", d.File, d.Problem)
+	}
+	return fmt.Sprintf("%s: section %q %s", d.File, d.Section, d.Problem)
+}
+
+// Reader returns the content of a file of a repository. Missing files are reported with an error
+// matching os.ErrNotExist.
+type Reader func(file string) ([]byte, error)
+
+// CheckLayout reports the protected sections of the generated files of the repository in dir, a
+// repository of the given kind, that match neither the current template nor the version recorded
+// in its marker. read supplies the content to check, e.g. the staged version of the files; nil
+// reads the working tree. Files without sections from repositories created before sections
+// existed are not checked.
+func CheckLayout(dir string, kind marker.Kind, read Reader) ([]Drift, error) {
+	m, err := marker.Read(dir, kind)
+	if err != nil {
+		return nil, err
+	}
+	if read == nil {
+		read = func(file string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(dir, file))
+		}
+	}
+
+	pack, values, err := packOf(dir, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, file := range Files {
+		theirs, err := TemplateFile(pack, kind, file, values)
+		if err != nil {
+			continue
+		}
+		theirsChunks, err := parse(theirs)
+		if err != nil {
+			return drifts, fmt.Errorf("template %s of %s: %w", file, pack.Name, err)
+		}
+		var protected []chunk
+		for _, c := range theirsChunks {
+			if c.protected {
+				protected = append(protected, c)
+			}
+		}
+		if len(protected) == 0 {
+			continue
+		}
+
+		ours, err := read(file)
+		if errors.Is(err, os.ErrNotExist) {
+			drifts = append(drifts, Drift{File: file, Problem: "file missing"})
+			continue
+		}
+		if err != nil {
+			return drifts, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		oursChunks, err := parse(string(ours))
+		if err != nil {
+			drifts = append(drifts, Drift{File: file, Problem: "broken section markers: " + err.Error()})
+			continue
+		}
+		base := m.Context[file]
+		if len(base) == 0 && !hasSections(oursChunks) {
+			continue
+		}
+		for _, t := range protected {
+			i := indexOf(oursChunks, t.id)
+			if i < 0 {
+				drifts = append(drifts, Drift{File: file, Section: t.id, Problem: "removed"})
+				continue
+			}
+			h := hash(oursChunks[i].content)
+			if h != hash(t.content) && h != base[t.id] {
+				drifts = append(drifts, Drift{File: file, Section: t.id, Problem: "modified"})
+			}
+		}
+	}
+	return drifts, nil
+}
+
+// FixLayout restores the protected sections reported by CheckLayout for the working tree of the
+// repository in dir from the current template, recreating missing files, and records the restored
+// sections in its marker. It returns the drifts it fixed. Text outside the sections is kept.
+func FixLayout(dir string, kind marker.Kind) ([]Drift, error) {
+	drifts, err := CheckLayout(dir, kind, nil)
+	if err != nil || len(drifts) == 0 {
+		return nil, err
+	}
+	m, err := marker.Read(dir, kind)
+	if err != nil {
+		return nil, err
+	}
+	pack, values, err := packOf(dir, m)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string][]string)
+	var files []string
+	for _, d := range drifts {
+		if _, seen := sections[d.File]; !seen {
+			files = append(files, d.File)
+		}
+		sections[d.File] = append(sections[d.File], d.Section)
+	}
+
+	var fixed []Drift
+	for _, file := range files {
+		theirs, err := TemplateFile(pack, kind, file, values)
+		if err != nil {
+			return fixed, err
+		}
+		path := filepath.Join(dir, file)
+		var restored string
+		if ids := sections[file]; len(ids) == 1 && ids[0] == "" {
+			if _, err := os.Stat(path); err == nil {
+				return fixed, fmt.Errorf("cannot restore %s: its section markers are broken; repair them by hand", path)
+			}
+			restored = theirs
+		} else {
+			ours, err := os.ReadFile(path)
+			if err != nil {
+				return fixed, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			if restored, err = restore(string(ours), theirs, ids); err != nil {
+				return fixed, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		if err := fs.WriteFile(path, []byte(restored)); err != nil {
+			return fixed, err
+		}
+
+		hashes, err := Hashes(theirs)
+		if err != nil {
+			return fixed, err
+		}
+		if m.Context == nil {
+			m.Context = make(map[string]map[string]string)
+		}
+		if m.Context[file] == nil {
+			m.Context[file] = make(map[string]string)
+		}
+		for _, d := range drifts {
+			if d.File != file {
+				continue
+			}
+			if d.Section == "" {
+				m.Context[file] = hashes
+			} else {
+				m.Context[file][d.Section] = hashes[d.Section]
+			}
+			fixed = append(fixed, d)
+		}
+	}
+	return fixed, marker.Write(dir, m)
+}
+
+// restore replaces the sections ids of ours with their template version, inserting the ones ours
+// does not have.
+func restore(ours, theirs string, ids []string) (string, error) {
+	oursChunks, err := parse(ours)
+	if err != nil {
+		return "", err
+	}
+	theirsChunks, err := parse(theirs)
+	if err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	var order []string
+	for _, t := range theirsChunks {
+		if t.id == "" {
+			continue
+		}
+		order = append(order, t.id)
+		if !slices.Contains(ids, t.id) {
+			continue
+		}
+		if i := indexOf(oursChunks, t.id); i >= 0 {
+			oursChunks[i].begin, oursChunks[i].content = t.begin, t.content
+		} else {
+			oursChunks = insertSection(oursChunks, t, order[:len(order)-1])
+		}
+	}
+
+	var out strings.Builder
+	for _, c := range oursChunks {
+		out.WriteString(c.String())
+	}
+	return out.String(), nil
+}
+
+// templateOf returns the template the repository described by m was generated from.
+func templateOf(m *marker.Marker) string {
+	if m.Template != "" {
+		return m.Template
+	}
+	return templates.Default(m.Kind)
+}
+
+func hasSections(chunks []chunk) bool {
+	for _, c := range chunks {
+		if c.id != "" {
+			return true
+		}
+	}
+	return false
+}
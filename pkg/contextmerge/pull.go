@@ -0,0 +1,164 @@
+package contextmerge
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/templates"
+)
+
+// TemplateFile returns the content the template pack generates for file rendered with v, falling
+// back to the default template of the kind when the pack does not provide the file.
+func TemplateFile(pack *templates.Pack, kind marker.Kind, file string, v templates.Values) (string, error) {
+	content, err := pack.Content(kind, file, v)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// packOf returns the template pack the repository in dir, described by m, was generated from, and
+// the values its files were rendered with. Parameters added to the pack since then take their defaults,
+// and those it dropped are left out.
+func packOf(dir string, m *marker.Marker) (*templates.Pack, templates.Values, error) {
+	values := templates.ValuesFor(m, dir)
+	root, err := marker.FindRoot(dir)
+	if err != nil {
+		return nil, values, err
+	}
+	pack, err := templates.Find(root, templateOf(m))
+	if err != nil {
+		return nil, values, fmt.Errorf("%s was generated from template %q, which is not available: %w", dir, templateOf(m), err)
+	}
+	// Parameters the pack no longer declares are dropped
+	recorded := maps.Clone(m.Params)
+	undeclared, err := templates.Undeclared(recorded, pack)
+	if err != nil {
+		return nil, values, err
+	}
+	for _, name := range undeclared {
+		delete(recorded, name)
+	}
+	if values.Params, err = pack.ResolveParams(recorded, values, nil); err != nil {
+		return nil, values, err
+	}
+	return pack, values, nil
+}
+
+// Baseline returns the section hashes of the context files generated from the template, for
+// recording in the marker of a new repository.
+func Baseline(pack *templates.Pack, kind marker.Kind, v templates.Values) map[string]map[string]string {
+	baseline := make(map[string]map[string]string)
+	for _, file := range Files {
+		content, err := TemplateFile(pack, kind, file, v)
+		if err != nil {
+			continue
+		}
+		if hashes, err := Hashes(content); err == nil && len(hashes) > 0 {
+			baseline[file] = hashes
+		}
+	}
+	return baseline
+}
+
+// FileResult describes what Pull did with one context file.
+type FileResult struct {
+	File    string
+	Created bool   // The file did not exist and was written from the template
+	Skipped string // Why the file was left alone, if it was
+	*Result
+}
+
+// Pull merges the current templates into the context files of the repository in dir, a repository
+// of the given kind, and records the new base in its marker. With dryRun nothing is written.
+func Pull(dir string, kind marker.Kind, dryRun bool) ([]FileResult, error) {
+	m, err := marker.Read(dir, kind)
+	if err != nil {
+		return nil, err
+	}
+	pack, values, err := packOf(dir, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []FileResult
+	context := make(map[string]map[string]string)
+	changed := m.TemplateVersion != templates.Version
+	for _, file := range Files {
+		theirs, err := TemplateFile(pack, kind, file, values)
+		if err != nil {
+			return results, err
+		}
+		hashes, err := Hashes(theirs)
+		if err != nil {
+			return results, fmt.Errorf("template %s of %s: %w", file, pack.Name, err)
+		}
+		path := filepath.Join(dir, file)
+		result := FileResult{File: file, Result: &Result{}}
+
+		ours, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			result.Created = true
+			if !dryRun {
+				if err := fs.WriteFile(path, []byte(theirs)); err != nil {
+					return results, err
+				}
+			}
+		case err != nil:
+			return results, fmt.Errorf("failed to read %s: %w", path, err)
+		default:
+			base := m.Context[file]
+			if len(base) == 0 {
+				if own, err := Hashes(string(ours)); err == nil && len(own) == 0 {
+					result.Skipped = "has no atelier sections"
+					results = append(results, result)
+					continue
+				}
+			}
+			merged, mergeResult, err := Merge(string(ours), theirs, base)
+			if err != nil {
+				return results, fmt.Errorf("%s: %w", path, err)
+			}
+			result.Result = mergeResult
+			if merged != string(ours) && !dryRun {
+				if err := fs.WriteFile(path, []byte(merged)); err != nil {
+					return results, err
+				}
+			}
+		}
+		if len(hashes) > 0 {
+			context[file] = hashes
+		}
+		if !equalHashes(m.Context[file], hashes) {
+			changed = true
+		}
+		results = append(results, result)
+	}
+
+	if changed && !dryRun {
+		m.Context = context
+		m.TemplateVersion = templates.Version
+		if err := marker.Write(dir, m); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func equalHashes(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, h := range a {
+		if b[id] != h {
+			return false
+		}
+	}
+	return true
+}
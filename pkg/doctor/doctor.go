@@ -0,0 +1,140 @@
+// Package doctor diagnoses the environment the CLI runs in and the consistency of an atelier,
+// and repairs what it safely can.
+package doctor
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/templates"
+)
+
+// MinGitVersion is the oldest git release the CLI is tested with.
+var MinGitVersion = [2]int{2, 31}
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn" // Something optional is missing or worth attention
+	StatusFail Status = "fail" // Something commands rely on is broken
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Scope   string // What was checked, e.g. "environment" or "artist-picasso/canvas-guernica"
+	Status  Status
+	Message string
+	Fix     string       // Suggested fix, if any
+	apply   func() error // Applies the fix; nil if it must be done by hand
+}
+
+// Fixable reports whether Apply can repair the problem.
+func (r *Result) Fixable() bool {
+	return r.apply != nil
+}
+
+// Apply repairs the problem.
+func (r *Result) Apply() error {
+	if r.apply == nil {
+		return fmt.Errorf("%s: no automatic fix", r.Message)
+	}
+	return r.apply()
+}
+
+func ok(scope, format string, args ...any) Result {
+	return Result{Scope: scope, Status: StatusOK, Message: fmt.Sprintf(format, args...)}
+}
+
+// optionalTool is a program some commands or generated files use.
+type optionalTool struct {
+	name    string
+	purpose string
+}
+
+var optionalTools = []optionalTool{
+	{"make", "runs the Makefile targets of the templates and 'atelier make'"},
+	{"gh", "GitHub CLI, for authenticating and working with GitHub remotes"},
+	{"task-master", "task management for AI agents, if your workflow uses it"},
+}
+
+// Environment checks git, the optional tools and the CLI installation.
+func Environment() []Result {
+	const scope = "environment"
+	var results []Result
+
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		return append(results, Result{Scope: scope, Status: StatusFail, Message: "git not found",
+			Fix: "install git " + versionString(MinGitVersion) + " or newer"})
+	}
+	version := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(out)), "git version"))
+	if v, parsed := parseVersion(version); parsed && less(v, MinGitVersion) {
+		results = append(results, Result{Scope: scope, Status: StatusFail,
+			Message: fmt.Sprintf("git %s is older than %s", version, versionString(MinGitVersion)),
+			Fix:     "upgrade git"})
+	} else {
+		results = append(results, ok(scope, "git %s", version))
+	}
+
+	name, _ := gitutil.RunGitCommandOutput(".", "config", "--get", "user.name")
+	email, _ := gitutil.RunGitCommandOutput(".", "config", "--get", "user.email")
+	name, email = strings.TrimSpace(name), strings.TrimSpace(email)
+	if name == "" || email == "" {
+		results = append(results, Result{Scope: scope, Status: StatusFail,
+			Message: "git identity not configured; commits made by the CLI will fail",
+			Fix:     `git config --global user.name "Your Name" && git config --global user.email you@example.com`})
+	} else {
+		results = append(results, ok(scope, "git identity %s <%s>", name, email))
+	}
+
+	for _, tool := range optionalTools {
+		if path, err := exec.LookPath(tool.name); err == nil {
+			results = append(results, ok(scope, "%s found at %s", tool.name, path))
+		} else {
+			results = append(results, Result{Scope: scope, Status: StatusWarn,
+				Message: fmt.Sprintf("%s not found (optional: %s)", tool.name, tool.purpose)})
+		}
+	}
+
+	if _, err := exec.LookPath("atelier-cli"); err != nil {
+		results = append(results, Result{Scope: scope, Status: StatusWarn,
+			Message: "atelier-cli is not on the PATH; the generated Makefile targets and git hooks call it",
+			Fix:     "run 'make install' in the go-atelier repository, or copy the binary into a PATH directory as atelier-cli"})
+	}
+	if missing := templates.MissingAssets(); len(missing) > 0 {
+		results = append(results, Result{Scope: scope, Status: StatusWarn,
+			Message: "template assets missing from this build: " + strings.Join(missing, ", "),
+			Fix:     "rebuild the CLI from a complete checkout"})
+	} else {
+		results = append(results, ok(scope, "template assets complete"))
+	}
+	return results
+}
+
+// parseVersion parses the major and minor number of a version such as "2.39.5".
+func parseVersion(version string) ([2]int, bool) {
+	fields := strings.Fields(version)
+	if len(fields) == 0 {
+		return [2]int{}, false
+	}
+	parts := strings.SplitN(fields[0], ".", 3)
+	if len(parts) < 2 {
+		return [2]int{}, false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	return [2]int{major, minor}, err1 == nil && err2 == nil
+}
+
+func less(a, b [2]int) bool {
+	return a[0] < b[0] || a[0] == b[0] && a[1] < b[1]
+}
+
+func versionString(v [2]int) string {
+	return fmt.Sprintf("%d.%d", v[0], v[1])
+}
@@ -0,0 +1,194 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    [2]int
+		ok      bool
+	}{
+		{"2.39.5", [2]int{2, 39}, true},
+		{"2.31.1.windows.1", [2]int{2, 31}, true},
+		{"2.50.1 (Apple Git-155)", [2]int{2, 50}, true},
+		{"2", [2]int{}, false},
+		{"x.y", [2]int{}, false},
+		{"", [2]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.version)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("parseVersion(%q) = %v, %v; want %v, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	tests := []struct {
+		a, b [2]int
+		want bool
+	}{
+		{[2]int{2, 30}, [2]int{2, 31}, true},
+		{[2]int{1, 99}, [2]int{2, 0}, true},
+		{[2]int{2, 31}, [2]int{2, 31}, false},
+		{[2]int{3, 0}, [2]int{2, 31}, false},
+	}
+	for _, tt := range tests {
+		if got := less(tt.a, tt.b); got != tt.want {
+			t.Errorf("less(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	tests := []struct {
+		name    string
+		damage  func(t *testing.T, w *gittest.Workspace, artist string)
+		scope   string
+		message string
+	}{
+		{
+			name: "marker naming another artist",
+			damage: func(t *testing.T, w *gittest.Workspace, artist string) {
+				canvas := filepath.Join(artist, "canvas-guernica")
+				if err := marker.Write(canvas, marker.New(marker.KindCanvas, "atelier-demo", "artist-monet", "canvas-guernica")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			scope:   "artist-picasso/canvas-guernica",
+			message: `artist "artist-monet" instead of "artist-picasso"`,
+		},
+		{
+			name: "missing marker",
+			damage: func(t *testing.T, w *gittest.Workspace, artist string) {
+				if err := os.Remove(marker.Path(artist, marker.KindArtist)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			scope:   "artist-picasso",
+			message: ".artist marker missing",
+		},
+		{
+			name: "unregistered canvas",
+			damage: func(t *testing.T, w *gittest.Workspace, artist string) {
+				canvas := filepath.Join(artist, "canvas-dora")
+				if err := os.MkdirAll(canvas, 0755); err != nil {
+					t.Fatal(err)
+				}
+				gittest.Run(t, canvas, "init", "--quiet")
+				if err := marker.Write(canvas, marker.New(marker.KindCanvas, "atelier-demo", "artist-picasso", "canvas-dora")); err != nil {
+					t.Fatal(err)
+				}
+				gittest.Run(t, canvas, "add", "-A")
+				gittest.Run(t, canvas, "commit", "-m", "Initial commit")
+			},
+			scope:   "artist-picasso",
+			message: "canvas-dora is not registered as a submodule",
+		},
+		{
+			name: "stale .gitmodules entry",
+			damage: func(t *testing.T, w *gittest.Workspace, artist string) {
+				gittest.Run(t, artist, "rm", "--cached", "--quiet", "canvas-guernica")
+				if err := os.RemoveAll(filepath.Join(artist, "canvas-guernica")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			scope:   "artist-picasso",
+			message: ".gitmodules lists canvas-guernica, which is neither in the index nor a repository",
+		},
+		{
+			name: "submodule not checked out",
+			damage: func(t *testing.T, w *gittest.Workspace, artist string) {
+				gittest.Run(t, artist, "submodule", "deinit", "--quiet", "--force", "canvas-guernica")
+			},
+			scope:   "artist-picasso",
+			message: "canvas-guernica is a submodule but is not checked out",
+		},
+		{
+			name: "orphaned git directory",
+			damage: func(t *testing.T, w *gittest.Workspace, artist string) {
+				gitDir := gittest.Run(t, artist, "rev-parse", "--absolute-git-dir")
+				orphan := filepath.Join(gitDir, "modules", "canvas-old")
+				gittest.Run(t, artist, "init", "--quiet", "--bare", orphan)
+			},
+			scope:   "artist-picasso",
+			message: "orphaned git directory .git/modules/canvas-old",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := gittest.New(t, "artist-picasso/canvas-guernica")
+			artist := filepath.Join(w.Root, "artist-picasso")
+			tt.damage(t, w, artist)
+
+			results, err := Workspace(w.Root)
+			if err != nil {
+				t.Fatalf("Workspace: %v", err)
+			}
+			var found *Result
+			for i := range results {
+				if results[i].Scope == tt.scope && strings.Contains(results[i].Message, tt.message) {
+					found = &results[i]
+				}
+			}
+			if found == nil {
+				t.Fatalf("Workspace = %v, want a result for %s containing %q", results, tt.scope, tt.message)
+			}
+			if !found.Fixable() {
+				t.Fatalf("%q has no automatic fix", found.Message)
+			}
+			if err := found.Apply(); err != nil {
+				t.Fatalf("fix %q: %v", found.Fix, err)
+			}
+
+			// A fix can surface a follow-up problem, such as the git
+			// directory a removed .gitmodules entry leaves behind.
+			for round := 0; ; round++ {
+				if results, err = Workspace(w.Root); err != nil {
+					t.Fatalf("Workspace after the fix: %v", err)
+				}
+				fixable := false
+				for _, r := range results {
+					if r.Fixable() {
+						fixable = true
+						if err := r.Apply(); err != nil {
+							t.Fatalf("fix %q: %v", r.Fix, err)
+						}
+					}
+				}
+				if !fixable || round == 3 {
+					break
+				}
+			}
+			if len(results) != 1 || results[0].Status != StatusOK {
+				t.Errorf("Workspace after the fix = %v, want a single ok", results)
+			}
+		})
+	}
+}
+
+func TestWorkspaceKeepsGitDirsForUndo(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	artist := filepath.Join(w.Root, "artist-picasso")
+	if err := engine.DeleteCanvas(artist, "canvas-guernica", engine.DeleteOptions{Commit: true}); err != nil {
+		t.Fatal(err)
+	}
+	results, err := Workspace(w.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			t.Errorf("Workspace reports %s: %s, but the git directory is kept for undo", r.Scope, r.Message)
+		}
+	}
+}
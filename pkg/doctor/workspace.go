@@ -0,0 +1,324 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// Workspace audits the atelier at atelierPath: markers consistent with the directory names,
+// .gitmodules entries matching the directories and the index, orphaned .git/modules entries and
+// artist or canvas directories that are not registered as submodules.
+func Workspace(atelierPath string) ([]Result, error) {
+	kept, err := keptForUndo(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	a := &audit{root: atelierPath, atelier: filepath.Base(atelierPath), kept: kept}
+
+	a.checkMarker(atelierPath, marker.KindAtelier, "", "")
+	if err := a.checkSubmodules(atelierPath, marker.KindArtist); err != nil {
+		return a.results, err
+	}
+	artists, err := marker.ChildDirs(atelierPath, marker.KindArtist)
+	if err != nil {
+		return a.results, err
+	}
+	for _, artistPath := range artists {
+		artist := filepath.Base(artistPath)
+		if !isRepo(artistPath) {
+			continue // Reported by checkSubmodules
+		}
+		a.checkMarker(artistPath, marker.KindArtist, artist, "")
+		if err := a.checkSubmodules(artistPath, marker.KindCanvas); err != nil {
+			return a.results, err
+		}
+		canvases, err := marker.ChildDirs(artistPath, marker.KindCanvas)
+		if err != nil {
+			return a.results, err
+		}
+		for _, canvasPath := range canvases {
+			if isRepo(canvasPath) {
+				a.checkMarker(canvasPath, marker.KindCanvas, artist, filepath.Base(canvasPath))
+			}
+		}
+	}
+
+	if len(a.results) == 0 {
+		a.results = append(a.results, ok(a.atelier, "markers, submodules and git directories consistent"))
+	}
+	return a.results, nil
+}
+
+// audit collects the results of a workspace audit.
+type audit struct {
+	root    string
+	atelier string
+	kept    map[string]bool // Submodule paths whose git directories are kept for 'atelier undo'
+	results []Result
+}
+
+func (a *audit) scope(dir string) string {
+	name, err := filepath.Rel(a.root, dir)
+	if err != nil || name == "." {
+		return a.atelier
+	}
+	return filepath.ToSlash(name)
+}
+
+func (a *audit) add(dir string, status Status, message, fix string, apply func() error) {
+	a.results = append(a.results, Result{Scope: a.scope(dir), Status: status, Message: message, Fix: fix, apply: apply})
+}
+
+// checkMarker checks that the marker of kind in dir exists, uses the current schema and names the
+// directories it lives in.
+func (a *audit) checkMarker(dir string, kind marker.Kind, artist, canvas string) {
+	if !marker.Exists(dir, kind) {
+		a.add(dir, StatusFail, kind.FileName()+" marker missing", "write a new "+kind.FileName()+" marker", func() error {
+			return marker.Write(dir, marker.New(kind, a.atelier, artist, canvas))
+		})
+		return
+	}
+	m, err := marker.Read(dir, kind)
+	if err != nil {
+		a.add(dir, StatusFail, err.Error(), "repair or delete the "+kind.FileName()+" file, then run 'atelier doctor --fix'", nil)
+		return
+	}
+
+	var wrong []string
+	for _, field := range []struct{ name, got, want string }{
+		{"atelier", m.Atelier, a.atelier},
+		{"artist", m.Artist, artist},
+		{"canvas", m.Canvas, canvas},
+	} {
+		if field.got != field.want {
+			wrong = append(wrong, fmt.Sprintf("%s %q instead of %q", field.name, field.got, field.want))
+		}
+	}
+	fix := func() error {
+		m.Atelier, m.Artist, m.Canvas = a.atelier, artist, canvas
+		return marker.Write(dir, m)
+	}
+	switch {
+	case len(wrong) > 0:
+		a.add(dir, StatusFail, kind.FileName()+" marker names "+strings.Join(wrong, ", "),
+			"rewrite the marker with the directory names", fix)
+	case m.Legacy():
+		a.add(dir, StatusWarn, kind.FileName()+" marker uses the legacy format", "rewrite it with the current schema", fix)
+	}
+}
+
+// checkSubmodules checks the .gitmodules entries, the submodules in the index and the child
+// directories of kind of the repository at dir against each other, and looks for git directories
+// under .git/modules that no submodule uses.
+func (a *audit) checkSubmodules(dir string, childKind marker.Kind) error {
+	entries, err := gitmodulesEntries(dir)
+	if err != nil {
+		return err
+	}
+	gitlinks, err := indexGitlinks(dir)
+	if err != nil {
+		return err
+	}
+	children, err := marker.ChildDirs(dir, childKind)
+	if err != nil {
+		return err
+	}
+
+	paths := make(map[string]string, len(entries)) // path -> submodule name
+	for name, path := range entries {
+		paths[path] = name
+	}
+	for _, name := range sortedKeys(entries) {
+		path := entries[name]
+		childPath := filepath.Join(dir, path)
+		switch {
+		case !gitlinks[path] && !isRepo(childPath):
+			a.add(dir, StatusFail, fmt.Sprintf(".gitmodules lists %s, which is neither in the index nor a repository", path),
+				"remove the stale .gitmodules entry", func() error { return removeGitmodulesEntry(dir, name) })
+		case gitlinks[path] && !isRepo(childPath):
+			a.add(dir, StatusFail, path+" is a submodule but is not checked out",
+				"git submodule update --init -- "+path, func() error { return gitutil.InitSubmodule(dir, path) })
+		}
+	}
+	for _, path := range sortedKeys(gitlinks) {
+		if _, registered := paths[path]; !registered {
+			a.add(dir, StatusFail, path+" is a submodule in the index without a .gitmodules entry",
+				fmt.Sprintf("git rm --cached %s, then register it again with 'git submodule add ./%s %s'", path, path, path), nil)
+		}
+	}
+
+	for _, childPath := range children {
+		path := filepath.Base(childPath)
+		if gitlinks[path] {
+			continue
+		}
+		message := fmt.Sprintf("%s is not registered as a submodule", path)
+		if !isRepo(childPath) || !hasCommits(childPath) {
+			a.add(dir, StatusFail, message+" and is not a git repository with commits",
+				fmt.Sprintf("run 'git init' and commit in %s, or remove the directory", path), nil)
+			continue
+		}
+		a.add(dir, StatusFail, message, "register it with 'git submodule add ./"+path+" "+path+"'", func() error {
+			if name, stale := paths[path]; stale {
+				if err := removeGitmodulesEntry(dir, name); err != nil {
+					return err
+				}
+			}
+			return gitutil.AddSubmodule(dir, path)
+		})
+	}
+
+	gitDir, err := gitutil.GitDir(dir)
+	if err != nil {
+		return err
+	}
+	modules := filepath.Join(gitDir, "modules")
+	dirs, err := os.ReadDir(modules)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not read %s: %w", modules, err)
+	}
+	linked, err := linkedGitDirs(dir, append(sortedKeys(gitlinks), sortedKeys(paths)...))
+	if err != nil {
+		return err
+	}
+	for _, entry := range dirs {
+		name := entry.Name()
+		moduleDir := filepath.Join(modules, name)
+		// A submodule whose .gitmodules entry is lost still uses its git directory
+		if _, used := entries[name]; used || gitlinks[name] || linked[filepath.Clean(moduleDir)] {
+			continue
+		}
+		if !entry.IsDir() || a.kept[filepath.Join(dir, name)] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(moduleDir, "HEAD")); err != nil {
+			continue
+		}
+		a.add(dir, StatusWarn, fmt.Sprintf("orphaned git directory .git/modules/%s; it blocks adding a submodule named %s again", name, name),
+			"move "+moduleDir+" to the trash of the operation log ('atelier undo' restores it)", func() error {
+				return engine.Trash(a.root, "doctor --fix", moduleDir)
+			})
+	}
+	return nil
+}
+
+// linkedGitDirs returns the git directories that the .git files of the working trees in the
+// subdirectories of dir and at the given paths below it point at, as absolute paths.
+func linkedGitDirs(dir string, paths []string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != ".git" {
+			paths = append(paths, entry.Name())
+		}
+	}
+	linked := make(map[string]bool)
+	for _, path := range paths {
+		worktree := filepath.Join(dir, filepath.FromSlash(path))
+		content, err := os.ReadFile(filepath.Join(worktree, ".git"))
+		if err != nil {
+			continue // No working tree, or one with its own .git directory
+		}
+		gitDir, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+		if !found {
+			continue
+		}
+		if gitDir = strings.TrimSpace(gitDir); !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(worktree, gitDir)
+		}
+		linked[filepath.Clean(gitDir)] = true
+	}
+	return linked, nil
+}
+
+// gitmodulesEntries returns the submodules of the .gitmodules file of the repository at dir, by name.
+func gitmodulesEntries(dir string) (map[string]string, error) {
+	entries := make(map[string]string)
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	// Exits with 1 when the file has no entries
+	out, _ := gitutil.RunGitCommandOutput(dir, "config", "-f", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		key, path, found := strings.Cut(line, " ")
+		if found {
+			entries[strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path")] = path
+		}
+	}
+	return entries, nil
+}
+
+// indexGitlinks returns the paths of the submodules recorded in the index of the repository at dir.
+func indexGitlinks(dir string) (map[string]bool, error) {
+	out, err := gitutil.RunGitCommandOutput(dir, "ls-files", "--stage")
+	if err != nil {
+		return nil, err
+	}
+	gitlinks := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		// Format: "160000 <sha> <stage>\t<path>"
+		info, path, found := strings.Cut(line, "\t")
+		if found && strings.HasPrefix(info, "160000 ") {
+			gitlinks[path] = true
+		}
+	}
+	return gitlinks, nil
+}
+
+// removeGitmodulesEntry removes the named submodule from the .gitmodules file of the repository at
+// dir and stages the change.
+func removeGitmodulesEntry(dir, name string) error {
+	if err := gitutil.RunGitCommand(dir, "config", "-f", ".gitmodules", "--remove-section", "submodule."+name); err != nil {
+		return err
+	}
+	return gitutil.AddPaths(dir, ".gitmodules")
+}
+
+// keptForUndo returns the paths of the submodules removed by operations in the operation log that
+// can still be undone. Their git directories stay under .git/modules so that undo can restore them.
+func keptForUndo(atelierPath string) (map[string]bool, error) {
+	entries, err := engine.OperationLog(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]bool)
+	for _, j := range entries {
+		for _, step := range j.Steps {
+			if step.Kind == engine.StepRemove && !step.Undone {
+				kept[filepath.Clean(step.Path)] = true
+			}
+		}
+	}
+	return kept, nil
+}
+
+// isRepo reports whether dir is the top of a git working tree of its own.
+func isRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// hasCommits reports whether the repository at dir has a commit checked out.
+func hasCommits(dir string) bool {
+	_, err := gitutil.HeadCommit(dir)
+	return err == nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
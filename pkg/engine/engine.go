@@ -2,22 +2,33 @@ package engine
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/frquxl/go-atelier/pkg/contextmerge"
+	"github.com/frquxl/go-atelier/pkg/forge"
 	"github.com/frquxl/go-atelier/pkg/fs"
 	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/guard"
+	"github.com/frquxl/go-atelier/pkg/marker"
 	"github.com/frquxl/go-atelier/pkg/templates"
-	"github.com/frquxl/go-atelier/pkg/util"
 )
 
-// CreateAtelier initializes the main atelier directory and repository.
-func CreateAtelier(basePath, atelierBaseName string) (atelierPath string, err error) {
+// CreateAtelier initializes the main atelier directory and repository from the template pack named
+// in opts, or from the default atelier template. opts.Forge is not used.
+func CreateAtelier(basePath, atelierBaseName string, opts CreateOptions) (atelierPath string, err error) {
 	ateliersDirName := "atelier-" + atelierBaseName
 	atelierPath = filepath.Join(basePath, ateliersDirName)
 
-	_ = templates.TemplatesFS // Dummy reference to prevent unused import error
+	// The atelier does not exist yet, so only the user's and the embedded packs are available
+	atelierMarker := marker.New(marker.KindAtelier, ateliersDirName, "", "")
+	pack, values, err := prepareTemplate("", basePath, atelierMarker, opts)
+	if err != nil {
+		return "", err
+	}
 
 	defer func() {
 		if err != nil {
@@ -34,22 +45,19 @@ func CreateAtelier(basePath, atelierBaseName string) (atelierPath string, err er
 		return "", err
 	}
 	// Write marker file
-	if err = fs.WriteFile(filepath.Join(atelierPath, ".atelier"), []byte(ateliersDirName)); err != nil {
+	if err = marker.Write(atelierPath, atelierMarker); err != nil {
 		return "", err
 	}
-	// Create boilerplate files
-	if err = templates.CreateBoilerplate(atelierPath, "atelier"); err != nil {
+	// Write the template files and run its post-create commands
+	files, err := pack.Create(atelierPath, marker.KindAtelier, values)
+	if err != nil {
+		return "", err
+	}
+	if _, err = guard.WriteAgentFiles(atelierPath); err != nil {
 		return "", err
 	}
 	// An initial commit is needed before adding submodules.
-	if err = gitutil.AddPaths(atelierPath, existingPaths(atelierPath, []string{
-		".atelier",
-		"README.md",
-		"AGENTS.md",
-		"Makefile",
-		".gitignore",
-		".geminiignore",
-	})...); err != nil {
+	if err = gitutil.AddPaths(atelierPath, initialPaths(atelierPath, marker.KindAtelier, files)...); err != nil {
 		return "", err
 	}
 	if err = gitutil.Commit(atelierPath, fmt.Sprintf("feat: initialize atelier %s", atelierBaseName)); err != nil {
@@ -59,18 +67,49 @@ func CreateAtelier(basePath, atelierBaseName string) (atelierPath string, err er
 	return atelierPath, nil
 }
 
+// CreateOptions customises how ateliers, artists and canvases are created.
+type CreateOptions struct {
+	Template string             // Template pack to use (e.g. "artist-sketch"); derived from the name when empty
+	Params   map[string]string  // Values of template parameters; offered to every template used
+	Prompt   templates.Prompter // Asks for the parameters without a value; their defaults are used when nil
+	Forge    forge.Forge        // Provisions an origin remote and pushes the new repository when set
+}
+
 // CreateArtist initializes a new artist and a default canvas within an atelier.
-func CreateArtist(atelierPath, artistName, canvasName string) (err error) {
+func CreateArtist(atelierPath, artistName, canvasName string, opts CreateOptions) (err error) {
 	ateliersDirName := filepath.Base(atelierPath)
 	artistDirName := "artist-" + artistName
 	artistPath := filepath.Join(atelierPath, artistDirName)
 
-	defer func() {
-		if err != nil {
-			fmt.Printf("Artist initialization failed, cleaning up directory: %s\n", artistPath)
-			os.RemoveAll(artistPath)
+	artistMarker := marker.New(marker.KindArtist, ateliersDirName, artistDirName, "")
+	pack, values, err := prepareTemplate(atelierPath, atelierPath, artistMarker, opts)
+	if err != nil {
+		return err
+	}
+
+	tx, finish, err := begin(atelierPath, "artist init", artistName)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.Track(atelierPath); err != nil {
+		return err
+	}
+	if err = tx.Created(artistPath); err != nil {
+		return err
+	}
+
+	var remoteURL string
+	if opts.Forge != nil {
+		if remoteURL, err = createRemote(opts.Forge, artistDirName); err != nil {
+			return err
 		}
-	}()
+		defer func() {
+			if err != nil {
+				deleteRemote(opts.Forge, artistDirName)
+			}
+		}()
+	}
 
 	fmt.Println("Initializing artist...")
 	if err = fs.CreateDir(artistPath); err != nil {
@@ -79,33 +118,25 @@ func CreateArtist(atelierPath, artistName, canvasName string) (err error) {
 	if err = gitutil.Init(artistPath); err != nil {
 		return err
 	}
+
 	// Write marker file
-	artistContext := fmt.Sprintf("%s\n%s", ateliersDirName, artistDirName)
-	if err = fs.WriteFile(filepath.Join(artistPath, ".artist"), []byte(artistContext)); err != nil {
+	if remoteURL != "" {
+		artistMarker.Remote = remoteMarker(opts.Forge, remoteURL)
+	}
+	if err = marker.Write(artistPath, artistMarker); err != nil {
 		return err
 	}
 
-	// Determine which template to use based on artistName
-	templateType := "artist-default"
-	if artistName == "sketch" {
-		templateType = "artist-sketch"
-	} else if artistName == "gallery" {
-		templateType = "artist-gallery"
+	// Write the template files and run its post-create commands
+	files, err := pack.Create(artistPath, marker.KindArtist, values)
+	if err != nil {
+		return err
 	}
-
-	// Create boilerplate files
-	if err = templates.CreateBoilerplate(artistPath, templateType); err != nil {
+	if _, err = guard.WriteAgentFiles(artistPath); err != nil {
 		return err
 	}
 	// Stage changes (marker + boilerplate)
-	if err = gitutil.AddPaths(artistPath, existingPaths(artistPath, []string{
-		".artist",
-		"README.md",
-		"AGENTS.md",
-		"Makefile",
-		".gitignore",
-		".geminiignore",
-	})...); err != nil {
+	if err = gitutil.AddPaths(artistPath, initialPaths(artistPath, marker.KindArtist, files)...); err != nil {
 		return err
 	}
 	if err = gitutil.Commit(artistPath, fmt.Sprintf("feat: initialize artist %s", artistName)); err != nil {
@@ -114,14 +145,20 @@ func CreateArtist(atelierPath, artistName, canvasName string) (err error) {
 
 	// 2. Create and initialize default Canvas for the artist (if specified)
 	if canvasName != "" {
-		if err = CreateCanvas(artistPath, canvasName); err != nil {
+		if err = CreateCanvas(artistPath, canvasName, CreateOptions{Params: opts.Params, Prompt: opts.Prompt, Forge: opts.Forge}); err != nil {
+			return err
+		}
+	}
+
+	if remoteURL != "" {
+		if err = publish(opts.Forge, artistPath, remoteURL); err != nil {
 			return err
 		}
 	}
 
 	// 3. Link artist to atelier
 	fmt.Println("Connecting artist to atelier...")
-	if err = gitutil.AddSubmodule(atelierPath, artistDirName); err != nil {
+	if err = linkSubmodule(atelierPath, artistDirName, remoteURL); err != nil {
 		return err
 	}
 	// Stage .gitmodules and submodule path
@@ -136,27 +173,47 @@ func CreateArtist(atelierPath, artistName, canvasName string) (err error) {
 }
 
 // CreateCanvas initializes a new canvas within an artist's workspace.
-func CreateCanvas(artistPath string, canvasName string) (err error) {
-	artistContent, err := os.ReadFile(filepath.Join(artistPath, ".artist"))
+func CreateCanvas(artistPath string, canvasName string, opts CreateOptions) (err error) {
+	artistMarker, err := marker.Read(artistPath, marker.KindArtist)
 	if err != nil {
-		return fmt.Errorf("could not read .artist file in %s: %w", artistPath, err)
-	}
-	artistLines := strings.Split(strings.TrimSpace(string(artistContent)), "\n")
-	if len(artistLines) < 2 {
-		return fmt.Errorf("invalid .artist file format")
+		return err
 	}
-	ateliersName := artistLines[0]
-	artistDirName := artistLines[1]
-
 	canvasDirName := "canvas-" + canvasName
 	canvasPath := filepath.Join(artistPath, canvasDirName)
 
-	defer func() {
-		if err != nil {
-			fmt.Printf("Canvas initialization failed, cleaning up directory: %s\n", canvasPath)
-			os.RemoveAll(canvasPath)
+	atelierPath, err := marker.FindRoot(artistPath)
+	if err != nil {
+		return err
+	}
+	canvasMarker := marker.New(marker.KindCanvas, artistMarker.Atelier, artistMarker.Artist, canvasDirName)
+	pack, values, err := prepareTemplate(atelierPath, artistPath, canvasMarker, opts)
+	if err != nil {
+		return err
+	}
+
+	tx, finish, err := begin(artistPath, "canvas init", canvasName)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.Track(artistPath); err != nil {
+		return err
+	}
+	if err = tx.Created(canvasPath); err != nil {
+		return err
+	}
+
+	var remoteURL string
+	if opts.Forge != nil {
+		if remoteURL, err = createRemote(opts.Forge, canvasDirName); err != nil {
+			return err
 		}
-	}()
+		defer func() {
+			if err != nil {
+				deleteRemote(opts.Forge, canvasDirName)
+			}
+		}()
+	}
 
 	fmt.Println("Initializing canvas...")
 	if err = fs.CreateDir(canvasPath); err != nil {
@@ -166,46 +223,37 @@ func CreateCanvas(artistPath string, canvasName string) (err error) {
 		return err
 	}
 	// Write marker file
-	canvasContext := fmt.Sprintf("%s\n%s\n%s", ateliersName, artistDirName, canvasDirName)
-	if err = fs.WriteFile(filepath.Join(canvasPath, ".canvas"), []byte(canvasContext)); err != nil {
-		return err
+	if remoteURL != "" {
+		canvasMarker.Remote = remoteMarker(opts.Forge, remoteURL)
 	}
-	// Create boilerplate files
-	if err = templates.CreateBoilerplate(canvasPath, "canvas"); err != nil {
+	if err = marker.Write(canvasPath, canvasMarker); err != nil {
 		return err
 	}
-
-	// --- Special handling for 'sunflowers' canvas ---
-	if canvasName == "sunflowers" {
-		fmt.Println("Configuring 'sunflowers' canvas with Van Gogh CLI...")
-		if err = copySunflowersAssets(canvasPath); err != nil {
-			return fmt.Errorf("failed to copy 'sunflowers' assets: %w", err)
-		}
-	}
-
-	// Stage changes (marker + boilerplate + special assets)
-	pathsToCommit := []string{
-		".canvas",
-		"README.md",
-		"AGENTS.md",
-		"Makefile",
-		".gitignore",
-		".geminiignore",
+	// Write the template files and run its post-create commands
+	files, err := pack.Create(canvasPath, marker.KindCanvas, values)
+	if err != nil {
+		return err
 	}
-	if canvasName == "sunflowers" {
-		pathsToCommit = append(pathsToCommit, "vincent")
+	if _, err = guard.WriteAgentFiles(canvasPath); err != nil {
+		return err
 	}
 
-	if err = gitutil.AddPaths(canvasPath, existingPaths(canvasPath, pathsToCommit)...); err != nil {
+	// Stage changes (marker + template files)
+	if err = gitutil.AddPaths(canvasPath, initialPaths(canvasPath, marker.KindCanvas, files)...); err != nil {
 		return err
 	}
 	if err = gitutil.Commit(canvasPath, fmt.Sprintf("feat: initialize canvas %s", canvasName)); err != nil {
 		return err
 	}
+	if remoteURL != "" {
+		if err = publish(opts.Forge, canvasPath, remoteURL); err != nil {
+			return err
+		}
+	}
 
 	// 2. Link canvas to artist
 	fmt.Println("Connecting canvas to artist...")
-	if err = gitutil.AddSubmodule(artistPath, canvasDirName); err != nil {
+	if err = linkSubmodule(artistPath, canvasDirName, remoteURL); err != nil {
 		return err
 	}
 	// Stage .gitmodules and the submodule path
@@ -219,102 +267,196 @@ func CreateCanvas(artistPath string, canvasName string) (err error) {
 	return nil
 }
 
-// copySunflowersAssets copies the specific assets for the sunflowers canvas.
-func copySunflowersAssets(destPath string) error {
-	// Define the files to copy from the embedded assets
-	filesToCopy := map[string]os.FileMode{
-		"assets/canvas-sunflowers/README.md": 0644,
-		"assets/canvas-sunflowers/vincent":   0755, // Executable
+// resolvePack returns the template pack for a new repository of kind in the atelier at atelierPath:
+// the named template if one is given, else a pack named like the new directory (e.g. artist-sketch
+// for the artist sketch), else the default template of kind.
+func resolvePack(atelierPath, template string, kind marker.Kind, dirName string) (*templates.Pack, error) {
+	if template != "" {
+		return templates.Find(atelierPath, template)
 	}
-
-	for src, perm := range filesToCopy {
-		content, err := templates.TemplatesFS.ReadFile(src)
-		if err != nil {
-			return fmt.Errorf("failed to read embedded asset %s: %w", src, err)
+	if dirName != "" {
+		if pack, err := templates.Find(atelierPath, dirName); err == nil {
+			return pack, nil
 		}
+	}
+	return templates.Find(atelierPath, templates.Default(kind))
+}
 
-		dest := filepath.Join(destPath, filepath.Base(src))
+// TemplateUse is a repository a command generates from a template pack: the pack named Template, else
+// the default pack for Kind and DirName.
+type TemplateUse struct {
+	Template string
+	Kind     marker.Kind
+	DirName  string // Directory name of the repository, e.g. artist-sketch; empty for an atelier
+}
 
-		// Overwrite the default README with the themed one, and write the binary.
-		if err := os.WriteFile(dest, content, perm); err != nil {
-			return fmt.Errorf("failed to write asset %s: %w", dest, err)
+// CheckParams returns an error if params, the template parameters given to a command, hold one that
+// none of the packs of uses declares, so a mistyped --set fails before anything is created. atelierPath
+// is empty when the atelier is yet to be created.
+func CheckParams(atelierPath string, params map[string]string, uses ...TemplateUse) error {
+	if len(params) == 0 {
+		return nil
+	}
+	var packs []*templates.Pack
+	var names []string
+	for _, use := range uses {
+		pack, err := resolvePack(atelierPath, use.Template, use.Kind, use.DirName)
+		if err != nil {
+			return err
+		}
+		packs = append(packs, pack)
+		if !slices.Contains(names, pack.Name) {
+			names = append(names, pack.Name)
 		}
 	}
+	undeclared, err := templates.Undeclared(params, packs...)
+	if err != nil {
+		return err
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("no template used (%s) declares parameter %s", strings.Join(names, ", "), strings.Join(undeclared, ", "))
+	}
 	return nil
 }
 
+// prepareTemplate resolves the template pack and the parameters of the new repository described by
+// m in the atelier at atelierPath, asking for missing parameters, and records them in m. dir is an
+// existing directory the author is looked up from.
+func prepareTemplate(atelierPath, dir string, m *marker.Marker, opts CreateOptions) (*templates.Pack, templates.Values, error) {
+	dirName := m.Name()
+	if m.Kind == marker.KindAtelier {
+		dirName = ""
+	}
+	values := templates.ValuesFor(m, dir)
+	pack, err := resolvePack(atelierPath, opts.Template, m.Kind, dirName)
+	if err != nil {
+		return nil, values, err
+	}
+	// Params are offered to every template a command uses, so each pack takes the ones it declares
+	given := maps.Clone(opts.Params)
+	undeclared, err := templates.Undeclared(given, pack)
+	if err != nil {
+		return nil, values, err
+	}
+	for _, name := range undeclared {
+		delete(given, name)
+	}
+	if values.Params, err = pack.ResolveParams(given, values, opts.Prompt); err != nil {
+		return nil, values, err
+	}
+	m.Template, m.TemplateVersion = pack.Name, templates.Version
+	if len(values.Params) > 0 {
+		m.Params = values.Params
+	}
+	m.Context = contextmerge.Baseline(pack, m.Kind, values)
+	return pack, values, nil
+}
+
 // DeleteArtist deletes an artist studio and removes it from Git tracking.
-func DeleteArtist(atelierPath, artistFullName string) (err error) {
+func DeleteArtist(atelierPath, artistFullName string, opts DeleteOptions) error {
 	artistPath := filepath.Join(atelierPath, artistFullName)
 
-	defer func() {
-		if err != nil {
-			fmt.Printf("Artist deletion failed, directory %s might need manual cleanup.\n", artistPath)
-		}
-	}()
-
 	fmt.Printf("Deleting artist %s...\n", artistFullName)
 
-	// 1. Deinitialize the submodule
-	if err = gitutil.SubmoduleDeinit(atelierPath, artistFullName); err != nil {
-		return fmt.Errorf("failed to deinitialize artist submodule: %w", err)
+	// Resolve remote repositories before the working trees are gone
+	var remotes []string
+	if opts.Forge != nil {
+		remotes = forgeRepos(opts.Forge, artistPath, marker.KindArtist)
 	}
 
-	// 2. Remove the submodule entry from .gitmodules and index
-	if err = gitutil.Remove(atelierPath, artistFullName); err != nil {
-		return fmt.Errorf("failed to remove artist from git tracking: %w", err)
+	var message string
+	if opts.Commit {
+		message = fmt.Sprintf("feat: remove artist %s", artistFullName)
 	}
-
-	// 3. Remove the actual directory
-	if err = os.RemoveAll(artistPath); err != nil {
-		return fmt.Errorf("failed to remove artist directory: %w", err)
+	if err := removeSubmodule(atelierPath, artistFullName, "artist delete", message); err != nil {
+		return fmt.Errorf("failed to delete artist: %w", err)
+	}
+	if err := deleteRemotes(opts.Forge, remotes); err != nil {
+		return err
 	}
 
-	// No automatic commit here. User is responsible for committing the changes.
-	fmt.Printf("Artist '%s' deleted. Remember to 'git add %s' and 'git commit' in the parent repository.\n", artistFullName, artistFullName)
+	fmt.Printf("Artist '%s' deleted. %s\n", artistFullName, deleteHint(filepath.Base(atelierPath), opts.Commit, remotes))
 	return nil
 }
 
 // DeleteCanvas deletes a canvas and removes it from Git tracking.
-func DeleteCanvas(artistPath, canvasFullName string) (err error) {
+func DeleteCanvas(artistPath, canvasFullName string, opts DeleteOptions) error {
 	canvasPath := filepath.Join(artistPath, canvasFullName)
 
-	defer func() {
-		if err != nil {
-			fmt.Printf("Canvas deletion failed, directory %s might need manual cleanup.\n", canvasPath)
-		}
-	}()
-
 	fmt.Printf("Deleting canvas %s...\n", canvasFullName)
 
-	// 1. Deinitialize the submodule
-	if err = gitutil.SubmoduleDeinit(artistPath, canvasFullName); err != nil {
-		return fmt.Errorf("failed to deinitialize canvas submodule: %w", err)
+	// Resolve the remote repository before the working tree is gone
+	var remotes []string
+	if opts.Forge != nil {
+		remotes = forgeRepos(opts.Forge, canvasPath, marker.KindCanvas)
 	}
 
-	// 2. Remove the submodule entry from .gitmodules and index
-	if err = gitutil.Remove(artistPath, canvasFullName); err != nil {
-		return fmt.Errorf("failed to remove canvas from git tracking: %w", err)
+	var message string
+	if opts.Commit {
+		message = fmt.Sprintf("feat: remove canvas %s", canvasFullName)
 	}
-
-	// 3. Remove the actual directory
-	if err = os.RemoveAll(canvasPath); err != nil {
-		return fmt.Errorf("failed to remove canvas directory: %w", err)
+	if err := removeSubmodule(artistPath, canvasFullName, "canvas delete", message); err != nil {
+		return fmt.Errorf("failed to delete canvas: %w", err)
+	}
+	if err := deleteRemotes(opts.Forge, remotes); err != nil {
+		return err
 	}
 
-	// No automatic commit here. User is responsible for committing the changes.
-	fmt.Printf("Canvas '%s' deleted. Remember to 'git add %s' and 'git commit' in the parent repository.\n", canvasFullName, canvasFullName)
+	fmt.Printf("Canvas '%s' deleted. %s\n", canvasFullName, deleteHint(filepath.Base(artistPath), opts.Commit, remotes))
 	return nil
 }
 
-// MoveCanvas moves a canvas from one artist to another.
-func MoveCanvas(canvasFullName, newArtistFullName string) error {
-	// Find the atelier root by walking up from current directory
-	atelierPath, err := findAtelierRoot()
+// deleteHint tells the user what a deletion left in the parent repository, whose directory name
+// is parent: the removal committed, or staged for them to commit. Undo restores the local
+// repositories from the trash but not the remote repositories deleted with them, so it is only
+// offered when there were none.
+func deleteHint(parent string, committed bool, remotes []string) string {
+	hint := fmt.Sprintf("The removal is staged in %s; commit it there with 'git commit'.", parent)
+	if committed {
+		hint = fmt.Sprintf("The removal is committed in %s.", parent)
+	}
+	if len(remotes) == 0 {
+		return hint + " Run 'atelier undo' to restore it."
+	}
+	return fmt.Sprintf("%s The remote repositories %s were deleted; 'atelier undo' restores the local copy only and cannot bring them back.", hint, strings.Join(remotes, ", "))
+}
+
+// removeSubmodule deletes the submodule at path from the repository at parentPath and stages the
+// removal, committing it with message unless that is empty. The directory is kept in the
+// transaction's trash until everything else succeeded.
+func removeSubmodule(parentPath, path, operation, message string) (err error) {
+	tx, finish, err := begin(parentPath, operation, path)
 	if err != nil {
-		return fmt.Errorf("could not find atelier root: %w", err)
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.Track(parentPath); err != nil {
+		return err
+	}
+
+	// 1. Move the directory out of the way
+	if err = tx.Remove(filepath.Join(parentPath, path)); err != nil {
+		return fmt.Errorf("failed to remove directory: %w", err)
 	}
 
+	// 2. Remove the submodule entry from .gitmodules and index
+	if err = gitutil.Remove(parentPath, path); err != nil {
+		return fmt.Errorf("failed to remove from git tracking: %w", err)
+	}
+
+	// 3. Forget the submodule in the local config, as `git submodule deinit` would
+	if config, err := gitutil.LocalConfig(parentPath); err == nil {
+		gitutil.ConfigRemoveSection(config, "submodule."+path)
+	}
+	if message == "" {
+		return nil
+	}
+	return commitStaged(parentPath, message)
+}
+
+// MoveCanvas moves a canvas from one artist to another within the atelier at atelierPath, committing
+// its updated marker in the canvas, both artists and their new pointers in the atelier.
+func MoveCanvas(atelierPath, canvasFullName, newArtistFullName string) (err error) {
 	// Find which artist currently contains the canvas
 	currentArtistPath, err := findCanvasArtist(atelierPath, canvasFullName)
 	if err != nil {
@@ -338,61 +480,66 @@ func MoveCanvas(canvasFullName, newArtistFullName string) error {
 
 	fmt.Printf("Moving canvas %s from artist %s to artist %s...\n", canvasFullName, currentArtistName, newArtistFullName)
 
-	// 1. Remove canvas from current artist's git tracking (but keep the directory)
-	// First, remove from index but keep working directory
-	if err = gitutil.RunGitCommand(currentArtistPath, "rm", "--cached", canvasFullName); err != nil {
-		return fmt.Errorf("failed to remove canvas from current artist's git index: %w", err)
+	tx, finish, err := begin(atelierPath, "canvas move", canvasFullName, newArtistFullName)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	canvasPath := filepath.Join(currentArtistPath, canvasFullName)
+	if err = tx.Track(atelierPath, currentArtistPath, newArtistPath, canvasPath); err != nil {
+		return err
 	}
 
-	// Remove from .gitmodules manually
-	if err = removeFromGitmodules(currentArtistPath, canvasFullName); err != nil {
-		return fmt.Errorf("failed to remove canvas from .gitmodules: %w", err)
+	// 1. Detach the canvas from its current artist (keeping the directory and its remote URL)
+	url, err := detachSubmodule(tx, currentArtistPath, canvasFullName)
+	if err != nil {
+		return err
 	}
 
 	// 2. Move the canvas directory to the new artist
-	canvasPath := filepath.Join(currentArtistPath, canvasFullName)
-	if err = os.Rename(canvasPath, newCanvasPath); err != nil {
+	if err = tx.Rename(canvasPath, newCanvasPath); err != nil {
 		return fmt.Errorf("failed to move canvas directory: %w", err)
 	}
 
-	// 3. Update the .canvas file with new artist context
+	// 3. Update the .canvas file with new artist context and commit it in the canvas
 	if err = updateCanvasContext(newCanvasPath, newArtistFullName, ""); err != nil {
 		return fmt.Errorf("failed to update canvas context: %w", err)
 	}
+	if err = gitutil.AddPaths(newCanvasPath, marker.KindCanvas.FileName()); err != nil {
+		return err
+	}
+	if err = commitStaged(newCanvasPath, fmt.Sprintf("chore: move canvas to artist %s", newArtistFullName)); err != nil {
+		return err
+	}
 
 	// 4. Add canvas as submodule to new artist
-	if err = gitutil.AddSubmodule(newArtistPath, canvasFullName); err != nil {
+	if err = linkSubmodule(newArtistPath, canvasFullName, url); err != nil {
 		return fmt.Errorf("failed to add canvas as submodule to new artist: %w", err)
 	}
-
-	// 5. Stage changes in both artists
-	if err = gitutil.AddPaths(currentArtistPath, ".gitmodules"); err != nil {
-		return fmt.Errorf("failed to stage .gitmodules changes in current artist: %w", err)
-	}
 	if err = gitutil.AddPaths(newArtistPath, ".gitmodules", canvasFullName); err != nil {
 		return fmt.Errorf("failed to stage changes in new artist: %w", err)
 	}
 
-	// 6. Commit changes in both artists
+	// 5. Commit changes in both artists and their new pointers in the atelier
 	if err = gitutil.Commit(currentArtistPath, fmt.Sprintf("feat: remove canvas %s (moved to %s)", canvasFullName, newArtistFullName)); err != nil {
 		return fmt.Errorf("failed to commit changes in current artist: %w", err)
 	}
 	if err = gitutil.Commit(newArtistPath, fmt.Sprintf("feat: add canvas %s (moved from %s)", canvasFullName, currentArtistName)); err != nil {
 		return fmt.Errorf("failed to commit changes in new artist: %w", err)
 	}
+	if err = gitutil.AddPaths(atelierPath, currentArtistName, newArtistFullName); err != nil {
+		return err
+	}
+	if err = commitStaged(atelierPath, fmt.Sprintf("feat: move canvas %s from %s to %s", canvasFullName, currentArtistName, newArtistFullName)); err != nil {
+		return err
+	}
 
 	fmt.Printf("Canvas %s successfully moved from %s to %s!\n", canvasFullName, currentArtistName, newArtistFullName)
 	return nil
 }
 
-// CloneCanvas clones a canvas from one artist to another.
-func CloneCanvas(canvasFullName, targetArtistFullName, newCanvasName string) error {
-	// Find the atelier root by walking up from current directory
-	atelierPath, err := findAtelierRoot()
-	if err != nil {
-		return fmt.Errorf("could not find atelier root: %w", err)
-	}
-
+// CloneCanvas clones a canvas from one artist to another within the atelier at atelierPath.
+func CloneCanvas(atelierPath, canvasFullName, targetArtistFullName, newCanvasName string) (err error) {
 	// Find which artist currently contains the canvas
 	sourceArtistPath, err := findCanvasArtist(atelierPath, canvasFullName)
 	if err != nil {
@@ -414,28 +561,10 @@ func CloneCanvas(canvasFullName, targetArtistFullName, newCanvasName string) err
 	}
 
 	// Check if target artist already has a canvas with the final name
-	targetCanvasPath := filepath.Join(targetArtistPath, finalCanvasName)
-	if _, err := os.Stat(targetCanvasPath); err == nil {
-		if newCanvasName != "" {
-			return fmt.Errorf("canvas %s already exists in artist %s", finalCanvasName, targetArtistFullName)
-		}
-		// If no new name was provided and there's a conflict, prompt repeatedly until a unique name is provided
-		for {
-			fmt.Printf("Canvas '%s' already exists in artist '%s'.\n", finalCanvasName, targetArtistFullName)
-			inputRaw := util.Prompt("Enter a new name for the cloned canvas (without 'canvas-' prefix)")
-			nameBase := strings.TrimPrefix(strings.TrimSpace(inputRaw), "canvas-")
-			if nameBase == "" {
-				fmt.Println("Please enter a non-empty name.")
-				continue
-			}
-			finalCanvasName = "canvas-" + nameBase
-			targetCanvasPath = filepath.Join(targetArtistPath, finalCanvasName)
-			if _, err := os.Stat(targetCanvasPath); os.IsNotExist(err) {
-				break
-			}
-			fmt.Printf("Canvas '%s' also already exists in artist '%s'. Try another name.\n", finalCanvasName, targetArtistFullName)
-		}
+	if finalCanvasName, err = resolveConflict(targetArtistPath, marker.KindCanvas, finalCanvasName, newCanvasName != ""); err != nil {
+		return err
 	}
+	targetCanvasPath := filepath.Join(targetArtistPath, finalCanvasName)
 
 	// Get source artist name for context
 	sourceArtistName := filepath.Base(sourceArtistPath)
@@ -446,6 +575,18 @@ func CloneCanvas(canvasFullName, targetArtistFullName, newCanvasName string) err
 	}
 	fmt.Printf("...\n")
 
+	tx, finish, err := begin(atelierPath, "canvas clone", canvasFullName, targetArtistFullName, finalCanvasName)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.Track(targetArtistPath); err != nil {
+		return err
+	}
+	if err = tx.Created(targetCanvasPath); err != nil {
+		return err
+	}
+
 	// 1. Copy the canvas directory to the target artist
 	sourceCanvasPath := filepath.Join(sourceArtistPath, canvasFullName)
 	if err = copyCanvasDirectory(sourceCanvasPath, targetCanvasPath); err != nil {
@@ -483,29 +624,6 @@ func CloneCanvas(canvasFullName, targetArtistFullName, newCanvasName string) err
 	return nil
 }
 
-// findAtelierRoot finds the atelier root directory by walking up from current directory
-func findAtelierRoot() (string, error) {
-	dir, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-
-	for {
-		if _, err := os.Stat(filepath.Join(dir, ".atelier")); err == nil {
-			return dir, nil
-		}
-
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			// Reached root directory
-			break
-		}
-		dir = parent
-	}
-
-	return "", fmt.Errorf("could not find atelier root (.atelier file not found)")
-}
-
 // findCanvasArtist finds which artist contains the specified canvas
 func findCanvasArtist(atelierPath, canvasFullName string) (string, error) {
 	entries, err := os.ReadDir(atelierPath)
@@ -526,45 +644,28 @@ func findCanvasArtist(atelierPath, canvasFullName string) (string, error) {
 	return "", fmt.Errorf("canvas %s not found in any artist", canvasFullName)
 }
 
-// updateCanvasContext updates the .canvas file with new artist context
+// updateCanvasContext updates the .canvas file with new artist context.
+// Legacy marker formats are migrated to the current schema as part of the rewrite.
 func updateCanvasContext(canvasPath, newArtistFullName, newCanvasName string) error {
-	canvasFile := filepath.Join(canvasPath, ".canvas")
-	content, err := os.ReadFile(canvasFile)
-	if err != nil {
-		return fmt.Errorf("could not read .canvas file: %w", err)
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
-
-	// Handle both old single-line format and new multi-line format
-	if len(lines) == 1 {
-		// Old format: just canvas name
-		// Convert to new format with atelier, artist, canvas
-		atelierName := "atelier-" + strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(canvasPath)))), "atelier-")
-		canvasName := lines[0]
-		if newCanvasName != "" {
-			canvasName = newCanvasName
-		}
-		newContent := fmt.Sprintf("%s\n%s\n%s", atelierName, newArtistFullName, canvasName)
-		if err = os.WriteFile(canvasFile, []byte(newContent), 0644); err != nil {
-			return fmt.Errorf("could not write updated .canvas file: %w", err)
-		}
-		return nil
-	} else if len(lines) >= 3 {
-		// New format: atelier, artist, canvas
-		// Update the artist line (second line)
-		lines[1] = newArtistFullName
+	return updateMarkerContext(canvasPath, marker.KindCanvas, func(m *marker.Marker) {
+		m.Artist = newArtistFullName
 		if newCanvasName != "" {
-			lines[2] = newCanvasName
-		}
-		newContent := strings.Join(lines, "\n")
-		if err = os.WriteFile(canvasFile, []byte(newContent), 0644); err != nil {
-			return fmt.Errorf("could not write updated .canvas file: %w", err)
+			m.Canvas = newCanvasName
 		}
-		return nil
-	} else {
-		return fmt.Errorf("invalid .canvas file format: unexpected number of lines (%d)", len(lines))
+	})
+}
+
+// updateMarkerContext rewrites the marker of the given kind in dir after applying update to it.
+func updateMarkerContext(dir string, kind marker.Kind, update func(*marker.Marker)) error {
+	m, err := marker.Read(dir, kind)
+	if err != nil {
+		return err
+	}
+	update(m)
+	if err = marker.Write(dir, m); err != nil {
+		return fmt.Errorf("could not write updated %s file: %w", kind.FileName(), err)
 	}
+	return nil
 }
 
 // removeFromGitmodules removes a submodule entry from .gitmodules file
@@ -617,6 +718,13 @@ func removeFromGitmodules(repoPath, submodulePath string) error {
 	return nil
 }
 
+// initialPaths returns the paths of the initial commit of a new repository of kind in dir: its
+// marker, the files written from its template and the agent ignore files.
+func initialPaths(dir string, kind marker.Kind, files []string) []string {
+	paths := append([]string{kind.FileName()}, files...)
+	return append(paths, existingPaths(dir, guard.AgentFiles)...)
+}
+
 // existingPaths returns only those names that currently exist under base.
 // It prevents staging non-existent files when generating boilerplate.
 func existingPaths(base string, names []string) []string {
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+)
+
+func TestDeleteHint(t *testing.T) {
+	tests := []struct {
+		name      string
+		committed bool
+		remotes   []string
+		want      []string
+		not       []string
+	}{
+		{"staged", false, nil, []string{"staged in artist-picasso", "Run 'atelier undo'"}, []string{"git add"}},
+		{"committed", true, nil, []string{"committed in artist-picasso", "Run 'atelier undo'"}, []string{"git commit"}},
+		{"remotes", false, []string{"artist-monet", "canvas-lilies"}, []string{"artist-monet, canvas-lilies were deleted"}, []string{"Run 'atelier undo'"}},
+	}
+	for _, tt := range tests {
+		got := deleteHint("artist-picasso", tt.committed, tt.remotes)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: deleteHint = %q, want %q in it", tt.name, got, want)
+			}
+		}
+		for _, not := range tt.not {
+			if strings.Contains(got, not) {
+				t.Errorf("%s: deleteHint = %q, want no %q in it", tt.name, got, not)
+			}
+		}
+	}
+}
+
+// TestGoGitBackend runs operations and their undo on the go-git backend without a git binary, so
+// any git command the engine ran besides its backend would fail.
+func TestGoGitBackend(t *testing.T) {
+	tests := []struct {
+		operation string
+		run       func(root, target string) error
+	}{
+		{"canvas move", func(root, _ string) error {
+			return MoveCanvas(root, "canvas-guernica", "artist-monet")
+		}},
+		{"canvas rename", func(root, _ string) error {
+			return RenameCanvas(root, "canvas-guernica", "dora")
+		}},
+		{"artist rename", func(root, _ string) error {
+			return RenameArtist(root, "artist-picasso", "pablo")
+		}},
+		{"canvas delete", func(root, _ string) error {
+			return DeleteCanvas(filepath.Join(root, "artist-picasso"), "canvas-guernica", DeleteOptions{Commit: true})
+		}},
+		{"artist move", func(root, target string) error {
+			return MoveArtistToAtelier(root, "artist-picasso", target)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-monet/canvas-lilies")
+			target := gittest.NewAtelier(t, "atelier-other", "artist-vincent/canvas-sunflowers")
+			before := snapshot(t, w.Root)
+			previous := gitutil.Backend()
+			gitutil.Use(gitutil.GoGit{})
+			t.Cleanup(func() { gitutil.Use(previous) })
+
+			withoutGit(t, func() {
+				if err := tt.run(w.Root, target.Root); err != nil {
+					t.Fatalf("%s: %v", tt.operation, err)
+				}
+			})
+			if snapshot(t, w.Root) == before {
+				t.Fatalf("%s changed nothing", tt.operation)
+			}
+
+			withoutGit(t, func() {
+				if _, err := Undo(w.Root, false); err != nil {
+					t.Fatalf("Undo: %v", err)
+				}
+			})
+			if after := snapshot(t, w.Root); after != before {
+				t.Errorf("state after undo:\n%s\nwant:\n%s", after, before)
+			}
+		})
+	}
+}
+
+// withoutGit runs fn with a PATH that holds no git binary.
+func withoutGit(t *testing.T, fn func()) {
+	t.Helper()
+	path := os.Getenv("PATH")
+	t.Setenv("PATH", t.TempDir())
+	defer os.Setenv("PATH", path)
+	fn()
+}
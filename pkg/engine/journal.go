@@ -0,0 +1,430 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// journalDir is where transaction journals are kept, relative to the git directory of the atelier root.
+const journalDir = "atelier/journal"
+
+// StepKind identifies how a journaled step is undone.
+type StepKind string
+
+const (
+	StepSnapshot StepKind = "snapshot" // A repository's HEAD and bookkeeping files before it was modified
+	StepFile     StepKind = "file"     // A file before it was modified
+	StepRename   StepKind = "rename"   // A file or directory moved from Path to Target
+	StepCreate   StepKind = "create"   // A path that did not exist before
+	StepRemove   StepKind = "remove"   // A path moved to the journal's trash instead of being deleted
+)
+
+// Step is one reversible change recorded in a journal.
+type Step struct {
+	Kind    StepKind     `json:"kind"`
+	Path    string       `json:"path"`
+	Target  string       `json:"target,omitempty"` // Destination of a rename, trash location of a removal
+	Head    string       `json:"head,omitempty"`   // HEAD of a snapshotted repository
+	After   string       `json:"after,omitempty"`  // HEAD of a snapshotted repository once the operation completed
+	Backups []FileBackup `json:"backups,omitempty"`
+	Undone  bool         `json:"undone,omitempty"`
+}
+
+// FileBackup records the content of a file before a transaction touched it.
+type FileBackup struct {
+	Path    string `json:"path"`
+	Backup  string `json:"backup,omitempty"` // Copy in the journal directory; empty if the file did not exist
+	Existed bool   `json:"existed"`
+}
+
+// Journal is the on-disk record of a transaction. Pending journals belong to operations that were
+// interrupted or could not be rolled back completely; `atelier recover` rolls them back. Journals of
+// completed operations are kept in the operation log, where `atelier undo` rolls back the latest.
+type Journal struct {
+	ID         string    `json:"id"`
+	Operation  string    `json:"operation"`
+	Args       []string  `json:"args,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Steps      []Step    `json:"steps"`
+
+	dir  string
+	root string
+}
+
+// Tx is a running transaction. Every mutating engine operation runs inside one, so a failure at any
+// step rolls back the steps before it.
+type Tx struct {
+	journal *Journal
+	depth   int
+	counter int
+}
+
+// activeTx is the transaction of the operation in progress; nested operations join it.
+var activeTx *Tx
+
+// begin starts a transaction for the atelier containing path, or joins the one already in progress.
+// The returned finish function must be called with the operation's result: it commits the transaction on
+// success and rolls it back on failure, returning the (possibly extended) error.
+func begin(path, operation string, args ...string) (*Tx, func(error) error, error) {
+	if activeTx != nil {
+		activeTx.depth++
+		return activeTx, activeTx.leave, nil
+	}
+
+	root, err := marker.FindRoot(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	base, err := journalBase(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending, err := readJournals(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pending) > 0 {
+		return nil, nil, fmt.Errorf("an interrupted %s operation is pending; run 'atelier recover' first", pending[0].Operation)
+	}
+
+	id := time.Now().UTC().Format("20060102T150405.000000000Z")
+	j := &Journal{ID: id, Operation: operation, Args: args, StartedAt: time.Now().UTC(), dir: filepath.Join(base, id), root: root}
+	if err := fs.CreateDir(j.dir); err != nil {
+		return nil, nil, err
+	}
+	if err := j.save(); err != nil {
+		return nil, nil, err
+	}
+	activeTx = &Tx{journal: j}
+	return activeTx, activeTx.leave, nil
+}
+
+// Atomic runs fn as one operation named operation of the atelier at atelierPath. Its repositories
+// are snapshotted first and the engine operations fn calls join the transaction, so commits fn makes
+// itself are rolled back with theirs on failure, and 'atelier undo' reverts fn as a whole.
+func Atomic(atelierPath, operation string, fn func() error) (err error) {
+	tx, finish, err := begin(atelierPath, operation)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.TrackTree(atelierPath); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// leave ends one level of nesting and finishes the transaction when the outermost operation returns.
+func (tx *Tx) leave(opErr error) error {
+	if tx.depth > 0 {
+		tx.depth--
+		return opErr
+	}
+	activeTx = nil
+
+	if opErr == nil {
+		if err := tx.journal.complete(); err != nil {
+			fmt.Printf("Warning: could not record %s in the operation log: %v\n", tx.journal.Operation, err)
+			return tx.journal.discard()
+		}
+		return nil
+	}
+	fmt.Printf("%s failed, rolling back...\n", tx.journal.Operation)
+	if err := tx.journal.rollback(); err != nil {
+		return fmt.Errorf("%w (rollback incomplete: %v; run 'atelier recover' to retry)", opErr, err)
+	}
+	return opErr
+}
+
+// Track snapshots the repositories at dirs (HEAD, index, config, .gitmodules and marker files) so they
+// can be restored. Paths that are not repositories are ignored.
+func (tx *Tx) Track(dirs ...string) error {
+	for _, dir := range dirs {
+		head, err := gitutil.HeadCommit(dir)
+		if err != nil {
+			continue // Not a repository, or no commits yet
+		}
+		gitDir, err := gitutil.GitDir(dir)
+		if err != nil {
+			return err
+		}
+		files := []string{filepath.Join(gitDir, "index"), filepath.Join(gitDir, "config"), filepath.Join(dir, ".gitmodules")}
+		for _, kind := range marker.Kinds {
+			files = append(files, marker.Path(dir, kind))
+		}
+
+		step := Step{Kind: StepSnapshot, Path: dir, Head: head}
+		for _, file := range files {
+			backup, err := tx.backup(file)
+			if err != nil {
+				return err
+			}
+			step.Backups = append(step.Backups, backup)
+		}
+		if err := tx.record(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrackTree snapshots the repository at dir and its artist and canvas subrepositories.
+func (tx *Tx) TrackTree(dir string) error {
+	dirs := []string{dir}
+	for _, kind := range []marker.Kind{marker.KindArtist, marker.KindCanvas} {
+		children, err := marker.ChildDirs(dir, kind)
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, children...)
+	}
+	return tx.Track(dirs...)
+}
+
+// SaveFile records the current content of path before it is modified.
+func (tx *Tx) SaveFile(path string) error {
+	backup, err := tx.backup(path)
+	if err != nil {
+		return err
+	}
+	return tx.record(Step{Kind: StepFile, Path: path, Backups: []FileBackup{backup}})
+}
+
+// Rename moves from to to and records the move.
+func (tx *Tx) Rename(from, to string) error {
+	if err := tx.record(Step{Kind: StepRename, Path: from, Target: to}); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// Created records that path is about to be created, so rolling back removes it.
+// It is an error to record a path that already exists.
+func (tx *Tx) Created(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	return tx.record(Step{Kind: StepCreate, Path: path})
+}
+
+// Remove moves path into the journal's trash. The trash is kept in the operation log so the removal
+// can be undone, and is deleted for good once the entry is pruned.
+func (tx *Tx) Remove(path string) error {
+	trash := tx.nextFile("trash")
+	if err := fs.CreateDir(filepath.Dir(trash)); err != nil {
+		return err
+	}
+	if err := tx.record(Step{Kind: StepRemove, Path: path, Target: trash}); err != nil {
+		return err
+	}
+	return os.Rename(path, trash)
+}
+
+func (tx *Tx) record(step Step) error {
+	tx.journal.Steps = append(tx.journal.Steps, step)
+	return tx.journal.save()
+}
+
+// backup copies path into the journal directory.
+func (tx *Tx) backup(path string) (FileBackup, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return FileBackup{Path: path}, nil
+	}
+	if err != nil {
+		return FileBackup{}, fmt.Errorf("could not back up %s: %w", path, err)
+	}
+	backup := tx.nextFile("backup")
+	if err := fs.CreateDir(filepath.Dir(backup)); err != nil {
+		return FileBackup{}, err
+	}
+	if err := os.WriteFile(backup, content, 0644); err != nil {
+		return FileBackup{}, fmt.Errorf("could not back up %s: %w", path, err)
+	}
+	return FileBackup{Path: path, Backup: backup, Existed: true}, nil
+}
+
+func (tx *Tx) nextFile(kind string) string {
+	tx.counter++
+	return filepath.Join(tx.journal.dir, kind, strconv.Itoa(tx.counter))
+}
+
+// PendingJournals returns the journals of interrupted operations in the atelier at atelierPath, oldest first.
+func PendingJournals(atelierPath string) ([]*Journal, error) {
+	base, err := journalBase(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	return readJournals(base)
+}
+
+// Recover rolls back the interrupted operations in the atelier at atelierPath, newest first,
+// and returns the journals that were rolled back.
+func Recover(atelierPath string) ([]*Journal, error) {
+	journals, err := PendingJournals(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	var recovered []*Journal
+	for i := len(journals) - 1; i >= 0; i-- {
+		j := journals[i]
+		fmt.Printf("Rolling back %s (started %s)...\n", j.Operation, j.StartedAt.Local().Format(time.DateTime))
+		if err := j.rollback(); err != nil {
+			return recovered, fmt.Errorf("could not roll back %s: %w", j.Operation, err)
+		}
+		recovered = append(recovered, j)
+	}
+	return recovered, nil
+}
+
+// rollback undoes the journal's steps in reverse order. Steps already undone are skipped, so an
+// incomplete rollback can be retried. The journal is removed once every step is undone.
+func (j *Journal) rollback() error {
+	var errs []error
+	for i := len(j.Steps) - 1; i >= 0; i-- {
+		step := &j.Steps[i]
+		if step.Undone {
+			continue
+		}
+		if err := step.undo(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		step.Undone = true
+		if err := j.save(); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return j.discard()
+}
+
+func (s *Step) undo() error {
+	switch s.Kind {
+	case StepSnapshot:
+		if head, err := gitutil.HeadCommit(s.Path); err == nil && head != s.Head {
+			if err := gitutil.ResetSoft(s.Path, s.Head); err != nil {
+				return err
+			}
+		}
+		return restoreFiles(s.Backups)
+	case StepFile:
+		return restoreFiles(s.Backups)
+	case StepRename:
+		if _, err := os.Stat(s.Target); os.IsNotExist(err) {
+			return nil // Never moved
+		}
+		return os.Rename(s.Target, s.Path)
+	case StepCreate:
+		return os.RemoveAll(s.Path)
+	case StepRemove:
+		if _, err := os.Stat(s.Target); os.IsNotExist(err) {
+			return nil // Never moved to the trash
+		}
+		return os.Rename(s.Target, s.Path)
+	default:
+		return fmt.Errorf("unknown journal step %q", s.Kind)
+	}
+}
+
+func restoreFiles(backups []FileBackup) error {
+	for _, b := range backups {
+		if !b.Existed {
+			if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		content, err := os.ReadFile(b.Backup)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(b.Path, content, 0644); err != nil {
+			return fmt.Errorf("could not restore %s: %w", b.Path, err)
+		}
+	}
+	return nil
+}
+
+// Describe renders the journal as a one-line summary.
+func (j *Journal) Describe() string {
+	when := "started " + j.StartedAt.Local().Format(time.DateTime)
+	if !j.FinishedAt.IsZero() {
+		when = "finished " + j.FinishedAt.Local().Format(time.DateTime)
+	}
+	return fmt.Sprintf("%s (%s, %d steps)", strings.Join(append([]string{j.Operation}, j.Args...), " "), when, len(j.Steps))
+}
+
+func (j *Journal) save() error {
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write and rename so an interruption never leaves a truncated journal
+	tmp := filepath.Join(j.dir, "journal.json.tmp")
+	if err := fs.WriteFile(tmp, append(content, '\n')); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(j.dir, "journal.json"))
+}
+
+// discard removes the journal together with its backups and trash.
+func (j *Journal) discard() error {
+	if err := os.RemoveAll(j.dir); err != nil {
+		return fmt.Errorf("could not remove journal %s: %w", j.dir, err)
+	}
+	return nil
+}
+
+// journalBase returns the journal directory of the atelier at atelierPath.
+func journalBase(atelierPath string) (string, error) {
+	gitDir, err := gitutil.GitDir(atelierPath)
+	if err != nil {
+		return "", fmt.Errorf("could not locate git directory of atelier: %w", err)
+	}
+	return filepath.Join(gitDir, journalDir), nil
+}
+
+// readJournals loads the journals under base, oldest first.
+func readJournals(base string) ([]*Journal, error) {
+	entries, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var journals []*Journal
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(base, entry.Name())
+		content, err := os.ReadFile(filepath.Join(dir, "journal.json"))
+		if err != nil {
+			return nil, fmt.Errorf("unreadable journal in %s: %w", dir, err)
+		}
+		var j Journal
+		if err := json.Unmarshal(content, &j); err != nil {
+			return nil, fmt.Errorf("invalid journal in %s: %w", dir, err)
+		}
+		j.dir = dir
+		journals = append(journals, &j)
+	}
+	sort.Slice(journals, func(a, b int) bool { return journals[a].ID < journals[b].ID })
+	return journals, nil
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+)
+
+// errInjected is the failure failingGit returns.
+var errInjected = errors.New("injected failure")
+
+// failingGit fails every commit in dir and passes everything else to the wrapped backend.
+type failingGit struct {
+	gitutil.Git
+	dir string
+}
+
+func (g failingGit) Commit(dir, message string) error {
+	if filepath.Clean(dir) == g.dir {
+		return errInjected
+	}
+	return g.Git.Commit(dir, message)
+}
+
+// failCommits makes commits in dir fail for the rest of the test.
+func failCommits(t *testing.T, dir string) {
+	t.Helper()
+	previous := gitutil.Backend()
+	gitutil.Use(failingGit{Git: previous, dir: filepath.Clean(dir)})
+	t.Cleanup(func() { gitutil.Use(previous) })
+}
+
+// snapshot describes the state of the atelier at root and its submodules: the commits checked out
+// and recorded, and uncommitted changes.
+func snapshot(t *testing.T, root string) string {
+	t.Helper()
+	return strings.Join([]string{
+		gittest.Head(t, root),
+		gittest.Run(t, root, "status", "--porcelain"),
+		gittest.Run(t, root, "submodule", "status", "--recursive"),
+		gittest.Run(t, root, "submodule", "foreach", "--quiet", "--recursive", "echo $displaypath $(git rev-parse HEAD) && git status --porcelain"),
+	}, "\n")
+}
+
+// assertSettled fails the test if operations are pending in the atelier at root or its operation
+// log does not hold logged entries.
+func assertSettled(t *testing.T, root string, logged int) {
+	t.Helper()
+	pending, err := PendingJournals(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("%d operations pending, want none", len(pending))
+	}
+	entries, err := OperationLog(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != logged {
+		t.Errorf("operation log holds %d entries, want %d", len(entries), logged)
+	}
+}
+
+// changeEverything applies one change of each kind the journal records in the atelier at root.
+func changeEverything(t *testing.T, tx *Tx, root string) {
+	t.Helper()
+	if err := tx.Track(root); err != nil {
+		t.Fatal(err)
+	}
+	readme := filepath.Join(root, "README.md")
+	if err := tx.SaveFile(readme); err != nil {
+		t.Fatal(err)
+	}
+	gittest.WriteFile(t, readme, "rewritten\n")
+	created := filepath.Join(root, "notes")
+	if err := tx.Created(created); err != nil {
+		t.Fatal(err)
+	}
+	gittest.WriteFile(t, filepath.Join(created, "todo.md"), "todo\n")
+	if err := tx.Rename(filepath.Join(root, "artist-picasso"), filepath.Join(root, "artist-pablo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Remove(filepath.Join(root, ".gitmodules")); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Run(t, root, "add", "-A")
+	gittest.Run(t, root, "commit", "--quiet", "-m", "Change everything")
+}
+
+func TestRollback(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(w.Root, "README.md"), "readme\n")
+	gittest.Run(t, w.Root, "add", "README.md")
+	gittest.Run(t, w.Root, "commit", "--quiet", "-m", "Add README")
+	before := snapshot(t, w.Root)
+
+	tx, finish, err := begin(w.Root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, leave, err := begin(w.Root, "nested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nested != tx {
+		t.Fatal("a nested begin started a second transaction")
+	}
+	changeEverything(t, nested, w.Root)
+	if err := leave(nil); err != nil {
+		t.Fatalf("leaving the nested operation: %v", err)
+	}
+	if err := tx.Created(filepath.Join(w.Root, "notes")); err == nil {
+		t.Error("Created accepted a path that exists")
+	}
+
+	if err := finish(errInjected); !errors.Is(err, errInjected) {
+		t.Fatalf("finish = %v, want the operation's error", err)
+	}
+	if after := snapshot(t, w.Root); after != before {
+		t.Errorf("state after the rollback:\n%s\nwant:\n%s", after, before)
+	}
+	if _, err := os.Stat(filepath.Join(w.Root, "notes")); !os.IsNotExist(err) {
+		t.Errorf("created directory survived the rollback: %v", err)
+	}
+	assertSettled(t, w.Root, 0)
+}
+
+func TestRecover(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(w.Root, "README.md"), "readme\n")
+	gittest.Run(t, w.Root, "add", "README.md")
+	gittest.Run(t, w.Root, "commit", "--quiet", "-m", "Add README")
+	before := snapshot(t, w.Root)
+
+	tx, _, err := begin(w.Root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeEverything(t, tx, w.Root)
+	activeTx = nil // The process dies without finishing the transaction
+
+	if _, _, err := begin(w.Root, "next"); err == nil || !strings.Contains(err.Error(), "atelier recover") {
+		t.Fatalf("begin with a pending journal = %v, want an error pointing at recover", err)
+	}
+	recovered, err := Recover(w.Root)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].Operation != "test" {
+		t.Fatalf("Recover rolled back %v, want the test operation", recovered)
+	}
+	if after := snapshot(t, w.Root); after != before {
+		t.Errorf("state after recovery:\n%s\nwant:\n%s", after, before)
+	}
+	assertSettled(t, w.Root, 0)
+}
+
+func TestRollbackAfterFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		fail string // Repository whose commit fails, where the operation commits it
+		run  func(root, target string) error
+	}{
+		{"canvas move, first commit", "$root/artist-monet/canvas-guernica", func(root, _ string) error {
+			return MoveCanvas(root, "canvas-guernica", "artist-monet")
+		}},
+		{"canvas move, last commit", "$root", func(root, _ string) error {
+			return MoveCanvas(root, "canvas-guernica", "artist-monet")
+		}},
+		{"artist rename, first commit", "$root/artist-pablo/canvas-guernica", func(root, _ string) error {
+			return RenameArtist(root, "artist-picasso", "pablo")
+		}},
+		{"artist rename, last commit", "$root", func(root, _ string) error {
+			return RenameArtist(root, "artist-picasso", "pablo")
+		}},
+		{"artist move to atelier, first commit", "$target/artist-picasso/canvas-guernica", func(root, target string) error {
+			return MoveArtistToAtelier(root, "artist-picasso", target)
+		}},
+		{"artist move to atelier, last commit", "$root", func(root, target string) error {
+			return MoveArtistToAtelier(root, "artist-picasso", target)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-monet/canvas-lilies")
+			target := gittest.NewAtelier(t, "atelier-other", "artist-vincent/canvas-sunflowers")
+			config := filepath.Join(gittest.Run(t, filepath.Join(w.Root, "artist-picasso"), "rev-parse", "--absolute-git-dir"), "config")
+			worktree, _ := gitutil.ConfigGet(config, "core.worktree")
+			before, targetBefore := snapshot(t, w.Root), snapshot(t, target.Root)
+
+			backend := gitutil.Backend()
+			dirs := map[string]string{"root": w.Root, "target": target.Root}
+			failCommits(t, os.Expand(tt.fail, func(name string) string { return dirs[name] }))
+			if err := tt.run(w.Root, target.Root); !errors.Is(err, errInjected) {
+				t.Fatalf("operation = %v, want the injected failure", err)
+			}
+
+			if after := snapshot(t, w.Root); after != before {
+				t.Errorf("state after the rollback:\n%s\nwant:\n%s", after, before)
+			}
+			if after := snapshot(t, target.Root); after != targetBefore {
+				t.Errorf("target atelier after the rollback:\n%s\nwant:\n%s", after, targetBefore)
+			}
+			if got, _ := gitutil.ConfigGet(config, "core.worktree"); got != worktree {
+				t.Errorf("core.worktree of artist-picasso = %q after the rollback, want %q", got, worktree)
+			}
+			assertSettled(t, w.Root, 0)
+
+			// The rolled back atelier is consistent enough to run the operation for real
+			gitutil.Use(backend)
+			if err := tt.run(w.Root, target.Root); err != nil {
+				t.Fatalf("operation after the rollback: %v", err)
+			}
+			assertSettled(t, w.Root, 1)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+)
+
+const (
+	// oplogDir is where journals of completed operations are kept, relative to the git directory of the atelier root.
+	oplogDir = "atelier/oplog"
+	// OperationLogLimit is the number of completed operations kept; older entries and their trash are deleted.
+	OperationLogLimit = 20
+)
+
+// complete records the HEADs the snapshotted repositories ended up at and moves the journal into
+// the operation log, pruning the oldest entries.
+func (j *Journal) complete() error {
+	for i := range j.Steps {
+		if j.Steps[i].Kind != StepSnapshot {
+			continue
+		}
+		if head, err := gitutil.HeadCommit(j.currentPath(i)); err == nil {
+			j.Steps[i].After = head
+		}
+	}
+	j.FinishedAt = time.Now().UTC()
+
+	base, err := oplogBase(j.root)
+	if err != nil {
+		return err
+	}
+	if err := fs.CreateDir(base); err != nil {
+		return err
+	}
+	dir := filepath.Join(base, j.ID)
+	if err := os.Rename(j.dir, dir); err != nil {
+		return err
+	}
+	// Backups and trash move along with the journal
+	for i := range j.Steps {
+		step := &j.Steps[i]
+		if step.Kind == StepRemove {
+			step.Target = swapPrefix(step.Target, j.dir, dir)
+		}
+		for k := range step.Backups {
+			step.Backups[k].Backup = swapPrefix(step.Backups[k].Backup, j.dir, dir)
+		}
+	}
+	j.dir = dir
+	if err := j.save(); err != nil {
+		return err
+	}
+
+	entries, err := readJournals(base)
+	if err != nil {
+		return err
+	}
+	for len(entries) > OperationLogLimit {
+		if err := entries[0].discard(); err != nil {
+			return err
+		}
+		entries = entries[1:]
+	}
+	return nil
+}
+
+// currentPath returns where the path of step i lives now, following the renames recorded after it
+// that have not been undone.
+func (j *Journal) currentPath(i int) string {
+	path := j.Steps[i].Path
+	for _, later := range j.Steps[i+1:] {
+		if later.Kind == StepRename && !later.Undone {
+			path = swapPrefix(path, later.Path, later.Target)
+		}
+	}
+	return path
+}
+
+// OperationLog returns the completed operations in the atelier at atelierPath that can be undone, oldest first.
+func OperationLog(atelierPath string) ([]*Journal, error) {
+	base, err := oplogBase(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	return readJournals(base)
+}
+
+// Undo rolls back the latest completed operation in the atelier at atelierPath and returns it, or nil if
+// the log is empty. Unless force is set, it refuses when a repository the operation committed to has
+// moved on since, as rolling back would discard those commits.
+func Undo(atelierPath string, force bool) (*Journal, error) {
+	pending, err := PendingJournals(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("an interrupted %s operation is pending; run 'atelier recover' first", pending[0].Operation)
+	}
+	entries, err := OperationLog(atelierPath)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	j := entries[len(entries)-1]
+
+	if !force {
+		for i, step := range j.Steps {
+			if step.Kind != StepSnapshot || step.Undone || step.After == "" {
+				continue
+			}
+			path := j.currentPath(i)
+			if head, err := gitutil.HeadCommit(path); err != nil || head != step.After {
+				return nil, fmt.Errorf("%s has changed since %s; use --force to undo anyway and drop the later commits", path, j.Operation)
+			}
+		}
+	}
+
+	fmt.Printf("Undoing %s...\n", j.Describe())
+	if err := j.rollback(); err != nil {
+		return nil, fmt.Errorf("could not undo %s: %w; run 'atelier undo' again to retry", j.Operation, err)
+	}
+	return j, nil
+}
+
+// Trash moves path, a file or directory of the atelier at atelierPath, into the trash of a new entry
+// of the operation log named operation, so 'atelier undo' can restore it until the entry is pruned.
+func Trash(atelierPath, operation, path string) (err error) {
+	tx, finish, err := begin(atelierPath, operation, path)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	return tx.Remove(path)
+}
+
+// oplogBase returns the operation log directory of the atelier at atelierPath.
+func oplogBase(atelierPath string) (string, error) {
+	gitDir, err := gitutil.GitDir(atelierPath)
+	if err != nil {
+		return "", fmt.Errorf("could not locate git directory of atelier: %w", err)
+	}
+	return filepath.Join(gitDir, oplogDir), nil
+}
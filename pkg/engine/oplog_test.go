@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+)
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		operation string
+		run       func(root, target string) error
+	}{
+		{"canvas move", func(root, _ string) error {
+			return MoveCanvas(root, "canvas-guernica", "artist-monet")
+		}},
+		{"canvas rename", func(root, _ string) error {
+			return RenameCanvas(root, "canvas-guernica", "dora")
+		}},
+		{"artist rename", func(root, _ string) error {
+			return RenameArtist(root, "artist-picasso", "pablo")
+		}},
+		{"canvas delete", func(root, _ string) error {
+			return DeleteCanvas(filepath.Join(root, "artist-picasso"), "canvas-guernica", DeleteOptions{})
+		}},
+		{"canvas delete", func(root, _ string) error {
+			return DeleteCanvas(filepath.Join(root, "artist-picasso"), "canvas-guernica", DeleteOptions{Commit: true})
+		}},
+		{"artist delete", func(root, _ string) error {
+			return DeleteArtist(root, "artist-picasso", DeleteOptions{Commit: true})
+		}},
+		{"artist move", func(root, target string) error {
+			return MoveArtistToAtelier(root, "artist-picasso", target)
+		}},
+		{"canvas move", func(root, target string) error {
+			return MoveCanvasToArtist(root, "canvas-guernica", filepath.Join(target, "artist-vincent"))
+		}},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i, " ", tt.operation), func(t *testing.T) {
+			w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-monet/canvas-lilies")
+			target := gittest.NewAtelier(t, "atelier-other", "artist-vincent/canvas-sunflowers")
+			before, targetBefore := snapshot(t, w.Root), snapshot(t, target.Root)
+
+			if err := tt.run(w.Root, target.Root); err != nil {
+				t.Fatalf("%s: %v", tt.operation, err)
+			}
+			if snapshot(t, w.Root) == before {
+				t.Fatalf("%s changed nothing", tt.operation)
+			}
+			assertSettled(t, w.Root, 1)
+			// Moves to another atelier are logged in the atelier they started from only
+			assertSettled(t, target.Root, 0)
+
+			j, err := Undo(w.Root, false)
+			if err != nil {
+				t.Fatalf("Undo: %v", err)
+			}
+			if j == nil || j.Operation != tt.operation {
+				t.Fatalf("Undo reverted %v, want %s", j, tt.operation)
+			}
+			if after := snapshot(t, w.Root); after != before {
+				t.Errorf("state after undo:\n%s\nwant:\n%s", after, before)
+			}
+			if after := snapshot(t, target.Root); after != targetBefore {
+				t.Errorf("target atelier after undo:\n%s\nwant:\n%s", after, targetBefore)
+			}
+			assertSettled(t, w.Root, 0)
+
+			if j, err := Undo(w.Root, false); j != nil || err != nil {
+				t.Errorf("Undo with an empty log = %v, %v; want nothing to undo", j, err)
+			}
+		})
+	}
+}
+
+func TestUndoRefusesNewCommits(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-monet/canvas-lilies")
+	before := snapshot(t, w.Root)
+	if err := MoveCanvas(w.Root, "canvas-guernica", "artist-monet"); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Run(t, w.Root, "commit", "--quiet", "--allow-empty", "-m", "Later work")
+
+	if _, err := Undo(w.Root, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("Undo over a later commit = %v, want a refusal suggesting --force", err)
+	}
+	assertSettled(t, w.Root, 1)
+
+	if _, err := Undo(w.Root, true); err != nil {
+		t.Fatalf("Undo --force: %v", err)
+	}
+	if after := snapshot(t, w.Root); after != before {
+		t.Errorf("state after a forced undo:\n%s\nwant:\n%s", after, before)
+	}
+}
+
+func TestOperationLogLimit(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	for i := 0; i <= OperationLogLimit; i++ {
+		path := filepath.Join(w.Root, fmt.Sprintf("scratch-%d", i))
+		gittest.WriteFile(t, path, "scratch\n")
+		if err := Trash(w.Root, "scratch", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := OperationLog(w.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != OperationLogLimit {
+		t.Fatalf("operation log holds %d entries, want %d", len(entries), OperationLogLimit)
+	}
+	if first := entries[0].Args[0]; filepath.Base(first) != "scratch-1" {
+		t.Errorf("oldest entry trashed %s, want scratch-1 once scratch-0 is pruned", first)
+	}
+}
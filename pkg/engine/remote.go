@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/forge"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// originRemote is the remote name used for provisioned repositories.
+const originRemote = "origin"
+
+// DeleteOptions customises how artists and canvases are deleted.
+type DeleteOptions struct {
+	Forge  forge.Forge // Also delete the remote repositories hosted on this forge when set
+	Commit bool        // Commit the removal in the parent repository instead of leaving it staged
+}
+
+// createRemote creates the remote repository for dirName on f and returns its clone URL.
+func createRemote(f forge.Forge, dirName string) (string, error) {
+	fmt.Printf("Creating remote repository %s on %s...\n", dirName, f.Name())
+	if err := f.CreateRepo(dirName); err != nil {
+		return "", fmt.Errorf("failed to create remote repository %s: %w", dirName, err)
+	}
+	return f.CloneURL(dirName), nil
+}
+
+// deleteRemote removes a remote repository created by createRemote during a failed initialization.
+func deleteRemote(f forge.Forge, dirName string) {
+	fmt.Printf("Removing remote repository %s from %s\n", dirName, f.Name())
+	if err := f.DeleteRepo(dirName); err != nil {
+		fmt.Printf("Warning: could not remove remote repository %s: %v\n", dirName, err)
+	}
+}
+
+// remoteMarker describes a provisioned origin for a marker file.
+func remoteMarker(f forge.Forge, url string) *marker.Remote {
+	return &marker.Remote{Name: originRemote, URL: url, Provider: f.Name()}
+}
+
+// publish points origin at url, pushes the current branch and makes it the default branch on f.
+func publish(f forge.Forge, repoPath, url string) error {
+	dirName := filepath.Base(repoPath)
+	if err := gitutil.SetRemote(repoPath, originRemote, url); err != nil {
+		return err
+	}
+	branch, err := gitutil.CurrentBranch(repoPath)
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return fmt.Errorf("cannot push %s: HEAD is detached", dirName)
+	}
+	fmt.Printf("Pushing %s to %s...\n", dirName, url)
+	if err := gitutil.Push(repoPath, originRemote, branch, true, false); err != nil {
+		return err
+	}
+	if err := f.SetDefaultBranch(dirName, branch); err != nil {
+		return fmt.Errorf("failed to set default branch of %s: %w", dirName, err)
+	}
+	return nil
+}
+
+// linkSubmodule records dirName as a submodule of parentPath, using url when the repository has
+// a provisioned remote and the relative "./<dirName>" URL otherwise.
+func linkSubmodule(parentPath, dirName, url string) error {
+	if url == "" {
+		return gitutil.AddSubmodule(parentPath, dirName)
+	}
+	return gitutil.AddSubmoduleURL(parentPath, url, dirName)
+}
+
+// forgeRepos returns the repositories under dir (dir itself and, for artists, its canvases) whose origin
+// is hosted on f. Repositories pointing elsewhere are reported and left alone.
+func forgeRepos(f forge.Forge, dir string, kind marker.Kind) []string {
+	dirs := []string{dir}
+	if kind == marker.KindArtist {
+		if canvases, err := marker.ChildDirs(dir, marker.KindCanvas); err == nil {
+			dirs = append(dirs, canvases...)
+		}
+	}
+
+	var names []string
+	for _, repoPath := range dirs {
+		name := filepath.Base(repoPath)
+		url := gitutil.RemoteURL(repoPath, originRemote)
+		if url == "" || url != f.CloneURL(name) {
+			fmt.Printf("Skipping remote of %s: origin %q is not %s on %s\n", name, url, f.CloneURL(name), f.Name())
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// deleteRemotes deletes the named repositories from f, reporting every failure.
+func deleteRemotes(f forge.Forge, names []string) error {
+	var failed []string
+	for _, name := range names {
+		fmt.Printf("Deleting remote repository %s on %s...\n", name, f.Name())
+		if err := f.DeleteRepo(name); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete remote repositories: %v", failed)
+	}
+	return nil
+}
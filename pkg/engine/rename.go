@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// RenameCanvas renames the canvas canvasFullName, wherever it lives in the atelier at atelierPath, to newCanvasName.
+// The canvas keeps its history; its marker, the artist's .gitmodules and the recorded pointers are updated and committed.
+func RenameCanvas(atelierPath, canvasFullName, newCanvasName string) (err error) {
+	artistPath, err := findCanvasArtist(atelierPath, canvasFullName)
+	if err != nil {
+		return fmt.Errorf("could not find artist containing canvas %s: %w", canvasFullName, err)
+	}
+	artistFullName := filepath.Base(artistPath)
+	newDirName := marker.KindCanvas.DirName(newCanvasName)
+
+	fmt.Printf("Renaming canvas %s to %s in artist %s...\n", canvasFullName, newDirName, artistFullName)
+	tx, finish, err := begin(atelierPath, "canvas rename", canvasFullName, newDirName)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err := tx.Track(atelierPath, artistPath, filepath.Join(artistPath, canvasFullName)); err != nil {
+		return err
+	}
+	if err := renameSubmodule(tx, artistPath, canvasFullName, newDirName); err != nil {
+		return err
+	}
+
+	canvasPath := filepath.Join(artistPath, newDirName)
+	if err := updateCanvasContext(canvasPath, artistFullName, newDirName); err != nil {
+		return fmt.Errorf("failed to update canvas context: %w", err)
+	}
+	if err := gitutil.AddPaths(canvasPath, marker.KindCanvas.FileName()); err != nil {
+		return err
+	}
+	if err := commitStaged(canvasPath, fmt.Sprintf("chore: rename canvas %s to %s", canvasFullName, newDirName)); err != nil {
+		return err
+	}
+
+	if err := gitutil.AddPaths(artistPath, ".gitmodules", newDirName); err != nil {
+		return err
+	}
+	if err := gitutil.Commit(artistPath, fmt.Sprintf("feat: rename canvas %s to %s", canvasFullName, newDirName)); err != nil {
+		return err
+	}
+
+	if err := gitutil.AddPaths(atelierPath, artistFullName); err != nil {
+		return err
+	}
+	return commitStaged(atelierPath, fmt.Sprintf("feat: update artist %s (rename canvas %s to %s)", artistFullName, canvasFullName, newDirName))
+}
+
+// RenameArtist renames the artist artistFullName in the atelier at atelierPath to newArtistName.
+// The markers of the artist and all of its canvases are rewritten and committed bottom-up.
+func RenameArtist(atelierPath, artistFullName, newArtistName string) (err error) {
+	newDirName := marker.KindArtist.DirName(newArtistName)
+
+	fmt.Printf("Renaming artist %s to %s...\n", artistFullName, newDirName)
+	tx, finish, err := begin(atelierPath, "artist rename", artistFullName, newDirName)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err := tx.Track(atelierPath); err != nil {
+		return err
+	}
+	if err := tx.TrackTree(filepath.Join(atelierPath, artistFullName)); err != nil {
+		return err
+	}
+	if err := renameSubmodule(tx, atelierPath, artistFullName, newDirName); err != nil {
+		return err
+	}
+
+	artistPath := filepath.Join(atelierPath, newDirName)
+	canvases, err := marker.ChildDirs(artistPath, marker.KindCanvas)
+	if err != nil {
+		return err
+	}
+	var toStage []string
+	for _, canvasPath := range canvases {
+		canvasDirName := filepath.Base(canvasPath)
+		if err := updateCanvasContext(canvasPath, newDirName, ""); err != nil {
+			return fmt.Errorf("failed to update context of canvas %s: %w", canvasDirName, err)
+		}
+		if err := gitutil.AddPaths(canvasPath, marker.KindCanvas.FileName()); err != nil {
+			return err
+		}
+		if err := commitStaged(canvasPath, fmt.Sprintf("chore: move canvas to renamed artist %s", newDirName)); err != nil {
+			return err
+		}
+		toStage = append(toStage, canvasDirName)
+	}
+
+	if err := updateMarkerContext(artistPath, marker.KindArtist, func(m *marker.Marker) { m.Artist = newDirName }); err != nil {
+		return err
+	}
+	if err := gitutil.AddPaths(artistPath, append(toStage, marker.KindArtist.FileName())...); err != nil {
+		return err
+	}
+	if err := commitStaged(artistPath, fmt.Sprintf("chore: rename artist %s to %s", artistFullName, newDirName)); err != nil {
+		return err
+	}
+
+	if err := gitutil.AddPaths(atelierPath, ".gitmodules", newDirName); err != nil {
+		return err
+	}
+	return gitutil.Commit(atelierPath, fmt.Sprintf("feat: rename artist %s to %s", artistFullName, newDirName))
+}
+
+// renameSubmodule moves the submodule at oldPath in parentPath to newPath: the working tree, its git directory
+// under .git/modules, the .gitmodules section and the gitlink in the index. Nothing is committed.
+func renameSubmodule(tx *Tx, parentPath, oldPath, newPath string) error {
+	oldDir, newDir := filepath.Join(parentPath, oldPath), filepath.Join(parentPath, newPath)
+	if _, err := os.Stat(oldDir); err != nil {
+		return fmt.Errorf("%s does not exist in %s", oldPath, filepath.Base(parentPath))
+	}
+	if _, err := os.Stat(newDir); err == nil {
+		return fmt.Errorf("%s already exists in %s", newPath, filepath.Base(parentPath))
+	}
+	name, err := gitutil.SubmoduleName(parentPath, oldPath)
+	if err != nil {
+		return err
+	}
+	parentGitDir, err := gitutil.GitDir(parentPath)
+	if err != nil {
+		return err
+	}
+	oldModules := filepath.Join(parentGitDir, "modules", name)
+	newModules := filepath.Join(parentGitDir, "modules", newPath)
+	if _, err := os.Stat(newModules); err == nil {
+		return fmt.Errorf("a git directory for %s already exists in %s", newPath, filepath.Dir(newModules))
+	}
+	links, err := gitLinks(oldDir)
+	if err != nil {
+		return err
+	}
+
+	// 1. Move the working tree and, for absorbed submodules, the git directory
+	if err := tx.Rename(oldDir, newDir); err != nil {
+		return fmt.Errorf("failed to move %s: %w", oldPath, err)
+	}
+	if _, err := os.Stat(oldModules); err == nil {
+		if err := tx.Rename(oldModules, newModules); err != nil {
+			return fmt.Errorf("failed to move git directory of %s: %w", oldPath, err)
+		}
+	}
+
+	// 2. Point .git files and core.worktree settings at the new locations
+	for worktree, gitDir := range links {
+		worktree = swapPrefix(worktree, oldDir, newDir)
+		gitDir = swapPrefix(swapPrefix(gitDir, oldModules, newModules), oldDir, newDir)
+		if err := writeGitLink(tx, worktree, gitDir); err != nil {
+			return err
+		}
+	}
+
+	// 3. Re-register the submodule under its new name
+	section, newSection := "submodule."+name, "submodule."+newPath
+	gitmodules := filepath.Join(parentPath, ".gitmodules")
+	if err := gitutil.ConfigRenameSection(gitmodules, section, newSection); err != nil {
+		return err
+	}
+	if err := gitutil.ConfigSet(gitmodules, newSection+".path", newPath); err != nil {
+		return err
+	}
+	if url, _ := gitutil.ConfigGet(gitmodules, newSection+".url"); url == "./"+oldPath {
+		if err := gitutil.ConfigSet(gitmodules, newSection+".url", "./"+newPath); err != nil {
+			return err
+		}
+	}
+	// The local config section only exists once the submodule is initialized
+	if config, err := gitutil.LocalConfig(parentPath); err == nil {
+		gitutil.ConfigRenameSection(config, section, newSection)
+	}
+	for _, repo := range []string{parentPath, newDir} {
+		if err := rewriteSubmoduleURLs(repo, oldDir, newDir); err != nil {
+			return err
+		}
+	}
+
+	return gitutil.MoveGitlink(parentPath, oldPath, newPath)
+}
+
+// gitLinks maps the working trees at dir and its artist/canvas subdirectories that use a .git file
+// to the absolute git directories the files point at.
+func gitLinks(dir string) (map[string]string, error) {
+	dirs := []string{dir}
+	for _, kind := range []marker.Kind{marker.KindArtist, marker.KindCanvas} {
+		children, err := marker.ChildDirs(dir, kind)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, children...)
+	}
+
+	links := map[string]string{}
+	for _, worktree := range dirs {
+		info, err := os.Stat(filepath.Join(worktree, ".git"))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(worktree, ".git"))
+		if err != nil {
+			return nil, err
+		}
+		gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+		if !ok {
+			return nil, fmt.Errorf("unexpected .git file in %s", worktree)
+		}
+		gitDir = strings.TrimSpace(gitDir)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(worktree, gitDir)
+		}
+		links[worktree] = filepath.Clean(gitDir)
+	}
+	return links, nil
+}
+
+// writeGitLink points the .git file of worktree at gitDir and, if the git directory records its
+// working tree, points core.worktree back at worktree. Both use relative paths, as git does.
+func writeGitLink(tx *Tx, worktree, gitDir string) error {
+	rel, err := filepath.Rel(worktree, gitDir)
+	if err != nil {
+		return err
+	}
+	if err := tx.SaveFile(filepath.Join(worktree, ".git")); err != nil {
+		return err
+	}
+	if err := fs.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+rel+"\n")); err != nil {
+		return err
+	}
+
+	config := filepath.Join(gitDir, "config")
+	if worktree, err := gitutil.ConfigGet(config, "core.worktree"); err != nil || worktree == "" {
+		return err
+	}
+	back, err := filepath.Rel(gitDir, worktree)
+	if err != nil {
+		return err
+	}
+	if err := tx.SaveFile(config); err != nil {
+		return err
+	}
+	return gitutil.ConfigSet(config, "core.worktree", back)
+}
+
+// rewriteSubmoduleURLs updates submodule URLs in the local config of the repository at dir that point into oldDir.
+func rewriteSubmoduleURLs(dir, oldDir, newDir string) error {
+	config, err := gitutil.LocalConfig(dir)
+	if err != nil {
+		return err
+	}
+	names, err := gitutil.ConfigSubsections(config, "submodule")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		key := "submodule." + name + ".url"
+		url, err := gitutil.ConfigGet(config, key)
+		if err != nil {
+			return err
+		}
+		if url == "" || swapPrefix(url, oldDir, newDir) == url {
+			continue
+		}
+		if err := gitutil.ConfigSet(config, key, swapPrefix(url, oldDir, newDir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// swapPrefix replaces the directory prefix oldDir of path with newDir.
+func swapPrefix(path, oldDir, newDir string) string {
+	if path == oldDir {
+		return newDir
+	}
+	if rest, ok := strings.CutPrefix(path, oldDir+string(os.PathSeparator)); ok {
+		return filepath.Join(newDir, rest)
+	}
+	return path
+}
+
+// commitStaged commits the staged changes in dir, if there are any.
+func commitStaged(dir, message string) error {
+	staged, err := gitutil.HasStagedChanges(dir)
+	if err != nil || !staged {
+		return err
+	}
+	return gitutil.Commit(dir, message)
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// assertSubmodules fails the test unless the repository at dir registers exactly the submodules urls
+// maps to their URLs, under sections named after their paths, and git reports each of them checked
+// out at the commit dir records.
+func assertSubmodules(t *testing.T, dir string, urls map[string]string) {
+	t.Helper()
+	registered := map[string]string{}
+	for _, line := range strings.Split(gittest.Run(t, dir, "config", "-f", ".gitmodules", "--get-regexp", `\.path$`), "\n") {
+		key, path, _ := strings.Cut(line, " ")
+		if key != "submodule."+path+".path" {
+			t.Errorf(".gitmodules of %s registers %s under %s", filepath.Base(dir), path, key)
+		}
+		registered[path] = gittest.Run(t, dir, "config", "-f", ".gitmodules", "submodule."+path+".url")
+	}
+	for path, url := range urls {
+		if registered[path] != url {
+			t.Errorf(".gitmodules of %s records %s at %q, want %s", filepath.Base(dir), path, registered[path], url)
+		}
+	}
+	for path := range registered {
+		if _, ok := urls[path]; !ok {
+			t.Errorf(".gitmodules of %s still registers %s", filepath.Base(dir), path)
+		}
+	}
+
+	var paths []string
+	for _, line := range strings.Split(gittest.Run(t, dir, "submodule", "status"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.ContainsAny(fields[0][:1], "+-U") {
+			t.Errorf("git submodule status in %s: %q", filepath.Base(dir), line)
+			continue
+		}
+		paths = append(paths, fields[1])
+	}
+	want := make([]string, 0, len(urls))
+	for path := range urls {
+		want = append(want, path)
+	}
+	slices.Sort(paths)
+	slices.Sort(want)
+	if !slices.Equal(paths, want) {
+		t.Errorf("git submodule status in %s lists %v, want %v", filepath.Base(dir), paths, want)
+	}
+}
+
+// assertMarker fails the test unless the marker of kind in dir names atelier, artist and canvas.
+func assertMarker(t *testing.T, dir string, kind marker.Kind, atelier, artist, canvas string) {
+	t.Helper()
+	m, err := marker.Read(dir, kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := [3]string{m.Atelier, m.Artist, m.Canvas}, [3]string{atelier, artist, canvas}; got != want {
+		t.Errorf("%s in %s names %q, want %q", kind.FileName(), filepath.Base(dir), got, want)
+	}
+	if status := gittest.Run(t, dir, "status", "--porcelain"); status != "" {
+		t.Errorf("%s not committed:\n%s", filepath.Base(dir), status)
+	}
+}
+
+// assertGitDir fails the test unless the submodule at path in parent keeps its git directory under
+// parent's, at the place git absorbs it to.
+func assertGitDir(t *testing.T, parent, path string) {
+	t.Helper()
+	parentGitDir := gittest.Run(t, parent, "rev-parse", "--absolute-git-dir")
+	if gitDir := gittest.Run(t, filepath.Join(parent, path), "rev-parse", "--absolute-git-dir"); gitDir != filepath.Join(parentGitDir, "modules", path) {
+		t.Errorf("git directory of %s is %s, want it under %s", path, gitDir, parentGitDir)
+	}
+}
+
+func TestRenameCanvas(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-weeping", "artist-monet/canvas-lilies")
+	artist := filepath.Join(w.Root, "artist-picasso")
+	history := gittest.Head(t, filepath.Join(artist, "canvas-guernica"))
+
+	if err := RenameCanvas(w.Root, "canvas-guernica", "dora"); err != nil {
+		t.Fatalf("RenameCanvas: %v", err)
+	}
+	canvas := filepath.Join(artist, "canvas-dora")
+	assertSubmodules(t, artist, map[string]string{
+		"canvas-dora":    w.Remote("canvas-guernica"),
+		"canvas-weeping": w.Remote("canvas-weeping"),
+	})
+	assertSubmodules(t, w.Root, map[string]string{
+		"artist-picasso": w.Remote("artist-picasso"),
+		"artist-monet":   w.Remote("artist-monet"),
+	})
+	assertGitDir(t, artist, "canvas-dora")
+	assertMarker(t, canvas, marker.KindCanvas, "atelier-demo", "artist-picasso", "canvas-dora")
+	assertMarker(t, artist, marker.KindArtist, "atelier-demo", "artist-picasso", "")
+	if parent := gittest.Run(t, canvas, "rev-parse", "HEAD~1"); parent != history {
+		t.Errorf("renamed canvas builds on %s, want its history up to %s", parent, history)
+	}
+	assertSettled(t, w.Root, 1)
+
+	for _, tt := range []struct{ canvas, name, err string }{
+		{"canvas-dora", "weeping", "canvas-weeping already exists in artist-picasso"},
+		{"canvas-guernica", "guernica", "canvas canvas-guernica not found in any artist"},
+	} {
+		before := snapshot(t, w.Root)
+		if err := RenameCanvas(w.Root, tt.canvas, tt.name); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("RenameCanvas(%s, %s) = %v, want error %q", tt.canvas, tt.name, err, tt.err)
+		}
+		if after := snapshot(t, w.Root); after != before {
+			t.Errorf("a refused rename changed the atelier:\n%s", after)
+		}
+	}
+	assertSettled(t, w.Root, 1)
+}
+
+func TestRenameArtist(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-weeping", "artist-monet/canvas-lilies")
+
+	if err := RenameArtist(w.Root, "artist-picasso", "pablo"); err != nil {
+		t.Fatalf("RenameArtist: %v", err)
+	}
+	artist := filepath.Join(w.Root, "artist-pablo")
+	assertSubmodules(t, w.Root, map[string]string{
+		"artist-pablo": w.Remote("artist-picasso"),
+		"artist-monet": w.Remote("artist-monet"),
+	})
+	assertSubmodules(t, artist, map[string]string{
+		"canvas-guernica": w.Remote("canvas-guernica"),
+		"canvas-weeping":  w.Remote("canvas-weeping"),
+	})
+	assertGitDir(t, w.Root, "artist-pablo")
+	assertMarker(t, artist, marker.KindArtist, "atelier-demo", "artist-pablo", "")
+	for _, canvas := range []string{"canvas-guernica", "canvas-weeping"} {
+		assertGitDir(t, artist, canvas)
+		assertMarker(t, filepath.Join(artist, canvas), marker.KindCanvas, "atelier-demo", "artist-pablo", canvas)
+	}
+	if status := gittest.Run(t, w.Root, "status", "--porcelain"); status != "" {
+		t.Errorf("atelier not committed after the rename:\n%s", status)
+	}
+	assertSettled(t, w.Root, 1)
+
+	before := snapshot(t, w.Root)
+	if err := RenameArtist(w.Root, "artist-pablo", "monet"); err == nil || !strings.Contains(err.Error(), "artist-monet already exists in atelier-demo") {
+		t.Errorf("renaming onto another artist = %v", err)
+	}
+	if after := snapshot(t, w.Root); after != before {
+		t.Errorf("a refused rename changed the atelier:\n%s", after)
+	}
+	assertSettled(t, w.Root, 1)
+}
@@ -0,0 +1,234 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/util"
+)
+
+// scpLikeURL matches scp-style Git addresses such as "git@github.com:acme/canvas-guernica.git".
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// IsRepoSource reports whether arg names a repository to clone (a Git URL, an scp-style address or a
+// filesystem path) rather than the directory name of an artist or canvas in the current atelier.
+func IsRepoSource(arg string) bool {
+	switch {
+	case strings.Contains(arg, "://"), scpLikeURL.MatchString(arg):
+		return true
+	case filepath.IsAbs(arg), strings.HasPrefix(arg, "~"), strings.HasPrefix(arg, "."):
+		return true
+	default:
+		return strings.ContainsRune(arg, os.PathSeparator)
+	}
+}
+
+// CloneArtistFromSource adds the artist repository at source (a Git URL or local path) to the atelier at
+// atelierPath as a submodule and initializes its canvases. The directory name is derived from the source
+// unless name is given. It returns the directory name of the cloned artist.
+func CloneArtistFromSource(atelierPath, source, name string) (artistDirName string, err error) {
+	atelierMarker, err := marker.Read(atelierPath, marker.KindAtelier)
+	if err != nil {
+		return "", err
+	}
+	tx, finish, err := begin(atelierPath, "artist clone", source, name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { err = finish(err) }()
+
+	artistDirName, err = cloneSource(tx, atelierPath, marker.KindArtist, source, name, func(m *marker.Marker) {
+		m.Atelier = atelierMarker.Atelier
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Canvases recorded with relative URLs only resolve when they live next to the artist's remote.
+	artistPath := filepath.Join(atelierPath, artistDirName)
+	if _, err := os.Stat(filepath.Join(artistPath, ".gitmodules")); err == nil {
+		fmt.Println("Initializing canvases...")
+		if err := gitutil.UpdateSubmodules(artistPath); err != nil {
+			fmt.Printf("Warning: could not initialize the canvases of %s: %v\n", artistDirName, err)
+			fmt.Printf("Fix their URLs in %s/.gitmodules and run 'git submodule update --init'.\n", artistDirName)
+		}
+	}
+	return artistDirName, nil
+}
+
+// CloneCanvasFromSource adds the canvas repository at source (a Git URL or local path) to the artist at
+// artistPath as a submodule. The directory name is derived from the source unless name is given.
+// It returns the directory name of the cloned canvas.
+func CloneCanvasFromSource(artistPath, source, name string) (canvasDirName string, err error) {
+	artistMarker, err := marker.Read(artistPath, marker.KindArtist)
+	if err != nil {
+		return "", err
+	}
+	tx, finish, err := begin(artistPath, "canvas clone", source, name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { err = finish(err) }()
+
+	return cloneSource(tx, artistPath, marker.KindCanvas, source, name, func(m *marker.Marker) {
+		m.Atelier, m.Artist = artistMarker.Atelier, artistMarker.Artist
+	})
+}
+
+// cloneSource clones source into parentPath as a submodule of the given kind, repairs its marker with
+// setContext and commits the new submodule in the parent.
+func cloneSource(tx *Tx, parentPath string, kind marker.Kind, source, name string, setContext func(*marker.Marker)) (dirName string, err error) {
+	url, err := resolveSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	base := strings.TrimPrefix(strings.TrimSpace(name), kind.Prefix())
+	if base == "" {
+		base = strings.TrimPrefix(sourceName(url), kind.Prefix())
+	}
+	if base == "" {
+		return "", fmt.Errorf("could not derive a %s name from %s; pass one explicitly", kind, source)
+	}
+	dirName, err = resolveConflict(parentPath, kind, kind.Prefix()+base, name != "")
+	if err != nil {
+		return "", err
+	}
+
+	// The clone creates the working tree and a git directory under the parent's .git/modules
+	childPath := filepath.Join(parentPath, dirName)
+	parentGitDir, err := gitutil.GitDir(parentPath)
+	if err != nil {
+		return "", err
+	}
+	if err = tx.Track(parentPath); err != nil {
+		return "", err
+	}
+	for _, path := range []string{childPath, filepath.Join(parentGitDir, "modules", dirName)} {
+		if err = tx.Created(path); err != nil {
+			return "", err
+		}
+	}
+
+	fmt.Printf("Cloning %s %s from %s...\n", kind, dirName, url)
+	if err = gitutil.AddSubmoduleURL(parentPath, url, dirName); err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", source, err)
+	}
+
+	if err = repairMarker(childPath, kind, dirName, url, setContext); err != nil {
+		return "", fmt.Errorf("failed to repair %s file: %w", kind.FileName(), err)
+	}
+
+	if err = gitutil.AddPaths(parentPath, ".gitmodules", dirName); err != nil {
+		return "", err
+	}
+	if err = gitutil.Commit(parentPath, fmt.Sprintf("feat: add cloned %s %s (from %s)", kind, dirName, source)); err != nil {
+		return "", err
+	}
+	return dirName, nil
+}
+
+// resolveSource turns local paths into absolute paths and checks that they hold a Git repository.
+// URLs are returned unchanged.
+func resolveSource(source string) (string, error) {
+	if strings.Contains(source, "://") || scpLikeURL.MatchString(source) {
+		return source, nil
+	}
+
+	path := source
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not expand %s: %w", source, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("source %s does not exist", source)
+	}
+	if _, err := gitutil.GitDir(path); err != nil {
+		return "", fmt.Errorf("source %s is not a git repository", source)
+	}
+	return path, nil
+}
+
+// sourceName derives a directory name from the last path element of a URL or path,
+// e.g. "git@github.com:acme/canvas-guernica.git" -> "canvas-guernica".
+func sourceName(url string) string {
+	name := strings.TrimRight(url, "/")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+// resolveConflict returns dirName if parentPath has no entry of that name. Otherwise an explicitly requested
+// name is an error, and the user is prompted for another name of the given kind until a free one is entered.
+// Without a terminal to prompt on, or if the user enters no name, the conflict is an error.
+func resolveConflict(parentPath string, kind marker.Kind, dirName string, explicit bool) (string, error) {
+	parentName := filepath.Base(parentPath)
+	if _, err := os.Stat(filepath.Join(parentPath, dirName)); os.IsNotExist(err) {
+		return dirName, nil
+	}
+	if explicit {
+		return "", fmt.Errorf("%s %s already exists in %s", kind, dirName, parentName)
+	}
+	if !util.IsInteractive() {
+		return "", fmt.Errorf("%s %s already exists in %s; give the %s another name", kind, dirName, parentName, kind)
+	}
+
+	label := strings.ToUpper(string(kind[:1])) + string(kind[1:])
+	fmt.Printf("%s '%s' already exists in '%s'.\n", label, dirName, parentName)
+	for {
+		inputRaw, err := util.Prompt(fmt.Sprintf("Enter a new name for the cloned %s (without '%s' prefix)", kind, kind.Prefix()))
+		if err != nil {
+			return "", fmt.Errorf("no new name entered for %s %s: %w", kind, dirName, err)
+		}
+		nameBase := strings.TrimPrefix(strings.TrimSpace(inputRaw), kind.Prefix())
+		if nameBase == "" {
+			return "", fmt.Errorf("no new name entered for %s %s", kind, dirName)
+		}
+		dirName = kind.Prefix() + nameBase
+		if _, err := os.Stat(filepath.Join(parentPath, dirName)); os.IsNotExist(err) {
+			return dirName, nil
+		}
+		fmt.Printf("%s '%s' also already exists in '%s'. Try another name.\n", label, dirName, parentName)
+	}
+}
+
+// repairMarker makes sure the cloned repository at childPath carries a current marker describing its new
+// place in the hierarchy, and commits the marker in the clone if it had to change.
+func repairMarker(childPath string, kind marker.Kind, dirName, url string, setContext func(*marker.Marker)) error {
+	m, err := marker.Read(childPath, kind)
+	if err != nil {
+		fmt.Printf("No usable %s file in %s, writing a new one.\n", kind.FileName(), dirName)
+		m = marker.New(kind, "", "", "")
+	}
+	setContext(m)
+	switch kind {
+	case marker.KindArtist:
+		m.Artist = dirName
+	case marker.KindCanvas:
+		m.Canvas = dirName
+	}
+	if m.Remote == nil {
+		m.Remote = &marker.Remote{Name: originRemote, URL: url}
+	}
+	if err := marker.Write(childPath, m); err != nil {
+		return err
+	}
+
+	if err := gitutil.AddPaths(childPath, kind.FileName()); err != nil {
+		return err
+	}
+	return commitStaged(childPath, fmt.Sprintf("chore: update %s file for %s", kind.FileName(), dirName))
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+func TestSourceName(t *testing.T) {
+	tests := []struct {
+		source   string
+		isSource bool
+		name     string
+	}{
+		{"git@github.com:acme/canvas-guernica.git", true, "canvas-guernica"},
+		{"https://github.com/acme/artist-picasso/", true, "artist-picasso"},
+		{"../remotes/canvas-lilies.git", true, "canvas-lilies"},
+		{"/srv/git/canvas-dora", true, "canvas-dora"},
+		{"canvas-guernica", false, "canvas-guernica"},
+	}
+	for _, tt := range tests {
+		if got := IsRepoSource(tt.source); got != tt.isSource {
+			t.Errorf("IsRepoSource(%q) = %v, want %v", tt.source, got, tt.isSource)
+		}
+		if got := sourceName(tt.source); got != tt.name {
+			t.Errorf("sourceName(%q) = %q, want %q", tt.source, got, tt.name)
+		}
+	}
+}
+
+func TestCloneCanvasFromSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string // Remote of the workspace to clone
+		as      string
+		dirName string
+		err     string
+	}{
+		{name: "derived name", source: "canvas-lilies", dirName: "canvas-lilies"},
+		{name: "given name", source: "canvas-guernica", as: "dora", dirName: "canvas-dora"},
+		{name: "given name taken", source: "canvas-lilies", as: "guernica", err: "canvas canvas-guernica already exists in artist-picasso"},
+		{name: "derived name taken", source: "canvas-guernica", err: "canvas canvas-guernica already exists in artist-picasso; give the canvas another name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-monet/canvas-lilies")
+			artist := filepath.Join(w.Root, "artist-picasso")
+			before := snapshot(t, w.Root)
+
+			dirName, err := CloneCanvasFromSource(artist, w.Remote(tt.source), tt.as)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("CloneCanvasFromSource = %q, %v; want error %q", dirName, err, tt.err)
+				}
+				if after := snapshot(t, w.Root); after != before {
+					t.Errorf("a refused clone changed the atelier:\n%s", after)
+				}
+				assertSettled(t, w.Root, 0)
+				return
+			}
+			if err != nil || dirName != tt.dirName {
+				t.Fatalf("CloneCanvasFromSource = %q, %v; want %s", dirName, err, tt.dirName)
+			}
+
+			canvas := filepath.Join(artist, dirName)
+			if url := gittest.Run(t, artist, "config", "-f", ".gitmodules", "submodule."+dirName+".url"); url != w.Remote(tt.source) {
+				t.Errorf(".gitmodules records %s at %q, want %s", dirName, url, w.Remote(tt.source))
+			}
+			if status := gittest.Run(t, artist, "status", "--porcelain"); status != "" {
+				t.Errorf("artist not committed after the clone:\n%s", status)
+			}
+			if gitDir := gittest.Run(t, canvas, "rev-parse", "--absolute-git-dir"); gitDir != filepath.Join(w.Root, ".git", "modules", "artist-picasso", "modules", dirName) {
+				t.Errorf("clone's git directory is %s, want it absorbed into the artist's", gitDir)
+			}
+			m, err := marker.Read(canvas, marker.KindCanvas)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Atelier != "atelier-demo" || m.Artist != "artist-picasso" || m.Canvas != dirName {
+				t.Errorf("marker of the clone = %s/%s/%s, want atelier-demo/artist-picasso/%s", m.Atelier, m.Artist, m.Canvas, dirName)
+			}
+			if status := gittest.Run(t, canvas, "status", "--porcelain"); status != "" {
+				t.Errorf("repaired marker not committed in the clone:\n%s", status)
+			}
+
+			if _, err := Undo(w.Root, false); err != nil {
+				t.Fatalf("Undo: %v", err)
+			}
+			if after := snapshot(t, w.Root); after != before {
+				t.Errorf("state after undoing the clone:\n%s\nwant:\n%s", after, before)
+			}
+		})
+	}
+}
+
+func TestCloneArtistFromSource(t *testing.T) {
+	source := gittest.New(t, "artist-monet/canvas-lilies", "artist-monet/canvas-poppies")
+	w := gittest.NewAtelier(t, "atelier-other", "artist-picasso/canvas-guernica")
+
+	dirName, err := CloneArtistFromSource(w.Root, source.Remote("artist-monet"), "")
+	if err != nil || dirName != "artist-monet" {
+		t.Fatalf("CloneArtistFromSource = %q, %v", dirName, err)
+	}
+	artist := filepath.Join(w.Root, dirName)
+	m, err := marker.Read(artist, marker.KindArtist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Atelier != "atelier-other" || m.Artist != "artist-monet" {
+		t.Errorf("marker of the clone = %s/%s, want atelier-other/artist-monet", m.Atelier, m.Artist)
+	}
+	submodules, err := gitutil.Backend().Submodules(artist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submodules) != 2 {
+		t.Fatalf("clone has submodules %v, want its two canvases", submodules)
+	}
+	for _, s := range submodules {
+		if s.Current != s.Recorded {
+			t.Errorf("canvas %s of the clone is not checked out", s.Path)
+		}
+	}
+
+	if _, err := CloneArtistFromSource(w.Root, t.TempDir(), "sketches"); err == nil || !strings.Contains(err.Error(), "is not a git repository") {
+		t.Errorf("cloning a directory that is no repository: %v", err)
+	}
+}
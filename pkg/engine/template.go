@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/guard"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/templates"
+)
+
+// SaveCanvasTemplate stores the canvas canvasFullName, wherever it lives in the atelier at
+// atelierPath, as the user's template pack templateName. The files git tracks in the canvas are
+// saved, except those its .gitignore matches, its marker and the agent files the CLI writes itself.
+func SaveCanvasTemplate(atelierPath, canvasFullName, templateName string, opts templates.SaveOptions) (*templates.Pack, error) {
+	artistPath, err := findCanvasArtist(atelierPath, canvasFullName)
+	if err != nil {
+		return nil, fmt.Errorf("could not find artist containing canvas %s: %w", canvasFullName, err)
+	}
+	canvasPath := filepath.Join(artistPath, canvasFullName)
+	m, err := marker.Read(canvasPath, marker.KindCanvas)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked, err := gitutil.Files(canvasPath, true)
+	if err != nil {
+		return nil, err
+	}
+	skip := append([]string{marker.KindCanvas.FileName(), ".gitmodules"}, guard.AgentFiles...)
+	var files []string
+	for _, file := range tracked {
+		if !slices.Contains(skip, file) {
+			files = append(files, file)
+		}
+	}
+
+	return templates.Save(canvasPath, files, m, templateName, opts)
+}
@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// MoveArtistToAtelier moves the artist artistFullName from the atelier at atelierPath into the atelier at
+// targetAtelierPath. The artist and its canvases keep their history; their markers are rewritten for the
+// target atelier and both ateliers commit the change. The operation is logged in the source atelier only,
+// where undoing it restores both ateliers.
+func MoveArtistToAtelier(atelierPath, artistFullName, targetAtelierPath string) (err error) {
+	targetMarker, err := checkTransferTarget(atelierPath, targetAtelierPath, marker.KindAtelier)
+	if err != nil {
+		return err
+	}
+	artistPath := filepath.Join(atelierPath, artistFullName)
+	if !marker.Exists(artistPath, marker.KindArtist) {
+		return fmt.Errorf("artist %s does not exist", artistFullName)
+	}
+	newArtistPath := filepath.Join(targetAtelierPath, artistFullName)
+	if _, err := os.Stat(newArtistPath); err == nil {
+		return fmt.Errorf("artist %s already exists in atelier %s", artistFullName, targetMarker.Atelier)
+	}
+
+	sourceAtelierName := filepath.Base(atelierPath)
+	fmt.Printf("Moving artist %s from atelier %s to atelier %s...\n", artistFullName, sourceAtelierName, targetMarker.Atelier)
+
+	tx, finish, err := begin(atelierPath, "artist move", artistFullName, targetAtelierPath)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.Track(atelierPath, targetAtelierPath); err != nil {
+		return err
+	}
+	if err = tx.TrackTree(artistPath); err != nil {
+		return err
+	}
+
+	// 1. Detach the artist from the source atelier (keeping the directory)
+	url, err := detachSubmodule(tx, atelierPath, artistFullName)
+	if err != nil {
+		return err
+	}
+
+	// 2. Move the directory into the target atelier
+	if err = tx.Rename(artistPath, newArtistPath); err != nil {
+		return fmt.Errorf("failed to move artist directory: %w", err)
+	}
+
+	// 3. Rewrite the context of every canvas, then of the artist itself
+	canvases, err := marker.ChildDirs(newArtistPath, marker.KindCanvas)
+	if err != nil {
+		return err
+	}
+	var toStage []string
+	for _, canvasPath := range canvases {
+		canvasDirName := filepath.Base(canvasPath)
+		if err = updateMarkerContext(canvasPath, marker.KindCanvas, func(m *marker.Marker) { m.Atelier = targetMarker.Atelier }); err != nil {
+			return fmt.Errorf("failed to update context of canvas %s: %w", canvasDirName, err)
+		}
+		if err = gitutil.AddPaths(canvasPath, marker.KindCanvas.FileName()); err != nil {
+			return err
+		}
+		if err = commitStaged(canvasPath, fmt.Sprintf("chore: move canvas to atelier %s", targetMarker.Atelier)); err != nil {
+			return err
+		}
+		toStage = append(toStage, canvasDirName)
+	}
+	if err = updateMarkerContext(newArtistPath, marker.KindArtist, func(m *marker.Marker) { m.Atelier = targetMarker.Atelier }); err != nil {
+		return fmt.Errorf("failed to update artist context: %w", err)
+	}
+	if err = gitutil.AddPaths(newArtistPath, append(toStage, marker.KindArtist.FileName())...); err != nil {
+		return err
+	}
+	if err = commitStaged(newArtistPath, fmt.Sprintf("chore: move artist to atelier %s", targetMarker.Atelier)); err != nil {
+		return err
+	}
+
+	// 4. Register the artist in the target atelier and commit both ateliers
+	if err = linkSubmodule(targetAtelierPath, artistFullName, url); err != nil {
+		return fmt.Errorf("failed to add artist as submodule to target atelier: %w", err)
+	}
+	if err = gitutil.AddPaths(targetAtelierPath, ".gitmodules", artistFullName); err != nil {
+		return err
+	}
+	if err = gitutil.Commit(targetAtelierPath, fmt.Sprintf("feat: add artist %s (moved from %s)", artistFullName, sourceAtelierName)); err != nil {
+		return err
+	}
+	if err = gitutil.Commit(atelierPath, fmt.Sprintf("feat: remove artist %s (moved to %s)", artistFullName, targetMarker.Atelier)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Artist %s successfully moved from %s to %s!\n", artistFullName, sourceAtelierName, targetMarker.Atelier)
+	return nil
+}
+
+// MoveCanvasToArtist moves the canvas canvasFullName, wherever it lives in the atelier at atelierPath, into the
+// artist at targetArtistPath in another atelier. Both artists and both ateliers commit the change. Like
+// MoveArtistToAtelier, the operation is logged in the source atelier only.
+func MoveCanvasToArtist(atelierPath, canvasFullName, targetArtistPath string) (err error) {
+	targetMarker, err := checkTransferTarget(atelierPath, targetArtistPath, marker.KindArtist)
+	if err != nil {
+		return err
+	}
+	targetAtelierPath, err := marker.FindRoot(targetArtistPath)
+	if err != nil {
+		return err
+	}
+	currentArtistPath, err := findCanvasArtist(atelierPath, canvasFullName)
+	if err != nil {
+		return fmt.Errorf("could not find artist containing canvas %s: %w", canvasFullName, err)
+	}
+	newCanvasPath := filepath.Join(targetArtistPath, canvasFullName)
+	if _, err := os.Stat(newCanvasPath); err == nil {
+		return fmt.Errorf("canvas %s already exists in artist %s", canvasFullName, targetMarker.Artist)
+	}
+
+	currentArtistName := filepath.Base(currentArtistPath)
+	fmt.Printf("Moving canvas %s from artist %s to artist %s in atelier %s...\n", canvasFullName, currentArtistName, targetMarker.Artist, targetMarker.Atelier)
+
+	tx, finish, err := begin(atelierPath, "canvas move", canvasFullName, targetArtistPath)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+	if err = tx.Track(atelierPath, currentArtistPath, filepath.Join(currentArtistPath, canvasFullName), targetAtelierPath, targetArtistPath); err != nil {
+		return err
+	}
+
+	// 1. Detach the canvas from its current artist (keeping the directory)
+	url, err := detachSubmodule(tx, currentArtistPath, canvasFullName)
+	if err != nil {
+		return err
+	}
+
+	// 2. Move the directory and rewrite its context
+	if err = tx.Rename(filepath.Join(currentArtistPath, canvasFullName), newCanvasPath); err != nil {
+		return fmt.Errorf("failed to move canvas directory: %w", err)
+	}
+	if err = updateMarkerContext(newCanvasPath, marker.KindCanvas, func(m *marker.Marker) {
+		m.Atelier, m.Artist = targetMarker.Atelier, targetMarker.Artist
+	}); err != nil {
+		return fmt.Errorf("failed to update canvas context: %w", err)
+	}
+	if err = gitutil.AddPaths(newCanvasPath, marker.KindCanvas.FileName()); err != nil {
+		return err
+	}
+	if err = commitStaged(newCanvasPath, fmt.Sprintf("chore: move canvas to %s/%s", targetMarker.Atelier, targetMarker.Artist)); err != nil {
+		return err
+	}
+
+	// 3. Register the canvas in the target artist and commit both sides up to the ateliers
+	if err = linkSubmodule(targetArtistPath, canvasFullName, url); err != nil {
+		return fmt.Errorf("failed to add canvas as submodule to target artist: %w", err)
+	}
+	if err = gitutil.AddPaths(targetArtistPath, ".gitmodules", canvasFullName); err != nil {
+		return err
+	}
+	if err = gitutil.Commit(targetArtistPath, fmt.Sprintf("feat: add canvas %s (moved from %s/%s)", canvasFullName, filepath.Base(atelierPath), currentArtistName)); err != nil {
+		return err
+	}
+	if err = gitutil.Commit(currentArtistPath, fmt.Sprintf("feat: remove canvas %s (moved to %s/%s)", canvasFullName, targetMarker.Atelier, targetMarker.Artist)); err != nil {
+		return err
+	}
+	for _, side := range []struct{ atelier, artist string }{
+		{targetAtelierPath, filepath.Base(targetArtistPath)},
+		{atelierPath, currentArtistName},
+	} {
+		if err = gitutil.AddPaths(side.atelier, side.artist); err != nil {
+			return err
+		}
+		if err = commitStaged(side.atelier, fmt.Sprintf("feat: update artist %s (move canvas %s)", side.artist, canvasFullName)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Canvas %s successfully moved to %s/%s!\n", canvasFullName, targetMarker.Atelier, targetMarker.Artist)
+	return nil
+}
+
+// checkTransferTarget validates that targetPath holds a marker of the given kind in an atelier other than
+// the one at atelierPath, and returns that marker.
+func checkTransferTarget(atelierPath, targetPath string, kind marker.Kind) (*marker.Marker, error) {
+	if !marker.Exists(targetPath, kind) {
+		return nil, fmt.Errorf("%s has no %s file", targetPath, kind.FileName())
+	}
+	targetRoot, err := marker.FindRoot(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	sourceRoot, err := filepath.Abs(atelierPath)
+	if err != nil {
+		return nil, err
+	}
+	if targetRoot == sourceRoot {
+		return nil, fmt.Errorf("%s belongs to the same atelier; use a move within the atelier instead", targetPath)
+	}
+	return marker.Read(targetPath, kind)
+}
+
+// detachSubmodule removes the submodule at path from the repository at parentPath while keeping its directory,
+// and stages the change. The submodule's git directory is moved into its working tree so the directory can
+// leave the parent. It returns the URL to register the submodule with elsewhere: empty for relative URLs.
+func detachSubmodule(tx *Tx, parentPath, path string) (string, error) {
+	url, _ := gitutil.ConfigGet(filepath.Join(parentPath, ".gitmodules"), "submodule."+path+".url")
+	if strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../") {
+		url = ""
+	}
+
+	if err := embedGitDir(tx, filepath.Join(parentPath, path)); err != nil {
+		return "", fmt.Errorf("failed to move git directory of %s: %w", path, err)
+	}
+	if err := gitutil.RemoveCached(parentPath, path); err != nil {
+		return "", fmt.Errorf("failed to remove %s from git index: %w", path, err)
+	}
+	if err := removeFromGitmodules(parentPath, path); err != nil {
+		return "", fmt.Errorf("failed to remove %s from .gitmodules: %w", path, err)
+	}
+	// The local config section only exists once the submodule is initialized
+	if config, err := gitutil.LocalConfig(parentPath); err == nil {
+		gitutil.ConfigRemoveSection(config, "submodule."+path)
+	}
+	if err := gitutil.AddPaths(parentPath, ".gitmodules"); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// embedGitDir moves the git directory of the submodule at worktree from its parent's .git/modules into
+// worktree/.git and re-points nested submodules, so the repository no longer depends on its parent.
+func embedGitDir(tx *Tx, worktree string) error {
+	links, err := gitLinks(worktree)
+	if err != nil {
+		return err
+	}
+	gitDir, absorbed := links[worktree]
+	if !absorbed {
+		return nil
+	}
+
+	gitFile := filepath.Join(worktree, ".git")
+	if err := tx.SaveFile(gitFile); err != nil {
+		return err
+	}
+	if err := os.Remove(gitFile); err != nil {
+		return err
+	}
+	if err := tx.Rename(gitDir, gitFile); err != nil {
+		return err
+	}
+	config := filepath.Join(gitFile, "config")
+	if err := tx.SaveFile(config); err != nil {
+		return err
+	}
+	if err := gitutil.ConfigUnset(config, "core.worktree"); err != nil {
+		return err
+	}
+
+	for child, childGitDir := range links {
+		if child == worktree {
+			continue
+		}
+		if err := writeGitLink(tx, child, swapPrefix(childGitDir, gitDir, gitFile)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+func TestMoveArtistToAtelier(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-weeping", "artist-monet/canvas-lilies")
+	target := gittest.NewAtelier(t, "atelier-other", "artist-vincent/canvas-sunflowers")
+
+	if err := MoveArtistToAtelier(w.Root, "artist-picasso", target.Root); err != nil {
+		t.Fatalf("MoveArtistToAtelier: %v", err)
+	}
+	artist := filepath.Join(target.Root, "artist-picasso")
+	assertSubmodules(t, w.Root, map[string]string{"artist-monet": w.Remote("artist-monet")})
+	assertSubmodules(t, target.Root, map[string]string{
+		"artist-vincent": target.Remote("artist-vincent"),
+		"artist-picasso": w.Remote("artist-picasso"),
+	})
+	assertSubmodules(t, artist, map[string]string{
+		"canvas-guernica": w.Remote("canvas-guernica"),
+		"canvas-weeping":  w.Remote("canvas-weeping"),
+	})
+	assertMarker(t, artist, marker.KindArtist, "atelier-other", "artist-picasso", "")
+	for _, canvas := range []string{"canvas-guernica", "canvas-weeping"} {
+		assertMarker(t, filepath.Join(artist, canvas), marker.KindCanvas, "atelier-other", "artist-picasso", canvas)
+	}
+	for _, root := range []string{w.Root, target.Root} {
+		if status := gittest.Run(t, root, "status", "--porcelain"); status != "" {
+			t.Errorf("%s not committed after the move:\n%s", filepath.Base(root), status)
+		}
+	}
+	assertSettled(t, w.Root, 1)
+	assertSettled(t, target.Root, 0)
+
+	for _, tt := range []struct{ artist, target, err string }{
+		{"artist-monet", w.Root, "belongs to the same atelier"},
+		{"artist-monet", filepath.Join(target.Root, "artist-vincent"), "has no .atelier file"},
+		{"artist-picasso", target.Root, "artist artist-picasso does not exist"},
+	} {
+		before, targetBefore := snapshot(t, w.Root), snapshot(t, target.Root)
+		if err := MoveArtistToAtelier(w.Root, tt.artist, tt.target); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("MoveArtistToAtelier(%s, %s) = %v, want error %q", tt.artist, tt.target, err, tt.err)
+		}
+		if snapshot(t, w.Root) != before || snapshot(t, target.Root) != targetBefore {
+			t.Errorf("a refused move of %s changed an atelier", tt.artist)
+		}
+	}
+}
+
+func TestMoveCanvasToArtist(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-weeping", "artist-monet/canvas-lilies")
+	target := gittest.NewAtelier(t, "atelier-other", "artist-vincent/canvas-sunflowers")
+	source, vincent := filepath.Join(w.Root, "artist-picasso"), filepath.Join(target.Root, "artist-vincent")
+	history := gittest.Head(t, filepath.Join(source, "canvas-guernica"))
+
+	if err := MoveCanvasToArtist(w.Root, "canvas-guernica", vincent); err != nil {
+		t.Fatalf("MoveCanvasToArtist: %v", err)
+	}
+	canvas := filepath.Join(vincent, "canvas-guernica")
+	assertSubmodules(t, source, map[string]string{"canvas-weeping": w.Remote("canvas-weeping")})
+	assertSubmodules(t, vincent, map[string]string{
+		"canvas-sunflowers": target.Remote("canvas-sunflowers"),
+		"canvas-guernica":   w.Remote("canvas-guernica"),
+	})
+	assertSubmodules(t, w.Root, map[string]string{
+		"artist-picasso": w.Remote("artist-picasso"),
+		"artist-monet":   w.Remote("artist-monet"),
+	})
+	assertSubmodules(t, target.Root, map[string]string{"artist-vincent": target.Remote("artist-vincent")})
+	assertMarker(t, canvas, marker.KindCanvas, "atelier-other", "artist-vincent", "canvas-guernica")
+	assertMarker(t, source, marker.KindArtist, "atelier-demo", "artist-picasso", "")
+	assertMarker(t, vincent, marker.KindArtist, "atelier-other", "artist-vincent", "")
+	if parent := gittest.Run(t, canvas, "rev-parse", "HEAD~1"); parent != history {
+		t.Errorf("moved canvas builds on %s, want its history up to %s", parent, history)
+	}
+	for _, root := range []string{w.Root, target.Root} {
+		if status := gittest.Run(t, root, "status", "--porcelain"); status != "" {
+			t.Errorf("%s not committed after the move:\n%s", filepath.Base(root), status)
+		}
+	}
+	assertSettled(t, w.Root, 1)
+	assertSettled(t, target.Root, 0)
+
+	for _, tt := range []struct{ canvas, target, err string }{
+		{"canvas-weeping", filepath.Join(w.Root, "artist-monet"), "belongs to the same atelier"},
+		{"canvas-weeping", target.Root, "has no .artist file"},
+		{"canvas-guernica", vincent, "canvas canvas-guernica not found in any artist"},
+	} {
+		before, targetBefore := snapshot(t, w.Root), snapshot(t, target.Root)
+		if err := MoveCanvasToArtist(w.Root, tt.canvas, tt.target); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("MoveCanvasToArtist(%s, %s) = %v, want error %q", tt.canvas, tt.target, err, tt.err)
+		}
+		if snapshot(t, w.Root) != before || snapshot(t, target.Root) != targetBefore {
+			t.Errorf("a refused move of %s changed an atelier", tt.canvas)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package forge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiClient is a minimal JSON client for the forges' REST APIs.
+type apiClient struct {
+	baseURL string
+	header  string // Authentication header name
+	value   string // Authentication header value
+	http    *http.Client
+}
+
+func newAPIClient(baseURL, header, value string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		header:  header,
+		value:   value,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends body (if not nil) as JSON and decodes the response into out (if not nil).
+// Responses outside the 2xx range are returned as errors including the API's message.
+func (c *apiClient) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(c.header, c.value)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s failed: %s: %s", method, path, resp.Status, strings.TrimSpace(string(content)))
+	}
+	if out != nil && len(content) > 0 {
+		if err := json.Unmarshal(content, out); err != nil {
+			return fmt.Errorf("could not decode response of %s %s: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// login returns the name of the authenticated user from a GitHub-style "/user" endpoint.
+func (c *apiClient) login(path string) (string, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.do(http.MethodGet, path, nil, &user); err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
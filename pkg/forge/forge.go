@@ -0,0 +1,89 @@
+package forge
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Forge provisions remote repositories on a hosting provider.
+// Repository names are bare names (e.g. "canvas-guernica"); the owner is part of the forge configuration.
+type Forge interface {
+	// Name identifies the provider, e.g. "github". It is recorded in marker files.
+	Name() string
+	// CreateRepo creates an empty repository.
+	CreateRepo(name string) error
+	// DeleteRepo deletes a repository and everything in it.
+	DeleteRepo(name string) error
+	// SetDefaultBranch makes branch the default branch of a repository that already has it pushed.
+	SetDefaultBranch(name, branch string) error
+	// CloneURL returns the URL used as the origin remote for a repository.
+	CloneURL(name string) string
+}
+
+// Providers lists the supported provider names.
+var Providers = []string{"github", "gitlab", "gitea", "local"}
+
+// Config selects and configures a forge.
+type Config struct {
+	Provider string // One of Providers
+	Owner    string // User or organization (group for GitLab) owning the repositories; the token's user when empty
+	URL      string // API base URL for self-hosted instances; the parent directory of bare repositories for "local"
+	Token    string // API token; read from the provider's environment variable when empty
+	Public   bool   // Create public repositories; they are private by default
+	Protocol string // "ssh" (default) or "https" clone URLs
+}
+
+// New returns the forge described by cfg.
+func New(cfg Config) (Forge, error) {
+	if cfg.Protocol == "" {
+		cfg.Protocol = "ssh"
+	}
+	if cfg.Protocol != "ssh" && cfg.Protocol != "https" {
+		return nil, fmt.Errorf("unknown clone protocol %q (want ssh or https)", cfg.Protocol)
+	}
+
+	switch cfg.Provider {
+	case "github":
+		return newGitHub(cfg)
+	case "gitlab":
+		return newGitLab(cfg)
+	case "gitea":
+		return newGitea(cfg)
+	case "local":
+		return newLocal(cfg)
+	default:
+		return nil, fmt.Errorf("unknown forge %q (supported: %s)", cfg.Provider, strings.Join(Providers, ", "))
+	}
+}
+
+// token returns cfg.Token, falling back to the first set environment variable in envVars.
+func token(cfg Config, envVars ...string) (string, error) {
+	if cfg.Token != "" {
+		return cfg.Token, nil
+	}
+	for _, name := range envVars {
+		if value := os.Getenv(name); value != "" {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("%s forge needs an API token; set %s", cfg.Provider, strings.Join(envVars, " or "))
+}
+
+// cloneURL builds an ssh or https clone URL for owner/name on host.
+func cloneURL(protocol, host, owner, name string) string {
+	if protocol == "https" {
+		return fmt.Sprintf("https://%s/%s/%s.git", host, owner, name)
+	}
+	return fmt.Sprintf("git@%s:%s/%s.git", host, owner, name)
+}
+
+// hostOf returns the host part of a base URL.
+func hostOf(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid forge URL %q", baseURL)
+	}
+	return u.Host, nil
+}
@@ -0,0 +1,54 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCreateRepoVisibility checks that the API forges create private repositories unless Public is set.
+func TestCreateRepoVisibility(t *testing.T) {
+	var created map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"login": "acme", "username": "acme"}`)
+			return
+		}
+		created = nil
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		provider string
+		key      string
+		private  any
+		public   any
+	}{
+		{"github", "private", true, false},
+		{"gitea", "private", true, false},
+		{"gitlab", "visibility", "private", "public"},
+	}
+	for _, tt := range tests {
+		for _, public := range []bool{false, true} {
+			f, err := New(Config{Provider: tt.provider, URL: server.URL, Token: "token", Public: public})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tt.provider, err)
+			}
+			if err := f.CreateRepo("canvas-guernica"); err != nil {
+				t.Fatalf("%s CreateRepo: %v", tt.provider, err)
+			}
+			want := tt.private
+			if public {
+				want = tt.public
+			}
+			if created[tt.key] != want {
+				t.Errorf("%s with Public %v: %s = %v, want %v", tt.provider, public, tt.key, created[tt.key], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// gitea provisions repositories through the Gitea (and Forgejo) REST API.
+type gitea struct {
+	api      *apiClient
+	owner    string
+	org      bool // Owner is an organization rather than the token's user
+	host     string
+	public   bool
+	protocol string
+}
+
+func newGitea(cfg Config) (Forge, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("gitea forge needs the instance URL, e.g. https://gitea.example.com")
+	}
+	tok, err := token(cfg, "GITEA_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	host, err := hostOf(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gitea{
+		api:      newAPIClient(strings.TrimRight(cfg.URL, "/")+"/api/v1", "Authorization", "token "+tok),
+		host:     host,
+		public:   cfg.Public,
+		protocol: cfg.Protocol,
+	}
+	login, err := g.api.login("/user")
+	if err != nil {
+		return nil, fmt.Errorf("could not authenticate with Gitea: %w", err)
+	}
+	g.owner, g.org = cfg.Owner, cfg.Owner != "" && cfg.Owner != login
+	if g.owner == "" {
+		g.owner = login
+	}
+	return g, nil
+}
+
+func (g *gitea) Name() string { return "gitea" }
+
+func (g *gitea) CreateRepo(name string) error {
+	path := "/user/repos"
+	if g.org {
+		path = "/orgs/" + g.owner + "/repos"
+	}
+	return g.api.do(http.MethodPost, path, map[string]any{"name": name, "private": !g.public}, nil)
+}
+
+func (g *gitea) DeleteRepo(name string) error {
+	return g.api.do(http.MethodDelete, "/repos/"+g.owner+"/"+name, nil, nil)
+}
+
+func (g *gitea) SetDefaultBranch(name, branch string) error {
+	return g.api.do(http.MethodPatch, "/repos/"+g.owner+"/"+name, map[string]any{"default_branch": branch}, nil)
+}
+
+func (g *gitea) CloneURL(name string) string {
+	return cloneURL(g.protocol, g.host, g.owner, name)
+}
@@ -0,0 +1,65 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// githubAPI is the public GitHub API; GitHub Enterprise uses "https://<host>/api/v3".
+const githubAPI = "https://api.github.com"
+
+// gitHub provisions repositories through the GitHub REST API.
+type gitHub struct {
+	api      *apiClient
+	owner    string
+	org      bool // Owner is an organization rather than the token's user
+	host     string
+	public   bool
+	protocol string
+}
+
+func newGitHub(cfg Config) (Forge, error) {
+	tok, err := token(cfg, "GITHUB_TOKEN", "GH_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	baseURL, host := cfg.URL, "github.com"
+	if baseURL == "" {
+		baseURL = githubAPI
+	} else if host, err = hostOf(baseURL); err != nil {
+		return nil, err
+	}
+
+	g := &gitHub{api: newAPIClient(baseURL, "Authorization", "Bearer "+tok), host: host, public: cfg.Public, protocol: cfg.Protocol}
+	login, err := g.api.login("/user")
+	if err != nil {
+		return nil, fmt.Errorf("could not authenticate with GitHub: %w", err)
+	}
+	g.owner, g.org = cfg.Owner, cfg.Owner != "" && cfg.Owner != login
+	if g.owner == "" {
+		g.owner = login
+	}
+	return g, nil
+}
+
+func (g *gitHub) Name() string { return "github" }
+
+func (g *gitHub) CreateRepo(name string) error {
+	path := "/user/repos"
+	if g.org {
+		path = "/orgs/" + g.owner + "/repos"
+	}
+	return g.api.do(http.MethodPost, path, map[string]any{"name": name, "private": !g.public}, nil)
+}
+
+func (g *gitHub) DeleteRepo(name string) error {
+	return g.api.do(http.MethodDelete, "/repos/"+g.owner+"/"+name, nil, nil)
+}
+
+func (g *gitHub) SetDefaultBranch(name, branch string) error {
+	return g.api.do(http.MethodPatch, "/repos/"+g.owner+"/"+name, map[string]any{"default_branch": branch}, nil)
+}
+
+func (g *gitHub) CloneURL(name string) string {
+	return cloneURL(g.protocol, g.host, g.owner, name)
+}
@@ -0,0 +1,93 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitLabURL is gitlab.com; self-managed instances pass their own base URL.
+const gitLabURL = "https://gitlab.com"
+
+// gitLab provisions projects through the GitLab REST API.
+type gitLab struct {
+	api         *apiClient
+	owner       string // Namespace path (user or group)
+	namespaceID int    // Zero for the token's personal namespace
+	host        string
+	public      bool
+	protocol    string
+}
+
+func newGitLab(cfg Config) (Forge, error) {
+	tok, err := token(cfg, "GITLAB_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	baseURL := cfg.URL
+	if baseURL == "" {
+		baseURL = gitLabURL
+	}
+	host, err := hostOf(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gitLab{
+		api:      newAPIClient(strings.TrimRight(baseURL, "/")+"/api/v4", "PRIVATE-TOKEN", tok),
+		host:     host,
+		public:   cfg.Public,
+		protocol: cfg.Protocol,
+	}
+
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := g.api.do(http.MethodGet, "/user", nil, &user); err != nil {
+		return nil, fmt.Errorf("could not authenticate with GitLab: %w", err)
+	}
+	g.owner = user.Username
+	if cfg.Owner != "" && cfg.Owner != user.Username {
+		var namespace struct {
+			ID       int    `json:"id"`
+			FullPath string `json:"full_path"`
+		}
+		if err := g.api.do(http.MethodGet, "/namespaces/"+url.PathEscape(cfg.Owner), nil, &namespace); err != nil {
+			return nil, fmt.Errorf("could not find GitLab namespace %s: %w", cfg.Owner, err)
+		}
+		g.owner, g.namespaceID = namespace.FullPath, namespace.ID
+	}
+	return g, nil
+}
+
+func (g *gitLab) Name() string { return "gitlab" }
+
+func (g *gitLab) CreateRepo(name string) error {
+	visibility := "private"
+	if g.public {
+		visibility = "public"
+	}
+	body := map[string]any{"name": name, "path": name, "visibility": visibility}
+	if g.namespaceID != 0 {
+		body["namespace_id"] = g.namespaceID
+	}
+	return g.api.do(http.MethodPost, "/projects", body, nil)
+}
+
+func (g *gitLab) DeleteRepo(name string) error {
+	return g.api.do(http.MethodDelete, "/projects/"+g.project(name), nil, nil)
+}
+
+func (g *gitLab) SetDefaultBranch(name, branch string) error {
+	return g.api.do(http.MethodPut, "/projects/"+g.project(name), map[string]any{"default_branch": branch}, nil)
+}
+
+func (g *gitLab) CloneURL(name string) string {
+	return cloneURL(g.protocol, g.host, g.owner, name)
+}
+
+// project returns the URL-encoded "namespace/name" project ID.
+func (g *gitLab) project(name string) string {
+	return url.PathEscape(g.owner + "/" + name)
+}
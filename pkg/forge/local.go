@@ -0,0 +1,56 @@
+package forge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+)
+
+// local keeps bare repositories in a directory, e.g. a shared drive or a test fixture.
+type local struct {
+	dir string
+}
+
+func newLocal(cfg Config) (Forge, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("local forge needs a directory for its bare repositories")
+	}
+	dir, err := filepath.Abs(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	return &local{dir: dir}, nil
+}
+
+func (l *local) Name() string { return "local" }
+
+func (l *local) CreateRepo(name string) error {
+	path := l.CloneURL(name)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("repository %s already exists", path)
+	}
+	if err := fs.CreateDir(l.dir); err != nil {
+		return err
+	}
+	_, err := gitutil.RunGitCommandOutput(l.dir, "init", "--bare", path)
+	return err
+}
+
+func (l *local) DeleteRepo(name string) error {
+	if err := os.RemoveAll(l.CloneURL(name)); err != nil {
+		return fmt.Errorf("failed to remove repository %s: %w", name, err)
+	}
+	return nil
+}
+
+func (l *local) SetDefaultBranch(name, branch string) error {
+	_, err := gitutil.RunGitCommandOutput(l.CloneURL(name), "symbolic-ref", "HEAD", "refs/heads/"+branch)
+	return err
+}
+
+func (l *local) CloneURL(name string) string {
+	return filepath.Join(l.dir, name+".git")
+}
@@ -0,0 +1,199 @@
+package gitutil_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+)
+
+// withoutGit runs fn with a PATH that holds no git binary, so only in-process code can work.
+func withoutGit(t *testing.T, fn func()) {
+	t.Helper()
+	path := os.Getenv("PATH")
+	t.Setenv("PATH", t.TempDir())
+	defer os.Setenv("PATH", path)
+	fn()
+}
+
+// backends runs test on each backend; go-git without a git binary.
+func backends(t *testing.T, test func(t *testing.T, g gitutil.Git, run func(func()))) {
+	t.Run("exec", func(t *testing.T) {
+		test(t, gitutil.ExecGit{}, func(fn func()) { fn() })
+	})
+	t.Run("go-git", func(t *testing.T) {
+		test(t, gitutil.GoGit{}, func(fn func()) { withoutGit(t, fn) })
+	})
+}
+
+// repositoryState builds an artist whose repositories are in every state the queries distinguish:
+// a staged file and an unpushed commit in the artist; canvas-guernica behind its upstream;
+// canvas-dora ahead of the commit its artist records, on a detached HEAD; canvas-weeping not
+// checked out.
+func repositoryState(t *testing.T) (w *gittest.Workspace, artist string) {
+	w = gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-dora", "artist-picasso/canvas-weeping")
+	artist = filepath.Join(w.Root, "artist-picasso")
+
+	guernica := filepath.Join(artist, "canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(guernica, "sketch.md"), "sketch\n")
+	gittest.Run(t, guernica, "add", "sketch.md")
+	gittest.Run(t, guernica, "commit", "--quiet", "-m", "Sketch")
+	gittest.Run(t, guernica, "push", "--quiet", "origin", "main")
+	gittest.Run(t, guernica, "reset", "--quiet", "--hard", "HEAD~1")
+
+	dora := filepath.Join(artist, "canvas-dora")
+	gittest.Run(t, dora, "commit", "--quiet", "--allow-empty", "-m", "Portrait")
+	gittest.Run(t, dora, "checkout", "--quiet", "--detach")
+
+	gittest.Run(t, artist, "submodule", "deinit", "--quiet", "--force", "canvas-weeping")
+	gittest.Run(t, artist, "commit", "--quiet", "--allow-empty", "-m", "Unpushed")
+	gittest.WriteFile(t, filepath.Join(artist, "notes.md"), "staged\n")
+	gittest.Run(t, artist, "add", "notes.md")
+	return w, artist
+}
+
+func TestBackendQueries(t *testing.T) {
+	w, artist := repositoryState(t)
+	guernica, dora := filepath.Join(artist, "canvas-guernica"), filepath.Join(artist, "canvas-dora")
+	recorded := map[string]string{}
+	for _, name := range []string{"canvas-guernica", "canvas-dora", "canvas-weeping"} {
+		recorded[name] = gittest.Run(t, artist, "rev-parse", "HEAD:"+name)
+	}
+
+	queries := []struct {
+		name  string
+		query func(g gitutil.Git) (any, error)
+		want  any
+	}{
+		{"StagedFile", func(g gitutil.Git) (any, error) {
+			content, err := g.StagedFile(artist, "notes.md")
+			return string(content), err
+		}, "staged\n"},
+		{"HookPath", func(g gitutil.Git) (any, error) {
+			return g.HookPath(artist, "pre-commit")
+		}, filepath.Join(w.Root, ".git", "modules", "artist-picasso", "hooks", "pre-commit")},
+		{"CurrentBranch", func(g gitutil.Git) (any, error) { return g.CurrentBranch(artist) }, "main"},
+		{"CurrentBranch detached", func(g gitutil.Git) (any, error) { return g.CurrentBranch(dora) }, ""},
+		{"RefExists", func(g gitutil.Git) (any, error) { return g.RefExists(artist, "refs/remotes/origin/main") }, true},
+		{"RefExists missing", func(g gitutil.Git) (any, error) { return g.RefExists(artist, "refs/heads/sketches") }, false},
+		{"IsAncestor", func(g gitutil.Git) (any, error) { return g.IsAncestor(artist, "origin/main", "HEAD") }, true},
+		{"IsAncestor reversed", func(g gitutil.Git) (any, error) { return g.IsAncestor(artist, "HEAD", "origin/main") }, false},
+		{"RemoteURL", func(g gitutil.Git) (any, error) { return g.RemoteURL(artist, "origin") }, w.Remote("artist-picasso")},
+		{"RemoteURL missing", func(g gitutil.Git) (any, error) { return g.RemoteURL(artist, "upstream") }, ""},
+		{"AheadBehind ahead", func(g gitutil.Git) (any, error) {
+			ahead, behind, hasUpstream, err := g.AheadBehind(artist)
+			return [3]any{ahead, behind, hasUpstream}, err
+		}, [3]any{1, 0, true}},
+		{"AheadBehind behind", func(g gitutil.Git) (any, error) {
+			ahead, behind, hasUpstream, err := g.AheadBehind(guernica)
+			return [3]any{ahead, behind, hasUpstream}, err
+		}, [3]any{0, 1, true}},
+		{"AheadBehind detached", func(g gitutil.Git) (any, error) {
+			ahead, behind, hasUpstream, err := g.AheadBehind(dora)
+			return [3]any{ahead, behind, hasUpstream}, err
+		}, [3]any{0, 0, false}},
+		{"HasUnpushedCommits", func(g gitutil.Git) (any, error) { return g.HasUnpushedCommits(artist) }, true},
+		{"HasUnpushedCommits behind", func(g gitutil.Git) (any, error) { return g.HasUnpushedCommits(guernica) }, false},
+		{"Submodules", func(g gitutil.Git) (any, error) { return g.Submodules(artist) }, []gitutil.Submodule{
+			{Path: "canvas-dora", Recorded: recorded["canvas-dora"], Current: gittest.Head(t, dora)},
+			{Path: "canvas-guernica", Recorded: recorded["canvas-guernica"], Current: recorded["canvas-guernica"]},
+			{Path: "canvas-weeping", Recorded: recorded["canvas-weeping"]},
+		}},
+		{"SubmoduleName", func(g gitutil.Git) (any, error) { return g.SubmoduleName(artist, "canvas-dora") }, "canvas-dora"},
+		{"SubmoduleCommit", func(g gitutil.Git) (any, error) { return g.SubmoduleCommit(artist, "canvas-weeping") }, recorded["canvas-weeping"]},
+		{"SubmoduleCommit of a file", func(g gitutil.Git) (any, error) { return g.SubmoduleCommit(artist, ".artist") }, ""},
+	}
+	backends(t, func(t *testing.T, g gitutil.Git, run func(func())) {
+		for _, q := range queries {
+			var got any
+			var err error
+			run(func() { got, err = q.query(g) })
+			if err != nil {
+				t.Errorf("%s: %v", q.name, err)
+			} else if !reflect.DeepEqual(got, q.want) {
+				t.Errorf("%s = %#v, want %#v", q.name, got, q.want)
+			}
+		}
+
+		run(func() {
+			if _, err := g.StagedFile(artist, "sketch.md"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("StagedFile of an unstaged path: %v, want os.ErrNotExist", err)
+			}
+			if _, err := g.SubmoduleName(artist, "notes.md"); err == nil {
+				t.Error("SubmoduleName of a file succeeded")
+			}
+		})
+	})
+}
+
+func TestBackendHookPath(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	gittest.Run(t, w.Root, "config", "core.hooksPath", ".githooks")
+	backends(t, func(t *testing.T, g gitutil.Git, run func(func())) {
+		run(func() {
+			path, err := g.HookPath(w.Root, "pre-commit")
+			if want := filepath.Join(w.Root, ".githooks", "pre-commit"); err != nil || path != want {
+				t.Errorf("HookPath = %q, %v; want %s", path, err, want)
+			}
+		})
+	})
+}
+
+func TestBackendSetRemote(t *testing.T) {
+	backends(t, func(t *testing.T, g gitutil.Git, run func(func())) {
+		w := gittest.New(t, "artist-picasso/canvas-guernica")
+		run(func() {
+			for _, remote := range []struct{ name, url string }{
+				{"backup", "/srv/backup/atelier-demo.git"},
+				{"backup", "/srv/mirror/atelier-demo.git"},
+				{"origin", "/srv/origin/atelier-demo.git"},
+			} {
+				if err := g.SetRemote(w.Root, remote.name, remote.url); err != nil {
+					t.Fatalf("SetRemote(%s): %v", remote.name, err)
+				}
+				if url, err := g.RemoteURL(w.Root, remote.name); err != nil || url != remote.url {
+					t.Errorf("RemoteURL(%s) = %q, %v; want %s", remote.name, url, err, remote.url)
+				}
+			}
+		})
+		if refspec := gittest.Run(t, w.Root, "config", "remote.backup.fetch"); refspec != "+refs/heads/*:refs/remotes/backup/*" {
+			t.Errorf("fetch refspec of the added remote = %q", refspec)
+		}
+	})
+}
+
+func TestBackendSubmoduleUpdate(t *testing.T) {
+	backends(t, func(t *testing.T, g gitutil.Git, run func(func())) {
+		w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-dora")
+		artist := filepath.Join(w.Root, "artist-picasso")
+		gittest.Run(t, artist, "submodule", "deinit", "--quiet", "--force", "--all")
+
+		run(func() {
+			if err := g.SubmoduleUpdate(artist, "canvas-dora"); err != nil {
+				t.Fatalf("SubmoduleUpdate(canvas-dora): %v", err)
+			}
+			if err := g.SubmoduleUpdate(artist); err != nil {
+				t.Fatalf("SubmoduleUpdate: %v", err)
+			}
+			submodules, err := g.Submodules(artist)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range submodules {
+				if s.Current != s.Recorded {
+					t.Errorf("%s checked out at %q, want %s", s.Path, s.Current, s.Recorded)
+				}
+			}
+		})
+		// git itself recognizes the checked-out submodules
+		status := gittest.Run(t, artist, "submodule", "status")
+		if lines := strings.Split(status, "\n"); len(lines) != 2 || strings.ContainsAny(lines[0][:1]+lines[1][:1], "+-U") {
+			t.Errorf("git submodule status after the update:\n%s", status)
+		}
+	})
+}
@@ -0,0 +1,419 @@
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ExecGit implements Git by running the git binary.
+type ExecGit struct{}
+
+func (ExecGit) Init(dir string) error {
+	return RunGitCommand(dir, "init")
+}
+
+func (ExecGit) Add(dir string, paths ...string) error {
+	if len(paths) == 0 {
+		return RunGitCommand(dir, "add", ".")
+	}
+	return RunGitCommand(dir, append([]string{"add", "--"}, paths...)...)
+}
+
+func (ExecGit) Commit(dir, message string) error {
+	return RunGitCommand(dir, "commit", "-m", message)
+}
+
+// SubmoduleAdd allows local paths and file:// URLs explicitly, as git refuses them for submodules by default.
+func (ExecGit) SubmoduleAdd(parentDir, url, path string) error {
+	return RunGitCommand(parentDir, "-c", "protocol.file.allow=always", "submodule", "add", url, path)
+}
+
+func (ExecGit) SubmoduleDeinit(parentDir, path string) error {
+	return RunGitCommand(parentDir, "submodule", "deinit", path)
+}
+
+func (ExecGit) Remove(dir, path string, cached bool) error {
+	args := []string{"rm", "-q"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	return RunGitCommand(dir, append(args, "--", path)...)
+}
+
+func (ExecGit) Status(dir string, paths ...string) ([]string, error) {
+	args := []string{"status", "--porcelain"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := RunGitCommandOutput(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func (ExecGit) Log(dir string, n int) ([]LogEntry, error) {
+	args := []string{"log", "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s"}
+	if n > 0 {
+		args = append(args, fmt.Sprintf("-n%d", n))
+	}
+	out, err := RunGitCommandOutput(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []LogEntry
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		commits = append(commits, LogEntry{Hash: fields[0], Author: fields[1], Email: fields[2], Date: date, Subject: fields[4]})
+	}
+	return commits, nil
+}
+
+func (ExecGit) Push(dir, remote, branch string, setUpstream, force bool) error {
+	args := []string{"push"}
+	if setUpstream {
+		args = append(args, "--set-upstream")
+	}
+	if force {
+		args = append(args, "--force-with-lease")
+	}
+	args = append(args, remote, branch)
+	return RunGitCommand(dir, args...)
+}
+
+func (ExecGit) Fetch(dir, remote string) error {
+	return RunGitCommand(dir, "fetch", "--prune", "--no-recurse-submodules", remote)
+}
+
+func (ExecGit) GitDir(dir string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (ExecGit) HeadCommit(dir string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (ExecGit) HasStagedChanges(dir string) (bool, error) {
+	out, err := RunGitCommandOutput(dir, "diff", "--cached", "--name-only")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+func (ExecGit) Files(dir string, excludeIgnored bool) ([]string, error) {
+	files, err := lsFiles(dir, "--cached")
+	if err != nil || !excludeIgnored {
+		return files, err
+	}
+	ignored, err := lsFiles(dir, "--cached", "--ignored", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(file string) bool { return slices.Contains(ignored, file) }), nil
+}
+
+// lsFiles returns the paths git ls-files lists in dir with the given options.
+func lsFiles(dir string, options ...string) ([]string, error) {
+	out, err := RunGitCommandOutput(dir, append([]string{"ls-files", "-z"}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (ExecGit) MoveGitlink(dir, oldPath, newPath string) error {
+	out, err := RunGitCommandOutput(dir, "ls-files", "--stage", "--", oldPath)
+	if err != nil {
+		return err
+	}
+	// Format: "160000 <sha> <stage>\t<path>"
+	fields := strings.Fields(out)
+	if len(fields) < 2 || fields[0] != "160000" {
+		return fmt.Errorf("%s is not a submodule in the index of %s", oldPath, dir)
+	}
+	if _, err := RunGitCommandOutput(dir, "update-index", "--force-remove", "--", oldPath); err != nil {
+		return err
+	}
+	_, err = RunGitCommandOutput(dir, "update-index", "--add", "--cacheinfo", "160000,"+fields[1]+","+newPath)
+	return err
+}
+
+func (ExecGit) ResetSoft(dir, commit string) error {
+	return RunGitCommand(dir, "reset", "--soft", commit)
+}
+
+func (ExecGit) StagedFile(dir, path string) ([]byte, error) {
+	out, err := RunGitCommandOutput(dir, "ls-files", "--cached", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(out) == "" {
+		return nil, fmt.Errorf("%s is not staged in %s: %w", path, dir, os.ErrNotExist)
+	}
+	out, err = RunGitCommandOutput(dir, "show", ":"+path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (ExecGit) HookPath(dir, name string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "rev-parse", "--path-format=absolute", "--git-path", "hooks/"+name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (ExecGit) CurrentBranch(dir string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "branch", "--show-current")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// RefExists exits with 1 when the ref does not exist.
+func (ExecGit) RefExists(dir, ref string) (bool, error) {
+	err := RunGitCommand(dir, "show-ref", "--verify", "--quiet", ref)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IsAncestor exits with 1 when ancestor is not an ancestor of descendant.
+func (ExecGit) IsAncestor(dir, ancestor, descendant string) (bool, error) {
+	err := RunGitCommand(dir, "merge-base", "--is-ancestor", ancestor, descendant)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RemoteURL exits with 2 when the remote does not exist.
+func (ExecGit) RemoteURL(dir, remote string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "remote", "get-url", remote)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+		return "", nil
+	}
+	return strings.TrimSpace(out), err
+}
+
+func (g ExecGit) SetRemote(dir, remote, url string) error {
+	current, err := g.RemoteURL(dir, remote)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return RunGitCommand(dir, "remote", "add", remote, url)
+	}
+	return RunGitCommand(dir, "remote", "set-url", remote, url)
+}
+
+func (ExecGit) AheadBehind(dir string) (ahead, behind int, hasUpstream bool, err error) {
+	if _, err := RunGitCommandOutput(dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"); err != nil {
+		return 0, 0, false, nil
+	}
+	out, err := RunGitCommandOutput(dir, "rev-list", "--count", "--left-right", "@{upstream}...HEAD")
+	if err != nil {
+		return 0, 0, true, err
+	}
+	if _, err := fmt.Sscanf(strings.TrimSpace(out), "%d\t%d", &behind, &ahead); err != nil {
+		return 0, 0, true, fmt.Errorf("could not parse ahead/behind counts %q: %w", out, err)
+	}
+	return ahead, behind, true, nil
+}
+
+// HasUnpushedCommits treats a missing origin/HEAD or HEAD, which git reports as unknown revisions,
+// as nothing unpushed.
+func (ExecGit) HasUnpushedCommits(dir string) (bool, error) {
+	out, err := RunGitCommandOutput(dir, "log", "--oneline", "origin..HEAD")
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have a commit") ||
+			strings.Contains(err.Error(), "unknown revision") ||
+			strings.Contains(err.Error(), "No remote configured") {
+			return false, nil
+		}
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Submodules combines `git submodule status`, which lists the checked-out commits, with its
+// --cached form, which lists the recorded ones.
+func (ExecGit) Submodules(dir string) ([]Submodule, error) {
+	current, err := submoduleStatus(dir)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := submoduleStatus(dir, "--cached")
+	if err != nil {
+		return nil, err
+	}
+	submodules := make([]Submodule, 0, len(recorded))
+	for _, line := range recorded {
+		s := Submodule{Path: line.path, Recorded: line.commit}
+		for _, c := range current {
+			if c.path == line.path && c.state != '-' {
+				s.Current = c.commit
+			}
+		}
+		submodules = append(submodules, s)
+	}
+	return submodules, nil
+}
+
+// submoduleLine is a line of `git submodule status`.
+type submoduleLine struct {
+	state  byte // ' ', '+', '-' or 'U'
+	commit string
+	path   string
+}
+
+func submoduleStatus(dir string, options ...string) ([]submoduleLine, error) {
+	out, err := RunGitCommandOutput(dir, append([]string{"submodule", "status"}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	var lines []submoduleLine
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		// Format: "<state><sha> <path> (<describe>)"
+		fields := strings.Fields(line[1:])
+		if len(fields) >= 2 {
+			lines = append(lines, submoduleLine{state: line[0], commit: fields[0], path: fields[1]})
+		}
+	}
+	return lines, nil
+}
+
+func (ExecGit) SubmoduleName(dir, path string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "config", "-f", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	if err != nil {
+		return "", fmt.Errorf("no submodules registered in %s: %w", dir, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if ok && value == path {
+			return strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path"), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a submodule of %s", path, dir)
+}
+
+func (ExecGit) SubmoduleCommit(dir, path string) (string, error) {
+	out, err := RunGitCommandOutput(dir, "ls-tree", "HEAD", "--", path)
+	if err != nil {
+		return "", err
+	}
+	// Format: "<mode> <type> <sha>\t<path>"
+	fields := strings.Fields(out)
+	if len(fields) < 3 || fields[1] != "commit" {
+		return "", nil
+	}
+	return fields[2], nil
+}
+
+func (ExecGit) SubmoduleUpdate(dir string, paths ...string) error {
+	args := []string{"-c", "protocol.file.allow=always", "submodule", "update", "--init"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	return RunGitCommand(dir, args...)
+}
+
+// ConfigGet exits with 1 when the key is not set.
+func (ExecGit) ConfigGet(file, key string) (string, error) {
+	out, err := gitConfig(file, "--get", key)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", nil
+	}
+	return strings.TrimSpace(out), err
+}
+
+func (ExecGit) ConfigSet(file, key, value string) error {
+	_, err := gitConfig(file, key, value)
+	return err
+}
+
+// ConfigUnset exits with 5 when the key is not set.
+func (ExecGit) ConfigUnset(file, key string) error {
+	_, err := gitConfig(file, "--unset-all", key)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 5 {
+		return nil
+	}
+	return err
+}
+
+func (ExecGit) ConfigSubsections(file, section string) ([]string, error) {
+	out, err := gitConfig(file, "--name-only", "--get-regexp", `^`+regexp.QuoteMeta(section)+`\.`)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, key := range strings.Fields(out) {
+		rest := strings.TrimPrefix(key, section+".")
+		if i := strings.LastIndex(rest, "."); i > 0 && !slices.Contains(names, rest[:i]) {
+			names = append(names, rest[:i])
+		}
+	}
+	return names, nil
+}
+
+func (ExecGit) ConfigRenameSection(file, section, newName string) error {
+	_, err := gitConfig(file, "--rename-section", section, newName)
+	return err
+}
+
+func (ExecGit) ConfigRemoveSection(file, section string) error {
+	_, err := gitConfig(file, "--remove-section", section)
+	return err
+}
+
+// gitConfig runs git config on file. It runs outside any repository: git refuses to start in one
+// whose core.worktree is stale, which is what some callers are repairing.
+func gitConfig(file string, args ...string) (string, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	return RunGitCommandOutput(os.TempDir(), append([]string{"config", "-f", file}, args...)...)
+}
@@ -0,0 +1,141 @@
+package gitutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Git is the set of repository operations the engine performs through a selectable backend.
+// ExecGit runs the git binary; GoGit works in process on top of go-git, so code built on it
+// can run without git installed.
+type Git interface {
+	// Init creates an empty repository in dir.
+	Init(dir string) error
+	// Add stages paths in the repository at dir; with no paths, every change is staged.
+	// Nested repositories are staged as submodule commits.
+	Add(dir string, paths ...string) error
+	// Commit commits the staged changes in dir.
+	Commit(dir, message string) error
+	// SubmoduleAdd registers the repository at url as a submodule at path, cloning it unless
+	// path already holds a repository, and stages .gitmodules and the submodule.
+	SubmoduleAdd(parentDir, url, path string) error
+	// SubmoduleDeinit unregisters the submodule at path from the local config and empties its directory.
+	SubmoduleDeinit(parentDir, path string) error
+	// Remove removes path from the index and, unless cached, from the working tree. Removing a
+	// submodule from the working tree also removes it from .gitmodules.
+	Remove(dir, path string, cached bool) error
+	// Status returns `git status --porcelain` lines, limited to paths if any are given.
+	Status(dir string, paths ...string) ([]string, error)
+	// Log returns up to n commits reachable from HEAD, newest first; n <= 0 returns all of them.
+	Log(dir string, n int) ([]LogEntry, error)
+	// Push pushes branch to remote, recording the upstream if setUpstream is true.
+	Push(dir, remote, branch string, setUpstream, force bool) error
+	// Fetch updates the remote-tracking branches of remote, pruning deleted ones. Submodules are not fetched.
+	Fetch(dir, remote string) error
+
+	// GitDir returns the absolute path of the git directory of the repository at dir.
+	GitDir(dir string) (string, error)
+	// HeadCommit returns the full SHA of HEAD.
+	HeadCommit(dir string) (string, error)
+	// HasStagedChanges reports whether the index differs from HEAD.
+	HasStagedChanges(dir string) (bool, error)
+	// Files returns the paths in the index, leaving out those the ignore rules match if excludeIgnored.
+	Files(dir string, excludeIgnored bool) ([]string, error)
+	// MoveGitlink moves the submodule entry at oldPath in the index to newPath, keeping the
+	// recorded commit. The working tree is not touched.
+	MoveGitlink(dir, oldPath, newPath string) error
+	// ResetSoft points the current branch at commit, keeping the index and the working tree.
+	ResetSoft(dir, commit string) error
+	// StagedFile returns the content of path in the index. A path that is not in the index is
+	// reported with an error matching os.ErrNotExist.
+	StagedFile(dir, path string) ([]byte, error)
+	// HookPath returns the absolute path of the named hook, e.g. pre-commit, honouring core.hooksPath.
+	HookPath(dir, name string) (string, error)
+
+	// CurrentBranch returns the checked-out branch, or an empty string when HEAD is detached.
+	CurrentBranch(dir string) (string, error)
+	// RefExists reports whether the fully qualified ref, e.g. refs/heads/main, exists.
+	RefExists(dir, ref string) (bool, error)
+	// IsAncestor reports whether the revision ancestor is reachable from the revision descendant.
+	IsAncestor(dir, ancestor, descendant string) (bool, error)
+	// RemoteURL returns the URL of remote, or an empty string if the remote is not configured.
+	RemoteURL(dir, remote string) (string, error)
+	// SetRemote points remote at url, adding the remote if it does not exist yet.
+	SetRemote(dir, remote, url string) error
+	// AheadBehind returns how many commits HEAD is ahead of and behind the upstream of the current
+	// branch. hasUpstream is false, and the counts zero, when the branch does not track one.
+	AheadBehind(dir string) (ahead, behind int, hasUpstream bool, err error)
+	// HasUnpushedCommits reports whether HEAD has commits the default branch of origin lacks.
+	// Without such a branch, or without commits, there is nothing unpushed.
+	HasUnpushedCommits(dir string) (bool, error)
+
+	// Submodules returns the submodules recorded in the index, as `git submodule status` lists them.
+	Submodules(dir string) ([]Submodule, error)
+	// SubmoduleName returns the name .gitmodules registers the submodule at path under.
+	SubmoduleName(dir, path string) (string, error)
+	// SubmoduleCommit returns the commit HEAD records for the submodule at path, or an empty
+	// string if HEAD records no submodule there.
+	SubmoduleCommit(dir, path string) (string, error)
+	// SubmoduleUpdate initializes the submodules at paths, or all of them, and checks out their
+	// recorded commits.
+	SubmoduleUpdate(dir string, paths ...string) error
+
+	// ConfigGet returns the value of key, e.g. submodule.canvas-guernica.url, in the git config
+	// file at file, or an empty string if it is not set.
+	ConfigGet(file, key string) (string, error)
+	// ConfigSet sets key in the git config file at file, creating the file if needed.
+	ConfigSet(file, key, value string) error
+	// ConfigUnset removes key from the git config file at file; an unset key is not an error.
+	ConfigUnset(file, key string) error
+	// ConfigSubsections returns the names of the subsections of section, e.g. the submodules.
+	ConfigSubsections(file, section string) ([]string, error)
+	// ConfigRenameSection renames a section such as submodule.canvas-guernica to newName.
+	ConfigRenameSection(file, section, newName string) error
+	// ConfigRemoveSection removes a section such as submodule.canvas-guernica.
+	ConfigRemoveSection(file, section string) error
+}
+
+// LogEntry is a commit as returned by Git.Log.
+type LogEntry struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string
+}
+
+// Submodule is a submodule as returned by Git.Submodules.
+type Submodule struct {
+	Path     string
+	Recorded string // Commit recorded in the index
+	Current  string // Commit checked out; empty when the submodule is not checked out
+}
+
+// Backends lists the names accepted by SetBackend.
+var Backends = []string{"exec", "go-git"}
+
+var backend Git = ExecGit{}
+
+// SetBackend selects the Git implementation by name. An empty name selects the default, exec.
+func SetBackend(name string) error {
+	switch name {
+	case "", "exec":
+		Use(ExecGit{})
+	case "go-git":
+		Use(GoGit{})
+	default:
+		return fmt.Errorf("unknown git backend %q (available: %s)", name, strings.Join(Backends, ", "))
+	}
+	return nil
+}
+
+// Use makes g the Git implementation behind the package functions, e.g. a fake in tests.
+func Use(g Git) {
+	backend = g
+}
+
+// Backend returns the Git implementation in use.
+func Backend() Git {
+	return backend
+}
@@ -0,0 +1,158 @@
+// Package gittest builds atelier workspaces backed by bare remotes for tests that run real git.
+package gittest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// Workspace is an atelier checked out in Root whose repositories each have a bare remote in Remotes.
+type Workspace struct {
+	Root    string
+	Remotes string
+}
+
+// Isolate points git and go-git at a configuration of their own for the rest of the test: a known
+// identity, main as the default branch and local submodule URLs allowed.
+func Isolate(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	config := filepath.Join(home, ".gitconfig")
+	content := "[user]\n\tname = Test\n\temail = test@example.com\n" +
+		"[init]\n\tdefaultBranch = main\n" +
+		"[protocol \"file\"]\n\tallow = always\n" +
+		"[commit]\n\tgpgsign = false\n"
+	if err := os.WriteFile(config, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+// New creates the atelier atelier-demo holding the canvases given as "artist-x/canvas-y", each
+// repository pushed to its bare remote and registered in its parent as a submodule of that remote.
+func New(t *testing.T, canvases ...string) *Workspace {
+	t.Helper()
+	return NewAtelier(t, "atelier-demo", canvases...)
+}
+
+// NewAtelier is like New for an atelier named name, e.g. the target of a move between ateliers.
+func NewAtelier(t *testing.T, name string, canvases ...string) *Workspace {
+	t.Helper()
+	Isolate(t)
+	base := t.TempDir()
+	w := &Workspace{Root: filepath.Join(base, name), Remotes: filepath.Join(base, "remotes")}
+	staging := filepath.Join(base, "staging")
+	if err := os.MkdirAll(w.Remotes, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	artists := map[string][]string{}
+	var order []string
+	for _, path := range canvases {
+		artist, canvas, _ := strings.Cut(path, "/")
+		if _, seen := artists[artist]; !seen {
+			order = append(order, artist)
+		}
+		artists[artist] = append(artists[artist], canvas)
+	}
+
+	atelier := w.create(t, filepath.Join(staging, name), marker.New(marker.KindAtelier, name, "", ""))
+	for _, artist := range order {
+		artistDir := w.create(t, filepath.Join(staging, artist), marker.New(marker.KindArtist, name, artist, ""))
+		for _, canvas := range artists[artist] {
+			w.create(t, filepath.Join(staging, canvas), marker.New(marker.KindCanvas, name, artist, canvas))
+			Run(t, artistDir, "submodule", "add", w.Remote(canvas), canvas)
+		}
+		Run(t, artistDir, "commit", "-m", "Add canvases")
+		Run(t, artistDir, "push", "origin", "main")
+		Run(t, atelier, "submodule", "add", w.Remote(artist), artist)
+	}
+	Run(t, atelier, "commit", "-m", "Add artists")
+	Run(t, atelier, "push", "origin", "main")
+
+	Run(t, base, "clone", "--recurse-submodules", w.Remote(name), w.Root)
+	w.CheckoutBranches(t, w.Root)
+	return w
+}
+
+// create makes dir a repository holding the marker m, pushed to a new bare remote named after it.
+func (w *Workspace) create(t *testing.T, dir string, m *marker.Marker) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	Run(t, dir, "init", "--quiet")
+	if err := marker.Write(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	Run(t, dir, "add", "-A")
+	Run(t, dir, "commit", "-m", "Initial commit")
+	remote := w.Remote(filepath.Base(dir))
+	Run(t, w.Remotes, "init", "--quiet", "--bare", remote)
+	Run(t, dir, "remote", "add", "origin", remote)
+	Run(t, dir, "push", "--set-upstream", "origin", "main")
+	return dir
+}
+
+// Remote returns the path of the bare remote of the repository named name, e.g. canvas-guernica.
+func (w *Workspace) Remote(name string) string {
+	return filepath.Join(w.Remotes, name+".git")
+}
+
+// Clone checks out the atelier a second time, e.g. as another user's workspace, and returns its path.
+func (w *Workspace) Clone(t *testing.T) string {
+	t.Helper()
+	name := filepath.Base(w.Root)
+	dir := filepath.Join(t.TempDir(), name)
+	Run(t, filepath.Dir(dir), "clone", "--recurse-submodules", w.Remote(name), dir)
+	w.CheckoutBranches(t, dir)
+	return dir
+}
+
+// CheckoutBranches checks out main in every submodule below dir, which git leaves on a detached HEAD.
+func (w *Workspace) CheckoutBranches(t *testing.T, dir string) {
+	t.Helper()
+	Run(t, dir, "submodule", "foreach", "--recursive", "git checkout --quiet main && git branch --quiet --set-upstream-to=origin/main")
+}
+
+// Run runs git in dir and fails the test if it fails.
+func Run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := gitutil.RunGitCommandOutput(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out)
+}
+
+// Head returns the commit HEAD of the repository at dir points at.
+func Head(t *testing.T, dir string) string {
+	t.Helper()
+	return Run(t, dir, "rev-parse", "HEAD")
+}
+
+// RemoteHead returns the commit the main branch of the bare remote of the repository named name
+// points at.
+func (w *Workspace) RemoteHead(t *testing.T, name string) string {
+	t.Helper()
+	return Run(t, w.Remote(name), "rev-parse", "main")
+}
+
+// WriteFile writes content to the file at path, creating its directory.
+func WriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,22 +3,27 @@ package gitutil
 import (
 	"bytes"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
+// Output receives the output of the commands run by RunGitCommand. It is discarded by default;
+// failures still report git's error output.
+var Output io.Writer = io.Discard
+
 // RunGitCommand executes a git command in a specified directory.
 func RunGitCommand(dir string, args ...string) error {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = dir
 
-	// Pass through stdout and stderr for visibility
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	var stderr bytes.Buffer
+	cmd.Stdout = Output
+	cmd.Stderr = io.MultiWriter(Output, &stderr)
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git command failed (%v) in %s: %w", args, dir, err)
+		return fmt.Errorf("git command failed (%v) in %s: %s: %w", args, dir, strings.TrimSpace(stderr.String()), err)
 	}
 	return nil
 }
@@ -38,12 +43,12 @@ func RunGitCommandOutput(dir string, args ...string) (string, error) {
 
 // Init initializes a new Git repository in the given directory.
 func Init(dir string) error {
-	return RunGitCommand(dir, "init")
+	return backend.Init(dir)
 }
 
 // Add stages all changes in the given directory.
 func Add(dir string) error {
-	return RunGitCommand(dir, "add", ".")
+	return backend.Add(dir)
 }
 
 // AddPaths stages specific paths in the given directory.
@@ -51,53 +56,247 @@ func AddPaths(dir string, paths ...string) error {
 	if len(paths) == 0 {
 		return nil
 	}
-	args := append([]string{"add", "--"}, paths...)
-	return RunGitCommand(dir, args...)
+	return backend.Add(dir, paths...)
 }
 
 // Commit creates a commit with the given message in the directory.
 func Commit(dir, message string) error {
-	return RunGitCommand(dir, "commit", "-m", message)
+	return backend.Commit(dir, message)
 }
 
 // AddSubmodule adds a submodule to the parent repository.
 func AddSubmodule(parentDir, submodulePath string) error {
 	// Submodule paths are relative to the parent directory
-	return RunGitCommand(parentDir, "submodule", "add", "./"+submodulePath, submodulePath)
+	return backend.SubmoduleAdd(parentDir, "./"+submodulePath, submodulePath)
 }
 
 // SubmoduleDeinit deinitializes a submodule.
 func SubmoduleDeinit(parentDir, submodulePath string) error {
-	return RunGitCommand(parentDir, "submodule", "deinit", submodulePath)
+	return backend.SubmoduleDeinit(parentDir, submodulePath)
 }
 
 // Remove removes a submodule from the Git index and .gitmodules.
 // It performs a `git rm` which removes from index, .gitmodules, and work tree.
 func Remove(parentDir, submodulePath string) error {
-	return RunGitCommand(parentDir, "rm", submodulePath)
+	return backend.Remove(parentDir, submodulePath, false)
+}
+
+// RemoveCached removes path from the Git index only, as `git rm --cached` does.
+func RemoveCached(dir, path string) error {
+	return backend.Remove(dir, path, true)
+}
+
+// Log returns up to n commits reachable from HEAD in the repository at dir, newest first.
+func Log(dir string, n int) ([]LogEntry, error) {
+	return backend.Log(dir, n)
 }
 
 // IsPathDirty reports whether the given path has local modifications in repo at dir.
 // It checks only the specified path (e.g., a submodule directory) via porcelain output.
 func IsPathDirty(dir, path string) (bool, error) {
-	out, err := RunGitCommandOutput(dir, "status", "--porcelain", "--", path)
+	lines, err := backend.Status(dir, path)
 	if err != nil {
 		return false, err
 	}
-	return strings.TrimSpace(out) != "", nil
+	return len(lines) > 0, nil
 }
 
 // HasUnpushedCommits checks if the repository has commits that haven't been pushed to the remote.
 func HasUnpushedCommits(dir string) (bool, error) {
-	out, err := RunGitCommandOutput(dir, "log", "--oneline", "origin..HEAD")
+	return backend.HasUnpushedCommits(dir)
+}
+
+// HasUncommittedChanges reports whether the repository at dir has any staged, unstaged or untracked changes.
+func HasUncommittedChanges(dir string) (bool, error) {
+	lines, err := backend.Status(dir)
 	if err != nil {
-		// If there's no remote or no origin branch, consider it as no unpushed commits
-		if strings.Contains(err.Error(), "does not have a commit") ||
-			strings.Contains(err.Error(), "unknown revision") ||
-			strings.Contains(err.Error(), "No remote configured") {
-			return false, nil
-		}
 		return false, err
 	}
-	return strings.TrimSpace(out) != "", nil
+	return len(lines) > 0, nil
+}
+
+// HasStagedChanges reports whether the index of the repository at dir differs from HEAD.
+func HasStagedChanges(dir string) (bool, error) {
+	return backend.HasStagedChanges(dir)
+}
+
+// CurrentBranch returns the checked-out branch name, or an empty string when HEAD is detached.
+func CurrentBranch(dir string) (string, error) {
+	return backend.CurrentBranch(dir)
+}
+
+// RemoteURL returns the URL of the named remote, or an empty string if the remote is not configured.
+func RemoteURL(dir, remote string) string {
+	url, _ := backend.RemoteURL(dir, remote)
+	return url
+}
+
+// AheadBehind returns how many commits HEAD is ahead of and behind its upstream branch.
+// hasUpstream is false (and the counts zero) when the current branch does not track a remote branch.
+func AheadBehind(dir string) (ahead, behind int, hasUpstream bool, err error) {
+	return backend.AheadBehind(dir)
+}
+
+// ModifiedSubmodules returns the paths of submodules whose checked-out commit differs from the one recorded in the index.
+func ModifiedSubmodules(dir string) ([]string, error) {
+	return submodulePaths(dir, func(s Submodule) bool { return s.Current != "" && s.Current != s.Recorded })
+}
+
+// UninitializedSubmodules returns the paths of submodules recorded in the index that are not checked out.
+func UninitializedSubmodules(dir string) ([]string, error) {
+	return submodulePaths(dir, func(s Submodule) bool { return s.Current == "" })
+}
+
+// submodulePaths returns the paths of the submodules in the repository at dir that match.
+func submodulePaths(dir string, match func(Submodule) bool) ([]string, error) {
+	submodules, err := backend.Submodules(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, s := range submodules {
+		if match(s) {
+			paths = append(paths, s.Path)
+		}
+	}
+	return paths, nil
+}
+
+// Push pushes branch to remote. When setUpstream is true the remote branch is recorded as upstream.
+func Push(dir, remote, branch string, setUpstream, force bool) error {
+	return backend.Push(dir, remote, branch, setUpstream, force)
+}
+
+// Fetch updates the remote-tracking branches of remote in the repository at dir.
+func Fetch(dir, remote string) error {
+	return backend.Fetch(dir, remote)
+}
+
+// HeadCommit returns the full SHA of HEAD in the repository at dir.
+func HeadCommit(dir string) (string, error) {
+	return backend.HeadCommit(dir)
+}
+
+// SubmoduleCommit returns the commit recorded for the submodule at path in the HEAD of the parent repository,
+// or an empty string if the parent does not record a submodule there.
+func SubmoduleCommit(parentDir, path string) (string, error) {
+	return backend.SubmoduleCommit(parentDir, path)
+}
+
+// StatusPorcelain returns the `git status --porcelain` lines for the repository at dir.
+func StatusPorcelain(dir string) ([]string, error) {
+	return backend.Status(dir)
+}
+
+// SetRemote points the named remote at url, adding the remote if it does not exist yet.
+func SetRemote(dir, remote, url string) error {
+	return backend.SetRemote(dir, remote, url)
+}
+
+// AddSubmoduleURL adds the repository at url to the parent repository as a submodule at submodulePath.
+func AddSubmoduleURL(parentDir, url, submodulePath string) error {
+	return backend.SubmoduleAdd(parentDir, url, submodulePath)
+}
+
+// UpdateSubmodules initializes and checks out the submodules recorded in the repository at dir.
+func UpdateSubmodules(dir string) error {
+	return backend.SubmoduleUpdate(dir)
+}
+
+// InitSubmodule initializes and checks out the single submodule at path in the repository at dir.
+func InitSubmodule(dir, path string) error {
+	return backend.SubmoduleUpdate(dir, path)
+}
+
+// IsAncestor reports whether commit ancestor is reachable from commit descendant in the repository at dir.
+func IsAncestor(dir, ancestor, descendant string) bool {
+	ok, err := backend.IsAncestor(dir, ancestor, descendant)
+	return err == nil && ok
+}
+
+// RefExists reports whether the fully qualified ref, e.g. refs/heads/main, exists in the repository at dir.
+func RefExists(dir, ref string) bool {
+	ok, err := backend.RefExists(dir, ref)
+	return err == nil && ok
+}
+
+// GitDir returns the absolute path of the git directory of the repository at dir.
+func GitDir(dir string) (string, error) {
+	return backend.GitDir(dir)
+}
+
+// LocalConfig returns the path of the config file of the repository at dir, e.g. .git/config.
+func LocalConfig(dir string) (string, error) {
+	gitDir, err := backend.GitDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "config"), nil
+}
+
+// HookPath returns the path of the named hook, e.g. pre-commit, of the repository at dir,
+// honouring core.hooksPath.
+func HookPath(dir, name string) (string, error) {
+	return backend.HookPath(dir, name)
+}
+
+// StagedFile returns the content of path in the index of the repository at dir. A path that is
+// not in the index is reported with an error matching os.ErrNotExist.
+func StagedFile(dir, path string) ([]byte, error) {
+	return backend.StagedFile(dir, path)
+}
+
+// SubmoduleName returns the name of the submodule registered at path in the .gitmodules file of the repository at dir.
+func SubmoduleName(dir, path string) (string, error) {
+	return backend.SubmoduleName(dir, path)
+}
+
+// MoveGitlink moves the submodule entry at oldPath in the index of the repository at dir to newPath,
+// keeping the recorded commit. The working tree is not touched.
+func MoveGitlink(dir, oldPath, newPath string) error {
+	return backend.MoveGitlink(dir, oldPath, newPath)
+}
+
+// Files returns the paths tracked in the index of the repository at dir. With excludeIgnored,
+// paths its ignore rules match, i.e. that were added with force, are left out.
+func Files(dir string, excludeIgnored bool) ([]string, error) {
+	return backend.Files(dir, excludeIgnored)
+}
+
+// ResetSoft points the current branch of the repository at dir at commit, keeping the index and
+// the working tree.
+func ResetSoft(dir, commit string) error {
+	return backend.ResetSoft(dir, commit)
+}
+
+// ConfigGet returns the value of key in the git config file at file, e.g. a .gitmodules file, or an
+// empty string if it is not set.
+func ConfigGet(file, key string) (string, error) {
+	return backend.ConfigGet(file, key)
+}
+
+// ConfigSet sets key in the git config file at file.
+func ConfigSet(file, key, value string) error {
+	return backend.ConfigSet(file, key, value)
+}
+
+// ConfigUnset removes key from the git config file at file.
+func ConfigUnset(file, key string) error {
+	return backend.ConfigUnset(file, key)
+}
+
+// ConfigSubsections returns the names of the subsections of section in the git config file at file,
+// e.g. the names of the submodules.
+func ConfigSubsections(file, section string) ([]string, error) {
+	return backend.ConfigSubsections(file, section)
+}
+
+// ConfigRenameSection renames section, e.g. submodule.canvas-guernica, in the git config file at file.
+func ConfigRenameSection(file, section, newName string) error {
+	return backend.ConfigRenameSection(file, section, newName)
+}
+
+// ConfigRemoveSection removes section from the git config file at file.
+func ConfigRemoveSection(file, section string) error {
+	return backend.ConfigRemoveSection(file, section)
 }
@@ -0,0 +1,1190 @@
+package gitutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	gitstorage "github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"github.com/go-git/go-git/v5/utils/merkletrie/filesystem"
+	mindex "github.com/go-git/go-git/v5/utils/merkletrie/index"
+	"github.com/go-git/go-git/v5/utils/merkletrie/noder"
+)
+
+// GoGit implements Git in process on top of go-git. Hooks are not run, and local repositories are
+// cloned and pushed to through go-git's own transport rather than git-upload-pack.
+type GoGit struct{}
+
+func init() {
+	client.InstallProtocol("file", localTransport{server.NewClient(localLoader{})})
+}
+
+// localTransport serves fetches through sessions that tolerate unknown haves; see knownHaves.
+type localTransport struct {
+	transport.Transport
+}
+
+func (t localTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	session, err := t.Transport.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := localLoader{}.Load(ep)
+	if err != nil {
+		return nil, err
+	}
+	return knownHaves{UploadPackSession: session, objects: objects}, nil
+}
+
+// knownHaves drops the commits the fetching repository has but the served one does not, such
+// as unpushed local commits. go-git's server fails on them where git-upload-pack ignores them.
+type knownHaves struct {
+	transport.UploadPackSession
+	objects storer.EncodedObjectStorer
+}
+
+func (s knownHaves) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	var haves []plumbing.Hash
+	for _, hash := range req.Haves {
+		if s.objects.HasEncodedObject(hash) == nil {
+			haves = append(haves, hash)
+		}
+	}
+	req.Haves = haves
+	return s.UploadPackSession.UploadPack(ctx, req)
+}
+
+// localLoader opens the repositories served by the in-process file transport. Unlike go-git's
+// default loader it accepts working trees as well as bare repositories.
+type localLoader struct{}
+
+func (localLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	repo, err := git.PlainOpen(ep.Path)
+	if err != nil {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return repo.Storer, nil
+}
+
+func (GoGit) Init(dir string) error {
+	branch := plumbing.Master
+	if cfg, err := config.LoadConfig(config.GlobalScope); err == nil && cfg.Init.DefaultBranch != "" {
+		branch = plumbing.NewBranchReferenceName(cfg.Init.DefaultBranch)
+	}
+	_, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{InitOptions: git.InitOptions{DefaultBranch: branch}})
+	return goGitError("init", dir, err)
+}
+
+func (g GoGit) Add(dir string, paths ...string) error {
+	repo, wt, err := openWorktree(dir)
+	if err != nil {
+		return err
+	}
+	status, nested, err := worktreeStatus(repo, wt, dir)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var files, removed, gitlinks []string
+	for name, s := range status {
+		switch {
+		case s.Worktree == git.Unmodified || !underAny(name, paths):
+		case s.Worktree == git.Deleted:
+			removed = append(removed, name)
+		case nested[name] != plumbing.ZeroHash:
+			gitlinks = append(gitlinks, name)
+		case isNested(nested, name):
+			return fmt.Errorf("git add failed in %s: %s does not have a commit checked out", dir, name)
+		default:
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		if err := wt.AddWithOptions(&git.AddOptions{Path: name, SkipStatus: true}); err != nil {
+			return goGitError("add "+name, dir, err)
+		}
+	}
+	if len(removed) == 0 && len(gitlinks) == 0 {
+		return nil
+	}
+
+	// Deletions and nested repositories are applied to the index directly: go-git would compute its
+	// own status for the former and read the latter as files
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	for _, name := range removed {
+		if _, err := idx.Remove(name); err != nil {
+			return goGitError("add "+name, dir, err)
+		}
+	}
+	for _, name := range gitlinks {
+		entry, err := idx.Entry(name)
+		if err != nil {
+			entry = idx.Add(name)
+		}
+		entry.Mode, entry.Hash = filemode.Submodule, nested[name]
+	}
+	return repo.Storer.SetIndex(idx)
+}
+
+func (GoGit) Commit(dir, message string) error {
+	_, wt, err := openWorktree(dir)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	_, err = wt.Commit(message, &git.CommitOptions{})
+	return goGitError("commit", dir, err)
+}
+
+func (g GoGit) SubmoduleAdd(parentDir, url, path string) error {
+	repo, err := openRepo(parentDir)
+	if err != nil {
+		return err
+	}
+	source := url
+	if strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../") {
+		source = filepath.Join(parentDir, url)
+	}
+	subDir := filepath.Join(parentDir, path)
+	if _, err := repoHead(subDir); err != nil {
+		if _, err := git.PlainClone(subDir, false, &git.CloneOptions{URL: source}); err != nil {
+			return goGitError("clone "+url, parentDir, err)
+		}
+	}
+
+	if err := updateGitmodules(parentDir, func(modules *format.Section) {
+		sub := modules.Subsection(path)
+		sub.SetOption("path", path)
+		sub.SetOption("url", url)
+	}); err != nil {
+		return err
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Submodules[path] = &config.Submodule{Name: path, URL: source}
+	if err := repo.SetConfig(cfg); err != nil {
+		return err
+	}
+	return g.Add(parentDir, ".gitmodules", path)
+}
+
+func (GoGit) SubmoduleDeinit(parentDir, path string) error {
+	repo, err := openRepo(parentDir)
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	modules, err := readGitmodules(parentDir)
+	if err != nil {
+		return err
+	}
+	for _, sub := range modules.Subsections {
+		if sub.Option("path") == path {
+			delete(cfg.Submodules, sub.Name)
+		}
+	}
+	if err := repo.SetConfig(cfg); err != nil {
+		return err
+	}
+
+	subDir := filepath.Join(parentDir, path)
+	entries, err := os.ReadDir(subDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(subDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g GoGit) Remove(dir, path string, cached bool) error {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	var names []string
+	gitlink := false
+	for _, entry := range idx.Entries {
+		if entry.Name == path || strings.HasPrefix(entry.Name, path+"/") {
+			names = append(names, entry.Name)
+			gitlink = gitlink || entry.Mode == filemode.Submodule
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("git rm failed in %s: pathspec '%s' did not match any files", dir, path)
+	}
+	for _, name := range names {
+		if _, err := idx.Remove(name); err != nil {
+			return err
+		}
+	}
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+	if cached {
+		return nil
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, path)); err != nil {
+		return err
+	}
+	if !gitlink {
+		return nil
+	}
+	if err := updateGitmodules(dir, func(modules *format.Section) {
+		for _, sub := range modules.Subsections {
+			if sub.Option("path") == path {
+				modules.RemoveSubsection(sub.Name)
+			}
+		}
+	}); err != nil {
+		return err
+	}
+	return g.Add(dir, ".gitmodules")
+}
+
+func (GoGit) Status(dir string, paths ...string) ([]string, error) {
+	repo, wt, err := openWorktree(dir)
+	if err != nil {
+		return nil, err
+	}
+	status, _, err := worktreeStatus(repo, wt, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var lines []string
+	for name, s := range status {
+		if s.Staging == git.Unmodified && s.Worktree == git.Unmodified || !underAny(name, paths) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%c%c %s", s.Staging, s.Worktree, name))
+	}
+	sort.Slice(lines, func(a, b int) bool { return lines[a][3:] < lines[b][3:] })
+	return lines, nil
+}
+
+func (GoGit) Log(dir string, n int) ([]LogEntry, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := repo.Log(&git.LogOptions{})
+	if err != nil {
+		return nil, goGitError("log", dir, err)
+	}
+	defer iter.Close()
+
+	var commits []LogEntry
+	for n <= 0 || len(commits) < n {
+		c, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, goGitError("log", dir, err)
+		}
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		commits = append(commits, LogEntry{Hash: c.Hash.String(), Author: c.Author.Name, Email: c.Author.Email, Date: c.Author.When, Subject: subject})
+	}
+	return commits, nil
+}
+
+func (GoGit) Push(dir, remote, branch string, setUpstream, force bool) error {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return err
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	opts := &git.PushOptions{RemoteName: remote, RefSpecs: []config.RefSpec{config.RefSpec(ref + ":" + ref)}}
+	if force {
+		opts.ForceWithLease = &git.ForceWithLease{}
+	}
+	if err := repo.Push(opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return goGitError("push "+remote+" "+branch, dir, err)
+	}
+	if !setUpstream {
+		return nil
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Branches[branch] = &config.Branch{Name: branch, Remote: remote, Merge: ref}
+	return repo.SetConfig(cfg)
+}
+
+func (GoGit) Fetch(dir, remote string) error {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return err
+	}
+	if err := repo.Fetch(&git.FetchOptions{RemoteName: remote, Prune: true}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return goGitError("fetch "+remote, dir, err)
+	}
+	return nil
+}
+
+func (GoGit) GitDir(dir string) (string, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return "", err
+	}
+	storage, ok := repo.Storer.(*gitstorage.Storage)
+	if !ok {
+		return "", fmt.Errorf("git rev-parse --git-dir failed in %s: not stored on disk", dir)
+	}
+	return filepath.Abs(storage.Filesystem().Root())
+}
+
+func (GoGit) HeadCommit(dir string) (string, error) {
+	head, err := repoHead(dir)
+	if err != nil {
+		return "", err
+	}
+	return head.String(), nil
+}
+
+func (GoGit) HasStagedChanges(dir string) (bool, error) {
+	repo, wt, err := openWorktree(dir)
+	if err != nil {
+		return false, err
+	}
+	status, _, err := worktreeStatus(repo, wt, dir)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range status {
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (GoGit) Files(dir string, excludeIgnored bool) ([]string, error) {
+	repo, wt, err := openWorktree(dir)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	patterns, _ := gitignore.ReadPatterns(wt.Filesystem, nil)
+	ignored := gitignore.NewMatcher(append(patterns, wt.Excludes...))
+	var files []string
+	for _, entry := range idx.Entries {
+		if !excludeIgnored || !ignored.Match(strings.Split(entry.Name, "/"), false) {
+			files = append(files, entry.Name)
+		}
+	}
+	return files, nil
+}
+
+func (GoGit) MoveGitlink(dir, oldPath, newPath string) error {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	entry, err := idx.Entry(oldPath)
+	if err != nil || entry.Mode != filemode.Submodule {
+		return fmt.Errorf("%s is not a submodule in the index of %s", oldPath, dir)
+	}
+	if _, err := idx.Remove(newPath); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+		return err
+	}
+	entry.Name = newPath
+	return repo.Storer.SetIndex(idx)
+}
+
+func (GoGit) ResetSoft(dir, commit string) error {
+	_, wt, err := openWorktree(dir)
+	if err != nil {
+		return err
+	}
+	err = wt.Reset(&git.ResetOptions{Commit: plumbing.NewHash(commit), Mode: git.SoftReset})
+	return goGitError("reset --soft "+commit, dir, err)
+}
+
+func (GoGit) StagedFile(dir, path string) ([]byte, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := idx.Entry(filepath.ToSlash(path))
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return nil, fmt.Errorf("%s is not staged in %s: %w", path, dir, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	blob, err := repo.BlobObject(entry.Hash)
+	if err != nil {
+		return nil, goGitError("show :"+path, dir, err)
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// HookPath resolves core.hooksPath like git: a leading ~/ is the home directory and a relative
+// path is relative to the top of the working tree.
+func (g GoGit) HookPath(dir, name string) (string, error) {
+	repo, wt, err := openWorktree(dir)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := repo.ConfigScoped(config.GlobalScope)
+	if err != nil {
+		return "", err
+	}
+	hooks := cfg.Raw.Section("core").Option("hooksPath")
+	switch {
+	case hooks == "":
+		gitDir, err := g.GitDir(dir)
+		if err != nil {
+			return "", err
+		}
+		hooks = filepath.Join(gitDir, "hooks")
+	case strings.HasPrefix(hooks, "~/"):
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		hooks = filepath.Join(home, hooks[2:])
+	case !filepath.IsAbs(hooks):
+		hooks = filepath.Join(wt.Filesystem.Root(), hooks)
+	}
+	return filepath.Abs(filepath.Join(hooks, name))
+}
+
+func (GoGit) CurrentBranch(dir string) (string, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", goGitError("branch --show-current", dir, err)
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return "", nil
+	}
+	return head.Target().Short(), nil
+}
+
+func (GoGit) RefExists(dir, ref string) (bool, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return false, err
+	}
+	_, err = repo.Reference(plumbing.ReferenceName(ref), false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, nil
+	}
+	return err == nil, goGitError("show-ref "+ref, dir, err)
+}
+
+func (GoGit) IsAncestor(dir, ancestor, descendant string) (bool, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return false, err
+	}
+	var commits [2]*object.Commit
+	for i, rev := range []string{ancestor, descendant} {
+		hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return false, goGitError("rev-parse "+rev, dir, err)
+		}
+		if commits[i], err = repo.CommitObject(*hash); err != nil {
+			return false, goGitError("rev-parse "+rev, dir, err)
+		}
+	}
+	ok, err := commits[0].IsAncestor(commits[1])
+	return ok, goGitError("merge-base --is-ancestor", dir, err)
+}
+
+func (GoGit) RemoteURL(dir, remote string) (string, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return "", err
+	}
+	r, err := repo.Remote(remote)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", goGitError("remote get-url "+remote, dir, err)
+	}
+	return r.Config().URLs[0], nil
+}
+
+func (GoGit) SetRemote(dir, remote, url string) error {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if r, ok := cfg.Remotes[remote]; ok {
+		r.URLs = []string{url}
+		return repo.SetConfig(cfg)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: remote, URLs: []string{url}})
+	return goGitError("remote add "+remote, dir, err)
+}
+
+// AheadBehind maps the branch's merge ref to a remote-tracking branch through the remote's fetch
+// refspecs, as git does to resolve @{upstream}.
+func (g GoGit) AheadBehind(dir string) (ahead, behind int, hasUpstream bool, err error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	branch, err := g.CurrentBranch(dir)
+	if err != nil || branch == "" {
+		return 0, 0, false, nil
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	b, ok := cfg.Branches[branch]
+	if !ok || b.Remote == "" || b.Merge == "" {
+		return 0, 0, false, nil
+	}
+	upstream := b.Merge
+	if b.Remote != "." {
+		r, ok := cfg.Remotes[b.Remote]
+		if !ok {
+			return 0, 0, false, nil
+		}
+		upstream = ""
+		for _, spec := range r.Fetch {
+			if spec.Match(b.Merge) {
+				upstream = spec.Dst(b.Merge)
+			}
+		}
+	}
+	upstreamRef, err := repo.Reference(upstream, true)
+	if err != nil {
+		return 0, 0, false, nil
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return 0, 0, true, goGitError("rev-parse HEAD", dir, err)
+	}
+
+	local, err := reachable(repo, head.Hash())
+	if err != nil {
+		return 0, 0, true, err
+	}
+	remote, err := reachable(repo, upstreamRef.Hash())
+	if err != nil {
+		return 0, 0, true, err
+	}
+	for hash := range local {
+		if !remote[hash] {
+			ahead++
+		}
+	}
+	for hash := range remote {
+		if !local[hash] {
+			behind++
+		}
+	}
+	return ahead, behind, true, nil
+}
+
+func (GoGit) HasUnpushedCommits(dir string) (bool, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return false, err
+	}
+	origin, err := repo.Reference("refs/remotes/origin/HEAD", true)
+	if err != nil {
+		return false, nil
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return false, nil
+	}
+	pushed, err := reachable(repo, origin.Hash())
+	if err != nil {
+		return false, err
+	}
+	return !pushed[head.Hash()], nil
+}
+
+// reachable returns the commits reachable from the commit from, including from.
+func reachable(repo *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	iter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return nil, err
+	}
+	commits := map[plumbing.Hash]bool{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits[c.Hash] = true
+		return nil
+	})
+	return commits, err
+}
+
+// Submodules treats a submodule as checked out when its directory holds a repository.
+func (GoGit) Submodules(dir string) ([]Submodule, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	var submodules []Submodule
+	for _, entry := range idx.Entries {
+		if entry.Mode != filemode.Submodule {
+			continue
+		}
+		s := Submodule{Path: entry.Name, Recorded: entry.Hash.String()}
+		if head, err := repoHead(filepath.Join(dir, entry.Name)); err == nil {
+			s.Current = head.String()
+		}
+		submodules = append(submodules, s)
+	}
+	return submodules, nil
+}
+
+func (GoGit) SubmoduleName(dir, path string) (string, error) {
+	modules, err := readGitmodules(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(modules.Subsections) == 0 {
+		return "", fmt.Errorf("no submodules registered in %s", dir)
+	}
+	for _, sub := range modules.Subsections {
+		if sub.Option("path") == path {
+			return sub.Name, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a submodule of %s", path, dir)
+}
+
+func (GoGit) SubmoduleCommit(dir, path string) (string, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", goGitError("ls-tree HEAD", dir, err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	entry, err := tree.FindEntry(filepath.ToSlash(path))
+	if err != nil || entry.Mode != filemode.Submodule {
+		return "", nil
+	}
+	return entry.Hash.String(), nil
+}
+
+// SubmoduleUpdate leaves submodules checked out at their recorded commit alone. The others are
+// fetched only if the commit is missing, and checked out by force, as a deinitialized submodule
+// keeps its git directory under .git/modules but not its files.
+func (g GoGit) SubmoduleUpdate(dir string, paths ...string) error {
+	_, wt, err := openWorktree(dir)
+	if err != nil {
+		return err
+	}
+	submodules, err := wt.Submodules()
+	if err != nil {
+		return goGitError("submodule update", dir, err)
+	}
+	states, err := g.Submodules(dir)
+	if err != nil {
+		return err
+	}
+	gitDir, err := g.GitDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, sub := range submodules {
+		path := sub.Config().Path
+		if len(paths) > 0 && !slices.Contains(paths, path) {
+			continue
+		}
+		recorded, current := submoduleCommits(states, path)
+		if recorded == "" || current == recorded {
+			continue
+		}
+		if err := updateSubmodule(sub, filepath.Join(dir, path), filepath.Join(gitDir, "modules", sub.Config().Name), plumbing.NewHash(recorded)); err != nil {
+			return goGitError("submodule update --init "+path, dir, err)
+		}
+	}
+	return nil
+}
+
+// submoduleCommits returns the recorded and checked-out commits of the submodule at path.
+func submoduleCommits(submodules []Submodule, path string) (recorded, current string) {
+	for _, s := range submodules {
+		if s.Path == path {
+			return s.Recorded, s.Current
+		}
+	}
+	return "", ""
+}
+
+// updateSubmodule checks out commit in the submodule sub at worktree, whose git directory is gitDir.
+func updateSubmodule(sub *git.Submodule, worktree, gitDir string, commit plumbing.Hash) error {
+	if err := sub.Init(); err != nil && !errors.Is(err, git.ErrSubmoduleAlreadyInitialized) {
+		return err
+	}
+	repo, err := sub.Repository()
+	if err != nil {
+		return err
+	}
+	if _, err := repo.CommitObject(commit); err != nil {
+		if err := repo.Fetch(&git.FetchOptions{}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return err
+		}
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Hash: commit, Force: true}); err != nil {
+		return err
+	}
+	// Deinitializing removed the .git file that links the working tree to its git directory
+	gitFile := filepath.Join(worktree, ".git")
+	if _, err := os.Stat(gitFile); err == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(worktree, gitDir)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(gitFile, []byte("gitdir: "+filepath.ToSlash(rel)+"\n"), 0644)
+}
+
+func (GoGit) ConfigGet(file, key string) (string, error) {
+	cfg, err := readConfigFile(file)
+	if err != nil {
+		return "", err
+	}
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil || !cfg.HasSection(section) {
+		return "", err
+	}
+	options := cfg.Section(section).Options
+	if subsection != "" {
+		if !cfg.Section(section).HasSubsection(subsection) {
+			return "", nil
+		}
+		options = cfg.Section(section).Subsection(subsection).Options
+	}
+	return options.Get(name), nil
+}
+
+func (GoGit) ConfigSet(file, key, value string) error {
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+	return updateConfigFile(file, func(cfg *format.Config) error {
+		if subsection == "" {
+			cfg.Section(section).SetOption(name, value)
+		} else {
+			cfg.Section(section).Subsection(subsection).SetOption(name, value)
+		}
+		return nil
+	})
+}
+
+func (GoGit) ConfigUnset(file, key string) error {
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+	return updateConfigFile(file, func(cfg *format.Config) error {
+		switch {
+		case !cfg.HasSection(section):
+		case subsection == "":
+			cfg.Section(section).RemoveOption(name)
+		case cfg.Section(section).HasSubsection(subsection):
+			cfg.Section(section).Subsection(subsection).RemoveOption(name)
+		}
+		return nil
+	})
+}
+
+func (GoGit) ConfigSubsections(file, section string) ([]string, error) {
+	cfg, err := readConfigFile(file)
+	if err != nil || !cfg.HasSection(section) {
+		return nil, err
+	}
+	var names []string
+	for _, sub := range cfg.Section(section).Subsections {
+		names = append(names, sub.Name)
+	}
+	return names, nil
+}
+
+func (GoGit) ConfigRenameSection(file, section, newName string) error {
+	name, subsection, _ := strings.Cut(section, ".")
+	newSection, newSubsection, _ := strings.Cut(newName, ".")
+	return updateConfigFile(file, func(cfg *format.Config) error {
+		if !cfg.HasSection(name) || subsection != "" && !cfg.Section(name).HasSubsection(subsection) {
+			return fmt.Errorf("no such section: %s", section)
+		}
+		switch {
+		case subsection == "" && newSubsection == "":
+			cfg.Section(name).Name = newSection
+		case subsection != "" && newSection == name:
+			cfg.Section(name).Subsection(subsection).Name = newSubsection
+		default:
+			return fmt.Errorf("cannot rename section %s to %s", section, newName)
+		}
+		return nil
+	})
+}
+
+func (GoGit) ConfigRemoveSection(file, section string) error {
+	name, subsection, _ := strings.Cut(section, ".")
+	return updateConfigFile(file, func(cfg *format.Config) error {
+		if !cfg.HasSection(name) || subsection != "" && !cfg.Section(name).HasSubsection(subsection) {
+			return fmt.Errorf("no such section: %s", section)
+		}
+		if subsection == "" {
+			cfg.RemoveSection(name)
+		} else {
+			cfg.Section(name).RemoveSubsection(subsection)
+		}
+		return nil
+	})
+}
+
+// splitConfigKey splits a key such as submodule.canvas-guernica.url into its section, subsection
+// and name. The subsection is empty for keys such as core.worktree.
+func splitConfigKey(key string) (section, subsection, name string, err error) {
+	first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", fmt.Errorf("invalid config key %q", key)
+	}
+	if first < last {
+		subsection = key[first+1 : last]
+	}
+	return key[:first], subsection, key[last+1:], nil
+}
+
+func openRepo(dir string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(dir)
+	return repo, goGitError("open", dir, err)
+}
+
+func openWorktree(dir string) (*git.Repository, *git.Worktree, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	wt, err := repo.Worktree()
+	return repo, wt, goGitError("open", dir, err)
+}
+
+// repoHead returns the HEAD commit of the repository at dir.
+func repoHead(dir string) (plumbing.Hash, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, goGitError("rev-parse HEAD", dir, err)
+	}
+	return head.Hash(), nil
+}
+
+// worktreeStatus computes the status of the repository at dir the way Worktree.Status does, but resolves
+// nested repositories itself: go-git's submodule support expects git directories under .git/modules and
+// creates one when it finds an embedded .git directory. It also returns the nested repositories, mapped
+// to their HEAD commits.
+func worktreeStatus(repo *git.Repository, wt *git.Worktree, dir string) (git.Status, map[string]plumbing.Hash, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, nil, err
+	}
+	patterns, _ := gitignore.ReadPatterns(wt.Filesystem, nil)
+	ignored := gitignore.NewMatcher(append(patterns, wt.Excludes...))
+	nested, err := nestedRepos(dir, idx, ignored)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var head noder.Noder
+	if ref, err := repo.Head(); err == nil {
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil, nil, err
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, nil, err
+		}
+		head = object.NewTreeRootNode(tree)
+	} else if err != plumbing.ErrReferenceNotFound {
+		return nil, nil, err
+	}
+
+	status := git.Status{}
+	staged, err := merkletrie.DiffTree(head, mindex.NewRootNode(idx), sameHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ch := range staged {
+		action, err := ch.Action()
+		if err != nil {
+			return nil, nil, err
+		}
+		file := status.File(changedPath(ch))
+		file.Worktree = git.Unmodified
+		switch action {
+		case merkletrie.Delete:
+			file.Staging = git.Deleted
+		case merkletrie.Insert:
+			file.Staging = git.Added
+		case merkletrie.Modify:
+			file.Staging = git.Modified
+		}
+	}
+
+	worktree := filesystem.NewRootNodeWithOptions(wt.Filesystem, nested, filesystem.Options{Index: idx})
+	unstaged, err := merkletrie.DiffTree(mindex.NewRootNode(idx), worktree, sameHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ch := range unstaged {
+		action, err := ch.Action()
+		if err != nil {
+			return nil, nil, err
+		}
+		name := changedPath(ch)
+		if action == merkletrie.Insert && ignored.Match(strings.Split(name, "/"), ch.To.IsDir()) {
+			continue
+		}
+		file := status.File(name)
+		if file.Staging == git.Untracked {
+			file.Staging = git.Unmodified
+		}
+		switch action {
+		case merkletrie.Delete:
+			file.Worktree = git.Deleted
+		case merkletrie.Insert:
+			file.Worktree, file.Staging = git.Untracked, git.Untracked
+		case merkletrie.Modify:
+			file.Worktree = git.Modified
+		}
+	}
+	return status, nested, nil
+}
+
+// nestedRepos maps the repositories nested in the working tree at dir, and the submodules recorded in
+// idx, to their checked-out commits. Submodules that are not checked out map to their recorded commit;
+// nested repositories without commits map to the zero hash.
+func nestedRepos(dir string, idx *index.Index, ignored gitignore.Matcher) (map[string]plumbing.Hash, error) {
+	repos := map[string]plumbing.Hash{}
+	err := filepath.WalkDir(dir, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return err
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignored.Match(strings.Split(rel, "/"), true) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+			return nil
+		}
+		repos[rel], _ = repoHead(path)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range idx.Entries {
+		if entry.Mode == filemode.Submodule && repos[entry.Name] == plumbing.ZeroHash {
+			repos[entry.Name] = entry.Hash
+		}
+	}
+	return repos, nil
+}
+
+// isNested reports whether name is a repository nested in the working tree.
+func isNested(nested map[string]plumbing.Hash, name string) bool {
+	_, ok := nested[name]
+	return ok
+}
+
+func changedPath(ch merkletrie.Change) string {
+	if name := ch.To.String(); name != "" {
+		return name
+	}
+	return ch.From.String()
+}
+
+// sameHash compares merkletrie nodes by hash. go-git leaves some directory hashes zeroed; those never match.
+func sameHash(a, b noder.Hasher) bool {
+	hashA, hashB := a.Hash(), b.Hash()
+	if bytes.Equal(hashA, emptyNodeHash) || bytes.Equal(hashB, emptyNodeHash) {
+		return false
+	}
+	return bytes.Equal(hashA, hashB)
+}
+
+var emptyNodeHash = make([]byte, 24)
+
+// underAny reports whether name is one of paths or lies below one of them.
+func underAny(name string, paths []string) bool {
+	for _, path := range paths {
+		path = filepath.ToSlash(filepath.Clean(path))
+		if path == "." || name == path || strings.HasPrefix(name, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readGitmodules returns the submodule section of the .gitmodules file of the repository at dir.
+func readGitmodules(dir string) (*format.Section, error) {
+	cfg, err := readGitmodulesConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Section("submodule"), nil
+}
+
+// updateGitmodules applies update to the submodule section of the .gitmodules file of the repository
+// at dir. Sections keep their order, so the file only changes where update changes it.
+func updateGitmodules(dir string, update func(modules *format.Section)) error {
+	return updateConfigFile(filepath.Join(dir, ".gitmodules"), func(cfg *format.Config) error {
+		update(cfg.Section("submodule"))
+		if len(cfg.Section("submodule").Subsections) == 0 {
+			cfg.RemoveSection("submodule")
+		}
+		return nil
+	})
+}
+
+func readGitmodulesConfig(dir string) (*format.Config, error) {
+	return readConfigFile(filepath.Join(dir, ".gitmodules"))
+}
+
+// readConfigFile reads the git config file at file; a missing file is empty.
+func readConfigFile(file string) (*format.Config, error) {
+	cfg := format.New()
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := format.NewDecoder(bytes.NewReader(content)).Decode(cfg); err != nil {
+		return nil, fmt.Errorf("invalid git config file %s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+// updateConfigFile applies update to the git config file at file and writes it back.
+func updateConfigFile(file string, update func(cfg *format.Config) error) error {
+	cfg, err := readConfigFile(file)
+	if err != nil {
+		return err
+	}
+	if err := update(cfg); err != nil {
+		return fmt.Errorf("git config failed for %s: %w", file, err)
+	}
+	var buf bytes.Buffer
+	if err := format.NewEncoder(&buf).Encode(cfg); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+func goGitError(op, dir string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("git %s failed in %s: %w", op, dir, err)
+}
@@ -0,0 +1,233 @@
+package gitutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// hermetic runs the rest of the test without a git binary and with a git configuration of its own,
+// so only the go-git backend can work.
+func hermetic(t *testing.T) GoGit {
+	t.Helper()
+	home := t.TempDir()
+	config := "[user]\n\tname = Test\n\temail = test@example.com\n[init]\n\tdefaultBranch = main\n"
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("PATH", t.TempDir())
+	return GoGit{}
+}
+
+// commitFiles writes the files, by path relative to dir, and commits them with g.
+func commitFiles(t *testing.T, g Git, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Add(dir); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := g.Commit(dir, "Commit"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+}
+
+func newRepo(t *testing.T, g Git, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := g.Init(dir); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	commitFiles(t, g, dir, files)
+	return dir
+}
+
+func TestGoGitHeadAndReset(t *testing.T) {
+	g := hermetic(t)
+	dir := newRepo(t, g, map[string]string{"README.md": "one\n"})
+
+	gitDir, err := g.GitDir(dir)
+	if err != nil || gitDir != filepath.Join(dir, ".git") {
+		t.Errorf("GitDir = %q, %v; want %s", gitDir, err, filepath.Join(dir, ".git"))
+	}
+	first, err := g.HeadCommit(dir)
+	if err != nil || len(first) != 40 {
+		t.Fatalf("HeadCommit = %q, %v", first, err)
+	}
+	if staged, err := g.HasStagedChanges(dir); err != nil || staged {
+		t.Errorf("HasStagedChanges after a commit = %v, %v", staged, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if staged, err := g.HasStagedChanges(dir); err != nil || staged {
+		t.Errorf("HasStagedChanges with an unstaged change = %v, %v", staged, err)
+	}
+	if err := g.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	if staged, err := g.HasStagedChanges(dir); err != nil || !staged {
+		t.Errorf("HasStagedChanges with a staged change = %v, %v", staged, err)
+	}
+	if err := g.Commit(dir, "Second"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.ResetSoft(dir, first); err != nil {
+		t.Fatalf("ResetSoft: %v", err)
+	}
+	if head, _ := g.HeadCommit(dir); head != first {
+		t.Errorf("HEAD = %s after ResetSoft, want %s", head, first)
+	}
+	if staged, err := g.HasStagedChanges(dir); err != nil || !staged {
+		t.Errorf("ResetSoft did not keep the index: HasStagedChanges = %v, %v", staged, err)
+	}
+}
+
+func TestGoGitFiles(t *testing.T) {
+	g := hermetic(t)
+	dir := newRepo(t, g, map[string]string{"README.md": "# readme\n", "debug.log": "tracked before it was ignored\n"})
+	commitFiles(t, g, dir, map[string]string{".gitignore": "*.log\n", "src/main.go": "package main\n"})
+
+	tests := []struct {
+		excludeIgnored bool
+		want           []string
+	}{
+		{false, []string{".gitignore", "README.md", "debug.log", "src/main.go"}},
+		{true, []string{".gitignore", "README.md", "src/main.go"}},
+	}
+	for _, tt := range tests {
+		files, err := g.Files(dir, tt.excludeIgnored)
+		if err != nil {
+			t.Fatalf("Files: %v", err)
+		}
+		if !slices.Equal(files, tt.want) {
+			t.Errorf("Files(%v) = %v, want %v", tt.excludeIgnored, files, tt.want)
+		}
+	}
+}
+
+func TestGoGitMoveGitlink(t *testing.T) {
+	g := hermetic(t)
+	child := newRepo(t, g, map[string]string{"README.md": "child\n"})
+	parent := newRepo(t, g, map[string]string{"README.md": "parent\n"})
+	if err := os.Rename(child, filepath.Join(parent, "canvas-a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Add(parent, "canvas-a"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if err := g.MoveGitlink(parent, "canvas-a", "canvas-b"); err != nil {
+		t.Fatalf("MoveGitlink: %v", err)
+	}
+	files, err := g.Files(parent, false)
+	if err != nil || !slices.Equal(files, []string{"README.md", "canvas-b"}) {
+		t.Errorf("Files after MoveGitlink = %v, %v", files, err)
+	}
+	if err := g.MoveGitlink(parent, "README.md", "README.txt"); err == nil {
+		t.Error("MoveGitlink moved a file that is not a submodule")
+	}
+}
+
+// TestConfig runs the same config edits on both backends; the exec backend only if git is installed.
+func TestConfig(t *testing.T) {
+	const initial = `[core]
+	bare = false
+	worktree = ../../canvas-a
+[submodule "canvas-a"]
+	path = canvas-a
+	url = ./canvas-a
+[submodule "canvas-b"]
+	path = canvas-b
+	url = /ateliers/demo/canvas-b
+`
+	backends := map[string]func(t *testing.T) Git{
+		"go-git": func(t *testing.T) Git { return hermetic(t) },
+		"exec": func(t *testing.T) Git {
+			if _, err := exec.LookPath("git"); err != nil {
+				t.Skip("git is not installed")
+			}
+			return ExecGit{}
+		},
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			g := backend(t)
+			file := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(file, []byte(initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+			get := func(key string) string {
+				t.Helper()
+				value, err := g.ConfigGet(file, key)
+				if err != nil {
+					t.Fatalf("ConfigGet(%s): %v", key, err)
+				}
+				return value
+			}
+
+			if got := get("submodule.canvas-a.url"); got != "./canvas-a" {
+				t.Errorf("submodule.canvas-a.url = %q", got)
+			}
+			if got := get("core.worktree"); got != "../../canvas-a" {
+				t.Errorf("core.worktree = %q", got)
+			}
+			if got := get("submodule.canvas-z.url"); got != "" {
+				t.Errorf("unset key = %q", got)
+			}
+			names, err := g.ConfigSubsections(file, "submodule")
+			if err != nil || !slices.Equal(names, []string{"canvas-a", "canvas-b"}) {
+				t.Errorf("ConfigSubsections = %v, %v", names, err)
+			}
+
+			if err := g.ConfigRenameSection(file, "submodule.canvas-a", "submodule.canvas-c"); err != nil {
+				t.Fatalf("ConfigRenameSection: %v", err)
+			}
+			if err := g.ConfigSet(file, "submodule.canvas-c.path", "canvas-c"); err != nil {
+				t.Fatalf("ConfigSet: %v", err)
+			}
+			if got := get("submodule.canvas-c.path"); got != "canvas-c" || get("submodule.canvas-a.path") != "" {
+				t.Errorf("renamed section: path = %q", got)
+			}
+			if err := g.ConfigRenameSection(file, "submodule.missing", "submodule.other"); err == nil {
+				t.Error("ConfigRenameSection of a missing section succeeded")
+			}
+
+			if err := g.ConfigUnset(file, "core.worktree"); err != nil {
+				t.Fatalf("ConfigUnset: %v", err)
+			}
+			if err := g.ConfigUnset(file, "core.worktree"); err != nil {
+				t.Errorf("ConfigUnset of an unset key: %v", err)
+			}
+			if get("core.worktree") != "" || get("core.bare") != "false" {
+				t.Error("ConfigUnset removed the wrong keys")
+			}
+
+			if err := g.ConfigRemoveSection(file, "submodule.canvas-b"); err != nil {
+				t.Fatalf("ConfigRemoveSection: %v", err)
+			}
+			names, err = g.ConfigSubsections(file, "submodule")
+			if err != nil || !slices.Equal(names, []string{"canvas-c"}) {
+				t.Errorf("ConfigSubsections after removal = %v, %v", names, err)
+			}
+
+			missing := filepath.Join(t.TempDir(), "missing")
+			if value, err := g.ConfigGet(missing, "core.worktree"); err != nil || value != "" {
+				t.Errorf("ConfigGet on a missing file = %q, %v", value, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package guard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/contextmerge"
+	"github.com/frquxl/go-atelier/pkg/fs"
+)
+
+// IgnoreFiles are the ignore files of AI agents that WriteAgentFiles maintains.
+var IgnoreFiles = []string{".geminiignore", ".cursorignore", ".aiignore"}
+
+// SettingsFile is the agent settings file whose deny rules WriteAgentFiles maintains.
+const SettingsFile = ".claude/settings.json"
+
+// AgentFiles lists every file WriteAgentFiles may write, relative to the repository.
+var AgentFiles = append(slices.Clone(IgnoreFiles), SettingsFile)
+
+// sectionID identifies the guard's section in the ignore files.
+const sectionID = "guard"
+
+// deniedCommands are shell commands that print the environment, and with it any secret in it.
+var deniedCommands = []string{"Bash(env)", "Bash(env:*)", "Bash(printenv)", "Bash(printenv:*)", "Bash(set)", "Bash(export -p)"}
+
+// ignoreSection returns the guard's section of an ignore file.
+func ignoreSection() string {
+	var b strings.Builder
+	b.WriteString("# Credentials: agents must never read these files (maintained by 'atelier-cli guard install')\n")
+	for _, pattern := range SensitiveFiles {
+		b.WriteString(pattern + "\n")
+	}
+	for _, name := range templateFiles {
+		b.WriteString("!" + name + "\n")
+	}
+	return b.String()
+}
+
+// denyRules returns the deny rules the settings file must contain.
+func denyRules() []string {
+	var rules []string
+	for _, pattern := range SensitiveFiles {
+		rules = append(rules, "Read(**/"+pattern+")")
+	}
+	return append(rules, deniedCommands...)
+}
+
+// WriteAgentFiles makes the agent ignore files and the agent settings of the repository at dir
+// deny access to credential files and to commands printing the environment. Only the guard's
+// section of the ignore files and missing deny rules are written; everything else is kept.
+// It returns the files it changed.
+func WriteAgentFiles(dir string) ([]string, error) {
+	var changed []string
+	for _, name := range IgnoreFiles {
+		path := filepath.Join(dir, name)
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return changed, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		updated, err := contextmerge.SetSection(string(content), sectionID, ignoreSection())
+		if err != nil {
+			return changed, fmt.Errorf("%s: %w", path, err)
+		}
+		if updated == string(content) {
+			continue
+		}
+		if err := fs.WriteFile(path, []byte(updated)); err != nil {
+			return changed, err
+		}
+		changed = append(changed, name)
+	}
+
+	updated, err := writeDenyRules(filepath.Join(dir, SettingsFile))
+	if err != nil {
+		return changed, err
+	}
+	if updated {
+		changed = append(changed, SettingsFile)
+	}
+	return changed, nil
+}
+
+// writeDenyRules adds the missing deny rules to permissions.deny of the settings file at path,
+// keeping all other settings. It reports whether the file changed.
+func writeDenyRules(path string) (bool, error) {
+	settings := make(map[string]any)
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	default:
+		if err := json.Unmarshal(content, &settings); err != nil {
+			return false, fmt.Errorf("invalid settings file %s: %w", path, err)
+		}
+	}
+
+	permissions, _ := settings["permissions"].(map[string]any)
+	if permissions == nil {
+		permissions = make(map[string]any)
+	}
+	deny, _ := permissions["deny"].([]any)
+	added := false
+	for _, rule := range denyRules() {
+		if !slices.Contains(deny, any(rule)) {
+			deny = append(deny, rule)
+			added = true
+		}
+	}
+	if !added {
+		return false, nil
+	}
+	permissions["deny"] = deny
+	settings["permissions"] = permissions
+
+	encoded, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("could not encode %s: %w", path, err)
+	}
+	if err := fs.CreateDir(filepath.Dir(path)); err != nil {
+		return false, err
+	}
+	return true, fs.WriteFile(path, append(encoded, '\n'))
+}
@@ -0,0 +1,148 @@
+// Package guard keeps secrets out of atelier repositories and out of AI agent sessions. It scans
+// staged changes, unpushed commits and working trees for credentials, and writes ignore and deny
+// files that stop agents from reading credential files.
+package guard
+
+import (
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AllowPragma marks a line that the scanner must not report, e.g. a documented test key.
+const AllowPragma = "atelier:allow-secret"
+
+// Rule detects one kind of secret in a line of text.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Group is the capture group holding the secret; 0 is the whole match.
+	Group int
+	// MinEntropy, when set, is the Shannon entropy in bits per character the secret must reach.
+	// It keeps generic key=value rules from reporting placeholders like "changeme".
+	MinEntropy float64
+}
+
+// Rules are the secret patterns the scanner looks for.
+var Rules = []Rule{
+	{Name: "aws-access-key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "github-token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{Name: "gitlab-token", Pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{Name: "slack-token", Pattern: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{Name: "google-api-key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{Name: "stripe-key", Pattern: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}`)},
+	{Name: "api-key", Pattern: regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{32,}`)},
+	{Name: "private-key", Pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	// Credentials assigned to a quoted literal, e.g. api_key: "..." or password = '...'
+	{
+		Name:       "credential-assignment",
+		Pattern:    regexp.MustCompile(`(?i)[\w.-]*` + credentialKey + `[\w.-]*["']?\s*[:=]\s*["']([A-Za-z0-9+/=_.\-]{16,})["']`),
+		Group:      1,
+		MinEntropy: 3.5,
+	},
+	// Unquoted values only count in env, YAML and INI style lines, where the key starts the line and
+	// the value is a single word, so code such as h = computePasswordHash(x) or
+	// auth = pkg.NewAuthenticator is not taken for a secret
+	{
+		Name:       "credential-assignment",
+		Pattern:    regexp.MustCompile(`(?i)^\s*(?:export\s+)?[\w-]*` + credentialKey + `[\w-]*\s*[:=]\s*([A-Za-z0-9+/=_\-]{16,})\s*$`),
+		Group:      1,
+		MinEntropy: 3.5,
+	},
+}
+
+// credentialKey matches the part of a key name that marks its value as a credential.
+const credentialKey = `(?:api[_-]?key|secret|token|passw(?:or)?d|credential)`
+
+// SensitiveFiles are path patterns of files that hold credentials and must not be committed or
+// read by agents. Patterns without a slash match the file name in any directory.
+var SensitiveFiles = []string{
+	".env",
+	".env.*",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"*.keystore",
+	"*.jks",
+	"id_rsa",
+	"id_dsa",
+	"id_ecdsa",
+	"id_ed25519",
+	".netrc",
+	".git-credentials",
+	"credentials.json",
+	"service-account*.json",
+}
+
+// templateFiles are examples of sensitive files that are meant to be committed.
+var templateFiles = []string{".env.example", ".env.sample", ".env.template", ".env.dist"}
+
+// IsSensitiveFile reports whether the file at the slash-separated path p holds credentials.
+func IsSensitiveFile(p string) bool {
+	name := path.Base(p)
+	for _, t := range templateFiles {
+		if name == t {
+			return false
+		}
+	}
+	for _, pattern := range SensitiveFiles {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// match is a secret found in a line.
+type match struct {
+	rule   string
+	secret string
+}
+
+// scanLine returns the secrets in line.
+func scanLine(line string) []match {
+	if strings.Contains(line, AllowPragma) {
+		return nil
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	for _, rule := range Rules {
+		for _, m := range rule.Pattern.FindAllStringSubmatch(line, -1) {
+			secret := m[rule.Group]
+			if seen[secret] || rule.MinEntropy > 0 && entropy(secret) < rule.MinEntropy {
+				continue
+			}
+			seen[secret] = true
+			matches = append(matches, match{rule: rule.Name, secret: secret})
+		}
+	}
+	return matches
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// redact shortens a secret so findings can be shown without leaking it.
+func redact(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", 8)
+}
@@ -0,0 +1,197 @@
+package guard
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+)
+
+// maxFileSize is the size above which the scans do not read a file's content.
+const maxFileSize = 1 << 20
+
+// Finding is a secret or credential file found by a scan.
+type Finding struct {
+	Path   string // Slash-separated path relative to the repository
+	Line   int    // 0 for findings about the whole file
+	Rule   string // Rule name, or "sensitive-file"
+	Match  string // Redacted secret
+	Commit string // Commit introducing the secret, for scans of unpushed commits
+}
+
+func (f Finding) String() string {
+	location := f.Path
+	if f.Line > 0 {
+		location += ":" + strconv.Itoa(f.Line)
+	}
+	if f.Commit != "" {
+		location = f.Commit[:min(len(f.Commit), 12)] + " " + location
+	}
+	if f.Match == "" {
+		return fmt.Sprintf("%s: %s", location, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s (%s)", location, f.Rule, f.Match)
+}
+
+// FindingsError reports the findings that stopped a commit or push.
+type FindingsError struct {
+	Repo     string
+	Findings []Finding
+}
+
+func (e *FindingsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "possible secrets in %s:", e.Repo)
+	for _, f := range e.Findings {
+		b.WriteString("\n  " + f.String())
+	}
+	b.WriteString("\nremove them, or mark lines that are meant to be committed with '" + AllowPragma + "'")
+	return b.String()
+}
+
+// ScanStaged scans the changes staged in the repository at dir.
+func ScanStaged(dir string) ([]Finding, error) {
+	out, err := gitutil.RunGitCommandOutput(dir, append(diffArgs("diff"), "--cached")...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDiff(out)
+}
+
+// ScanUnpushed scans the commits of the current branch of the repository at dir that are not on
+// any branch of remote.
+func ScanUnpushed(dir, remote string) ([]Finding, error) {
+	args := append(diffArgs("log"), "-p", "--format=commit %H", "HEAD", "--not", "--remotes="+remote)
+	out, err := gitutil.RunGitCommandOutput(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDiff(out)
+}
+
+// diffArgs returns the arguments of a git diff or log command printing the added lines of new,
+// copied, modified and renamed files with unquoted paths and no context.
+func diffArgs(command string) []string {
+	return []string{"-c", "core.quotePath=false", command, "-U0", "--no-color", "--no-ext-diff",
+		"--ignore-submodules", "--diff-filter=ACMR"}
+}
+
+// scanDiff scans the added lines and new files of the output of git diff or git log -p.
+func scanDiff(diff string) ([]Finding, error) {
+	var findings []Finding
+	var commit, file string
+	line, inHeader := 0, false
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "commit "):
+			commit = strings.TrimPrefix(text, "commit ")
+		case strings.HasPrefix(text, "diff --git "):
+			// Binary files have no +++ line, so the path is taken from the header: diff --git a/<old> b/<new>
+			inHeader = true
+			if i := strings.LastIndex(text, " b/"); i >= 0 {
+				file = text[i+len(" b/"):]
+			}
+			if IsSensitiveFile(file) {
+				findings = append(findings, Finding{Path: file, Rule: "sensitive-file", Commit: commit})
+			}
+		case strings.HasPrefix(text, "@@ "):
+			inHeader = false
+			line = hunkStart(text)
+		case strings.HasPrefix(text, "+") && !inHeader:
+			for _, m := range scanLine(text[1:]) {
+				findings = append(findings, Finding{Path: file, Line: line, Rule: m.rule, Match: redact(m.secret), Commit: commit})
+			}
+			line++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return findings, fmt.Errorf("failed to read diff: %w", err)
+	}
+	return findings, nil
+}
+
+// hunkStart returns the first line number of the new side of a hunk header "@@ -a,b +c,d @@".
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// ScanWorkingTree scans the changes a commit of the whole working tree of the repository at dir
+// would include, without staging them: the changes to tracked files since HEAD and the untracked
+// files that are not ignored. Submodules are not entered.
+func ScanWorkingTree(dir string) ([]Finding, error) {
+	if _, err := gitutil.HeadCommit(dir); err != nil {
+		return ScanTree(dir) // No commit yet: every file is new
+	}
+	out, err := gitutil.RunGitCommandOutput(dir, append(diffArgs("diff"), "HEAD")...)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := scanDiff(out)
+	if err != nil {
+		return findings, err
+	}
+	out, err = gitutil.RunGitCommandOutput(dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return findings, err
+	}
+	untracked, err := scanFiles(dir, strings.Split(out, "\x00"))
+	return append(findings, untracked...), err
+}
+
+// ScanTree scans the files of the repository at dir that a commit could include: tracked files
+// and untracked files that are not ignored. Submodules are not entered.
+func ScanTree(dir string) ([]Finding, error) {
+	out, err := gitutil.RunGitCommandOutput(dir, "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return scanFiles(dir, strings.Split(out, "\x00"))
+}
+
+// scanFiles scans the content of files, slash-separated paths relative to dir, and reports
+// credential files among them.
+func scanFiles(dir string, files []string) ([]Finding, error) {
+	var findings []Finding
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		if IsSensitiveFile(file) {
+			findings = append(findings, Finding{Path: file, Rule: "sensitive-file"})
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return findings, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			continue // binary
+		}
+		for i, text := range strings.Split(string(content), "\n") {
+			for _, m := range scanLine(text) {
+				findings = append(findings, Finding{Path: file, Line: i + 1, Rule: m.rule, Match: redact(m.secret)})
+			}
+		}
+	}
+	return findings, nil
+}
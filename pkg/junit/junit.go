@@ -0,0 +1,170 @@
+// Package junit writes test results in the JUnit XML format understood by CI systems.
+package junit
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TestSuites is the root element of a report.
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr,omitempty"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite groups the test cases of one artist.
+type TestSuite struct {
+	Name     string     `xml:"name,attr"`
+	Tests    int        `xml:"tests,attr"`
+	Failures int        `xml:"failures,attr"`
+	Skipped  int        `xml:"skipped,attr"`
+	Time     float64    `xml:"time,attr"`
+	Cases    []TestCase `xml:"testcase"`
+}
+
+// TestCase is the result of one run, e.g. a make target in a canvas.
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      float64  `xml:"time,attr"`
+	Failure   *Failure `xml:"failure,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
+	SystemOut *Output  `xml:"system-out,omitempty"`
+}
+
+// Output is text captured from a test case, kept verbatim except for what XML cannot hold (see Encode).
+type Output struct {
+	Text string `xml:",cdata"`
+}
+
+// Failure marks a failed test case.
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// Skipped marks a test case that did not run.
+type Skipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// Add appends a test case to the suite and updates its counters.
+func (s *TestSuite) Add(c TestCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	s.Time = roundMillis(s.Time + c.Time)
+	switch {
+	case c.Failure != nil:
+		s.Failures++
+	case c.Skipped != nil:
+		s.Skipped++
+	}
+}
+
+// Add appends a suite to the report and updates its counters.
+func (r *TestSuites) Add(s TestSuite) {
+	r.Suites = append(r.Suites, s)
+	r.Tests += s.Tests
+	r.Failures += s.Failures
+	r.Skipped += s.Skipped
+	r.Time = roundMillis(r.Time + s.Time)
+}
+
+// Seconds converts a duration to the fractional seconds used in time attributes.
+func Seconds(d time.Duration) float64 {
+	return float64(d.Milliseconds()) / 1000
+}
+
+// roundMillis drops the floating point noise that summing times in seconds adds.
+func roundMillis(seconds float64) float64 {
+	return math.Round(seconds*1000) / 1000
+}
+
+// Encode writes the report as indented XML. Terminal escape sequences, e.g. colors, are removed from
+// the names, messages and output, and other characters XML 1.0 does not allow are replaced by U+FFFD.
+func (r *TestSuites) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(r.sanitized()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteFile writes the report to path.
+func (r *TestSuites) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// sanitized returns a copy of the report whose text XML 1.0 can hold.
+func (r *TestSuites) sanitized() *TestSuites {
+	report := *r
+	report.Name = sanitize(r.Name)
+	report.Suites = make([]TestSuite, len(r.Suites))
+	for i, suite := range r.Suites {
+		suite.Name = sanitize(suite.Name)
+		suite.Cases = make([]TestCase, len(r.Suites[i].Cases))
+		for j, c := range r.Suites[i].Cases {
+			c.Name, c.ClassName = sanitize(c.Name), sanitize(c.ClassName)
+			if c.Failure != nil {
+				c.Failure = &Failure{Message: sanitize(c.Failure.Message), Text: sanitize(c.Failure.Text)}
+			}
+			if c.Skipped != nil {
+				c.Skipped = &Skipped{Message: sanitize(c.Skipped.Message)}
+			}
+			if c.SystemOut != nil {
+				c.SystemOut = &Output{Text: sanitize(c.SystemOut.Text)}
+			}
+			suite.Cases[j] = c
+		}
+		report.Suites[i] = suite
+	}
+	return &report
+}
+
+// ansiEscape matches the terminal control sequences tools print for colors and cursor movement.
+var ansiEscape = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[@-Z\\-_])`)
+
+// sanitize removes terminal escape sequences from s and replaces the characters XML 1.0 does not
+// allow, including invalid UTF-8, by U+FFFD. CDATA sections are not escaped, so the encoder would
+// write such characters as they are and produce a report parsers reject.
+func sanitize(s string) string {
+	s = ansiEscape.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if isXMLChar(r) {
+			return r
+		}
+		return utf8.RuneError
+	}, s)
+}
+
+// isXMLChar reports whether r is in the Char production of the XML 1.0 specification.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
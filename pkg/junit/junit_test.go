@@ -0,0 +1,97 @@
+package junit
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdd(t *testing.T) {
+	suite := TestSuite{Name: "artist-picasso"}
+	suite.Add(TestCase{Name: "canvas-guernica", Time: 0.1})
+	suite.Add(TestCase{Name: "canvas-dora", Time: 0.2, Failure: &Failure{Message: "exit status 2"}})
+	suite.Add(TestCase{Name: "canvas-weeping", Skipped: &Skipped{Message: "no test target"}})
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 || suite.Time != 0.3 {
+		t.Errorf("suite counters = %d tests, %d failures, %d skipped, %v s", suite.Tests, suite.Failures, suite.Skipped, suite.Time)
+	}
+
+	var report TestSuites
+	report.Add(suite)
+	report.Add(TestSuite{Name: "artist-monet", Tests: 2, Time: 0.4})
+	if report.Tests != 5 || report.Failures != 1 || report.Skipped != 1 || report.Time != 0.7 {
+		t.Errorf("report counters = %d tests, %d failures, %d skipped, %v s", report.Tests, report.Failures, report.Skipped, report.Time)
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	if got := Seconds(1500*time.Millisecond + 400*time.Microsecond); got != 1.5 {
+		t.Errorf("Seconds = %v, want 1.5", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var report TestSuites
+	suite := TestSuite{Name: "artist-picasso"}
+	suite.Add(TestCase{Name: "canvas-guernica", ClassName: "artist-picasso", Failure: &Failure{Message: "failed", Text: "a < b && ]]> c"}})
+	report.Add(suite)
+
+	var out strings.Builder
+	if err := report.Encode(&out); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), xml.Header) {
+		t.Errorf("report does not start with the XML header:\n%s", out.String())
+	}
+	var decoded TestSuites
+	if err := xml.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, out.String())
+	}
+	if got := decoded.Suites[0].Cases[0].Failure.Text; got != "a < b && ]]> c" {
+		t.Errorf("failure text = %q", got)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := map[string]string{
+		"plain\ttext\r\n":              "plain\ttext\r\n",
+		"\x1b[31mFAIL\x1b[0m TestX":    "FAIL TestX",
+		"\x1b[1;32m--- PASS\x1b[K":     "--- PASS",
+		"\x1b]0;title\x07done":         "done",
+		"bell\x07 backspace\b nul\x00": "bell� backspace� nul�",
+		"invalid \xff utf-8":           "invalid � utf-8",
+		"emoji 🎨 and \uFFFE":           "emoji 🎨 and �",
+	}
+	for input, want := range tests {
+		if got := sanitize(input); got != want {
+			t.Errorf("sanitize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestEncodeControlCharacters(t *testing.T) {
+	var report TestSuites
+	suite := TestSuite{Name: "artist-picasso"}
+	suite.Add(TestCase{
+		Name:      "canvas-guernica",
+		Failure:   &Failure{Message: "\x1b[31mexit status 2\x1b[0m", Text: "\x1b[31mFAIL\x1b[0m\x00"},
+		SystemOut: &Output{Text: "progress\b\b\x1b[2K100%"},
+	})
+	report.Add(suite)
+
+	var out strings.Builder
+	if err := report.Encode(&out); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var decoded TestSuites
+	if err := xml.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%q", err, out.String())
+	}
+	c := decoded.Suites[0].Cases[0]
+	if c.Failure.Message != "exit status 2" || c.Failure.Text != "FAIL�" || c.SystemOut.Text != "progress��100%" {
+		t.Errorf("decoded case = %q, %q, %q", c.Failure.Message, c.Failure.Text, c.SystemOut.Text)
+	}
+	if report.Suites[0].Cases[0].Failure.Text != "\x1b[31mFAIL\x1b[0m\x00" {
+		t.Error("Encode modified the report")
+	}
+}
@@ -0,0 +1,56 @@
+// Package makefile reads the targets a Makefile documents with the `target: ## description`
+// convention used by the atelier templates and their help target.
+package makefile
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+)
+
+// FileName is the name of the Makefile looked up in a repository.
+const FileName = "Makefile"
+
+// helpTarget matches documented targets, like the grep in the templates' help target.
+var helpTarget = regexp.MustCompile(`^([a-zA-Z_-]+):.*?## (.*)$`)
+
+// Target is a documented Makefile target.
+type Target struct {
+	Name        string
+	Description string
+}
+
+// Targets returns the documented targets of the Makefile at path, in file order.
+func Targets(path string) ([]Target, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var targets []Target
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if m := helpTarget.FindStringSubmatch(scanner.Text()); m != nil {
+			targets = append(targets, Target{Name: m[1], Description: m[2]})
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// Defines reports whether the Makefile at path documents target. A missing Makefile defines nothing.
+func Defines(path, target string) (bool, error) {
+	targets, err := Targets(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, t := range targets {
+		if t.Name == target {
+			return true, nil
+		}
+	}
+	return false, nil
+}
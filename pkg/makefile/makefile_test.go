@@ -0,0 +1,48 @@
+package makefile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	content := `.PHONY: help test
+help: ## Show this help
+	@grep -E '^[a-zA-Z_-]+:.*?## .*$$' $(MAKEFILE_LIST)
+
+test: build ## Run the tests
+	go test ./...
+
+build:
+	go build ./...
+
+lint-all: ## Lint: every package ## twice
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := Targets(path)
+	if err != nil {
+		t.Fatalf("Targets: %v", err)
+	}
+	want := []Target{
+		{Name: "help", Description: "Show this help"},
+		{Name: "test", Description: "Run the tests"},
+		{Name: "lint-all", Description: "Lint: every package ## twice"},
+	}
+	if !slices.Equal(targets, want) {
+		t.Errorf("Targets = %v, want %v", targets, want)
+	}
+
+	for target, want := range map[string]bool{"test": true, "build": false, "deploy": false} {
+		if got, err := Defines(path, target); err != nil || got != want {
+			t.Errorf("Defines(%s) = %v, %v; want %v", target, got, err, want)
+		}
+	}
+	if got, err := Defines(filepath.Join(t.TempDir(), FileName), "test"); err != nil || got {
+		t.Errorf("Defines on a missing Makefile = %v, %v; want false, nil", got, err)
+	}
+}
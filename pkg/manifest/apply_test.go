@@ -0,0 +1,59 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+)
+
+func TestApplyUndo(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-dora", "artist-monet/canvas-lilies")
+	repos := []string{w.Root, filepath.Join(w.Root, "artist-picasso"), filepath.Join(w.Root, "artist-monet")}
+	before := make(map[string]string)
+	for _, repo := range repos {
+		before[repo] = gittest.Head(t, repo)
+	}
+
+	m := &Manifest{Artists: []Artist{
+		{Name: "picasso"},
+		{Name: "monet", Canvases: []Canvas{{Name: "lilies"}, {Name: "guernica"}}},
+	}}
+	actions, err := Plan(w.Root, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(w.Root, actions); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(w.Root, "artist-monet", "canvas-guernica", ".canvas")); err != nil {
+		t.Fatalf("canvas-guernica not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(w.Root, "artist-picasso", "canvas-dora")); !os.IsNotExist(err) {
+		t.Fatalf("canvas-dora not deleted: %v", err)
+	}
+	if status := gittest.Run(t, w.Root, "status", "--porcelain"); status != "" {
+		t.Errorf("atelier not clean after apply:\n%s", status)
+	}
+
+	// Apply commits in the atelier and both artists; undo must know every one of those commits
+	j, err := engine.Undo(w.Root, false)
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if j == nil || j.Operation != "apply" {
+		t.Fatalf("Undo reverted %v, want apply", j)
+	}
+	for _, repo := range repos {
+		if head := gittest.Head(t, repo); head != before[repo] {
+			t.Errorf("%s is at %s after undo, want %s", filepath.Base(repo), head, before[repo])
+		}
+	}
+	for _, canvas := range []string{"canvas-guernica", "canvas-dora"} {
+		if _, err := os.Stat(filepath.Join(w.Root, "artist-picasso", canvas, ".canvas")); err != nil {
+			t.Errorf("%s not back in artist-picasso: %v", canvas, err)
+		}
+	}
+}
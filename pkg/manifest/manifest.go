@@ -0,0 +1,105 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the manifest file looked up at the atelier root.
+const FileName = "atelier.yaml"
+
+// Manifest declares the desired shape of an atelier.
+//
+//	remote: git@github.com:acme/atelier-demo.git
+//	artists:
+//	  - name: picasso
+//	    template: artist-default
+//	    canvases:
+//	      - name: guernica
+//	        remote: git@github.com:acme/canvas-guernica.git
+type Manifest struct {
+	Remote  string   `yaml:"remote,omitempty"`
+	Artists []Artist `yaml:"artists"`
+}
+
+// Artist declares an artist and the canvases it should contain.
+type Artist struct {
+	Name     string   `yaml:"name"`
+	Template string   `yaml:"template,omitempty"`
+	Remote   string   `yaml:"remote,omitempty"`
+	Canvases []Canvas `yaml:"canvases,omitempty"`
+}
+
+// Canvas declares a canvas.
+type Canvas struct {
+	Name     string `yaml:"name"`
+	Template string `yaml:"template,omitempty"`
+	Remote   string `yaml:"remote,omitempty"`
+}
+
+// DirName returns the artist directory name, e.g. "artist-picasso".
+func (a Artist) DirName() string {
+	return marker.KindArtist.DirName(a.Name)
+}
+
+// DirName returns the canvas directory name, e.g. "canvas-guernica".
+func (c Canvas) DirName() string {
+	return marker.KindCanvas.DirName(c.Name)
+}
+
+// Load reads and validates the manifest at path.
+func Load(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Validate checks that names are present and that no artist or canvas is declared twice.
+// Canvas names must be unique across the atelier so moves between artists can be detected.
+func (m *Manifest) Validate() error {
+	artists := map[string]bool{}
+	canvases := map[string]string{}
+	for _, artist := range m.Artists {
+		if strings.TrimSpace(artist.Name) == "" {
+			return fmt.Errorf("artist without a name")
+		}
+		if artists[artist.DirName()] {
+			return fmt.Errorf("artist %s is declared more than once", artist.DirName())
+		}
+		artists[artist.DirName()] = true
+
+		for _, canvas := range artist.Canvases {
+			if strings.TrimSpace(canvas.Name) == "" {
+				return fmt.Errorf("canvas without a name in artist %s", artist.DirName())
+			}
+			if other, ok := canvases[canvas.DirName()]; ok {
+				return fmt.Errorf("canvas %s is declared in both %s and %s", canvas.DirName(), other, artist.DirName())
+			}
+			canvases[canvas.DirName()] = artist.DirName()
+		}
+	}
+	return nil
+}
+
+// Path returns the default manifest location for the atelier at atelierPath.
+func Path(atelierPath string) string {
+	return filepath.Join(atelierPath, FileName)
+}
@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		err      string
+	}{
+		{"empty", Manifest{}, ""},
+		{"valid", Manifest{Artists: []Artist{
+			{Name: "picasso", Canvases: []Canvas{{Name: "guernica"}}},
+			{Name: "artist-monet", Canvases: []Canvas{{Name: "canvas-water-lilies"}}},
+		}}, ""},
+		{"artist without a name", Manifest{Artists: []Artist{{Name: " "}}}, "artist without a name"},
+		{"artist twice", Manifest{Artists: []Artist{{Name: "picasso"}, {Name: "artist-picasso"}}}, "declared more than once"},
+		{"canvas without a name", Manifest{Artists: []Artist{{Name: "picasso", Canvases: []Canvas{{}}}}}, "canvas without a name"},
+		{"canvas in two artists", Manifest{Artists: []Artist{
+			{Name: "picasso", Canvases: []Canvas{{Name: "guernica"}}},
+			{Name: "monet", Canvases: []Canvas{{Name: "canvas-guernica"}}},
+		}}, "declared in both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.manifest.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDirName(t *testing.T) {
+	for name, want := range map[string]string{"picasso": "artist-picasso", "artist-picasso": "artist-picasso", " picasso ": "artist-picasso"} {
+		if got := (Artist{Name: name}).DirName(); got != want {
+			t.Errorf("Artist{%q}.DirName() = %q, want %q", name, got, want)
+		}
+	}
+	if got := (Canvas{Name: "guernica"}).DirName(); got != "canvas-guernica" {
+		t.Errorf("Canvas{guernica}.DirName() = %q", got)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string // artist or artist/canvas directories
+		manifest Manifest
+		want     []string
+	}{
+		{
+			name:     "in sync",
+			existing: []string{"artist-picasso/canvas-guernica"},
+			manifest: Manifest{Artists: []Artist{{Name: "picasso", Canvases: []Canvas{{Name: "guernica"}}}}},
+		},
+		{
+			name: "creations",
+			manifest: Manifest{Artists: []Artist{{Name: "picasso", Template: "artist-sketch", Canvases: []Canvas{
+				{Name: "guernica", Template: "canvas-sunflowers"},
+			}}}},
+			want: []string{
+				"+ create artist artist-picasso (template artist-sketch)",
+				"+ create canvas artist-picasso/canvas-guernica (template canvas-sunflowers)",
+			},
+		},
+		{
+			name:     "move between artists",
+			existing: []string{"artist-picasso/canvas-guernica", "artist-monet"},
+			manifest: Manifest{Artists: []Artist{
+				{Name: "picasso"},
+				{Name: "monet", Canvases: []Canvas{{Name: "guernica"}}},
+			}},
+			want: []string{"~ move canvas canvas-guernica: artist-picasso -> artist-monet"},
+		},
+		{
+			name:     "undeclared canvas and artist",
+			existing: []string{"artist-picasso/canvas-guernica", "artist-monet"},
+			manifest: Manifest{Artists: []Artist{{Name: "picasso"}}},
+			want: []string{
+				"- delete canvas artist-picasso/canvas-guernica",
+				"! artist-monet exists but is not declared in atelier.yaml (left untouched)",
+			},
+		},
+		{
+			name:     "copy in two artists",
+			existing: []string{"artist-picasso/canvas-guernica", "artist-monet/canvas-guernica"},
+			manifest: Manifest{Artists: []Artist{
+				{Name: "monet"},
+				{Name: "picasso", Canvases: []Canvas{{Name: "guernica"}}},
+			}},
+			want: []string{"- delete canvas artist-monet/canvas-guernica"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atelierPath := t.TempDir()
+			for _, dir := range tt.existing {
+				if err := os.MkdirAll(filepath.Join(atelierPath, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			actions, err := Plan(atelierPath, &tt.manifest)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			var got []string
+			for _, a := range actions {
+				got = append(got, a.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Plan =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/engine"
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// ActionType identifies what an Action does to the directory tree.
+type ActionType string
+
+const (
+	CreateArtist ActionType = "create-artist"
+	CreateCanvas ActionType = "create-canvas"
+	MoveCanvas   ActionType = "move-canvas"
+	DeleteCanvas ActionType = "delete-canvas"
+	SetRemote    ActionType = "set-remote"
+	Unmanaged    ActionType = "unmanaged" // Informational: present on disk, absent from the manifest
+)
+
+// remoteName is the remote managed by the manifest.
+const remoteName = "origin"
+
+// Action is one step needed to converge the directory tree on the manifest.
+type Action struct {
+	Type     ActionType
+	Artist   string // Artist directory name the action applies to (target artist for moves)
+	Canvas   string // Canvas directory name, if the action concerns a canvas
+	From     string // Source artist directory name for moves
+	Template string // Template for creations
+	Remote   string // Remote URL for SetRemote
+}
+
+// Destructive reports whether applying the action removes data.
+func (a Action) Destructive() bool {
+	return a.Type == DeleteCanvas
+}
+
+// String renders the action as a single plan line.
+func (a Action) String() string {
+	switch a.Type {
+	case CreateArtist:
+		return fmt.Sprintf("+ create artist %s%s", a.Artist, templateSuffix(a.Template))
+	case CreateCanvas:
+		return fmt.Sprintf("+ create canvas %s/%s%s", a.Artist, a.Canvas, templateSuffix(a.Template))
+	case MoveCanvas:
+		return fmt.Sprintf("~ move canvas %s: %s -> %s", a.Canvas, a.From, a.Artist)
+	case DeleteCanvas:
+		return fmt.Sprintf("- delete canvas %s/%s", a.Artist, a.Canvas)
+	case SetRemote:
+		return fmt.Sprintf("~ set remote %s of %s to %s", remoteName, a.target(), a.Remote)
+	case Unmanaged:
+		return fmt.Sprintf("! %s exists but is not declared in %s (left untouched)", a.Artist, FileName)
+	default:
+		return string(a.Type)
+	}
+}
+
+// target returns the path of the repository the action applies to, relative to the atelier root.
+func (a Action) target() string {
+	switch {
+	case a.Canvas != "":
+		return filepath.Join(a.Artist, a.Canvas)
+	case a.Artist != "":
+		return a.Artist
+	default:
+		return "."
+	}
+}
+
+func templateSuffix(template string) string {
+	if template == "" {
+		return ""
+	}
+	return " (template " + template + ")"
+}
+
+// Plan compares the manifest with the atelier at atelierPath and returns the actions needed to converge,
+// ordered so they can be applied in sequence: artist creations, moves, canvas creations, deletions, remotes.
+func Plan(atelierPath string, m *Manifest) ([]Action, error) {
+	// Current layout: artist -> canvases, and canvas -> artist
+	existingArtists := map[string]bool{}
+	canvasOwner := map[string]string{}
+	var existingOrder []string
+	artistPaths, err := marker.ChildDirs(atelierPath, marker.KindArtist)
+	if err != nil {
+		return nil, err
+	}
+	for _, artistPath := range artistPaths {
+		artist := filepath.Base(artistPath)
+		existingArtists[artist] = true
+		existingOrder = append(existingOrder, artist)
+		canvasPaths, err := marker.ChildDirs(artistPath, marker.KindCanvas)
+		if err != nil {
+			return nil, err
+		}
+		for _, canvasPath := range canvasPaths {
+			canvas := filepath.Base(canvasPath)
+			if _, seen := canvasOwner[canvas]; !seen {
+				canvasOwner[canvas] = artist
+			}
+		}
+	}
+
+	var creates, moves, canvasCreates, deletes, remotes, notes []Action
+	declaredCanvases := map[string]string{}
+	declaredArtists := map[string]bool{}
+	movedFrom := map[string]string{}
+
+	if m.Remote != "" && gitutil.RemoteURL(atelierPath, remoteName) != m.Remote {
+		remotes = append(remotes, Action{Type: SetRemote, Remote: m.Remote})
+	}
+
+	for _, artist := range m.Artists {
+		artistDir := artist.DirName()
+		declaredArtists[artistDir] = true
+		if !existingArtists[artistDir] {
+			creates = append(creates, Action{Type: CreateArtist, Artist: artistDir, Template: artist.Template})
+		}
+		if artist.Remote != "" && gitutil.RemoteURL(filepath.Join(atelierPath, artistDir), remoteName) != artist.Remote {
+			remotes = append(remotes, Action{Type: SetRemote, Artist: artistDir, Remote: artist.Remote})
+		}
+
+		for _, canvas := range artist.Canvases {
+			canvasDir := canvas.DirName()
+			declaredCanvases[canvasDir] = artistDir
+			owner, exists := existingOwner(atelierPath, artistDir, canvasDir, canvasOwner)
+			switch {
+			case !exists:
+				canvasCreates = append(canvasCreates, Action{Type: CreateCanvas, Artist: artistDir, Canvas: canvasDir, Template: canvas.Template})
+			case owner != artistDir:
+				moves = append(moves, Action{Type: MoveCanvas, Artist: artistDir, Canvas: canvasDir, From: owner})
+				movedFrom[canvasDir] = owner
+			}
+			if canvas.Remote != "" && gitutil.RemoteURL(filepath.Join(atelierPath, artistDir, canvasDir), remoteName) != canvas.Remote {
+				remotes = append(remotes, Action{Type: SetRemote, Artist: artistDir, Canvas: canvasDir, Remote: canvas.Remote})
+			}
+		}
+	}
+
+	for _, artistDir := range existingOrder {
+		if !declaredArtists[artistDir] {
+			notes = append(notes, Action{Type: Unmanaged, Artist: artistDir})
+			continue
+		}
+		canvasPaths, err := marker.ChildDirs(filepath.Join(atelierPath, artistDir), marker.KindCanvas)
+		if err != nil {
+			return nil, err
+		}
+		for _, canvasPath := range canvasPaths {
+			canvasDir := filepath.Base(canvasPath)
+			target, declared := declaredCanvases[canvasDir]
+			// A canvas declared elsewhere is moved away from one owner; any other copy is deleted.
+			if declared && (target == artistDir || movedFrom[canvasDir] == artistDir) {
+				continue
+			}
+			deletes = append(deletes, Action{Type: DeleteCanvas, Artist: artistDir, Canvas: canvasDir})
+		}
+	}
+
+	var actions []Action
+	for _, group := range [][]Action{creates, moves, canvasCreates, deletes, remotes, notes} {
+		actions = append(actions, group...)
+	}
+	return actions, nil
+}
+
+// existingOwner reports which artist currently holds canvasDir, preferring the declared artist
+// when the canvas exists in several artists.
+func existingOwner(atelierPath, artistDir, canvasDir string, canvasOwner map[string]string) (string, bool) {
+	if info, err := os.Stat(filepath.Join(atelierPath, artistDir, canvasDir)); err == nil && info.IsDir() {
+		return artistDir, true
+	}
+	owner, ok := canvasOwner[canvasDir]
+	return owner, ok
+}
+
+// Apply performs the actions in order against the atelier at atelierPath as a single operation:
+// it stops at the first failing action and rolls back the ones before it, and 'atelier undo'
+// reverts it as a whole.
+func Apply(atelierPath string, actions []Action) error {
+	return engine.Atomic(atelierPath, "apply", func() error {
+		for _, action := range actions {
+			if err := apply(atelierPath, action); err != nil {
+				return fmt.Errorf("failed to %s: %w", strings.TrimLeft(action.String(), "+-~! "), err)
+			}
+		}
+		return nil
+	})
+}
+
+func apply(atelierPath string, a Action) error {
+	artistPath := filepath.Join(atelierPath, a.Artist)
+	switch a.Type {
+	case CreateArtist:
+		name := strings.TrimPrefix(a.Artist, marker.KindArtist.Prefix())
+		return engine.CreateArtist(atelierPath, name, "", engine.CreateOptions{Template: a.Template})
+	case CreateCanvas:
+		name := strings.TrimPrefix(a.Canvas, marker.KindCanvas.Prefix())
+		if err := engine.CreateCanvas(artistPath, name, engine.CreateOptions{Template: a.Template}); err != nil {
+			return err
+		}
+		// Record the new canvas pointer in the atelier as well
+		if err := gitutil.AddPaths(atelierPath, a.Artist); err != nil {
+			return err
+		}
+		return gitutil.Commit(atelierPath, fmt.Sprintf("feat: update artist %s (add canvas %s)", a.Artist, a.Canvas))
+	case MoveCanvas:
+		// MoveCanvas commits the move in both artists and the atelier itself
+		return engine.MoveCanvas(atelierPath, a.Canvas, a.Artist)
+	case DeleteCanvas:
+		if err := engine.DeleteCanvas(artistPath, a.Canvas, engine.DeleteOptions{Commit: true}); err != nil {
+			return err
+		}
+		if err := gitutil.AddPaths(atelierPath, a.Artist); err != nil {
+			return err
+		}
+		return gitutil.Commit(atelierPath, fmt.Sprintf("feat: update artist %s (remove canvas %s)", a.Artist, a.Canvas))
+	case SetRemote:
+		if err := setRemote(filepath.Join(atelierPath, a.target()), a.Remote); err != nil {
+			return err
+		}
+		// The marker commit moves the repository's HEAD; record the new pointer in its parents.
+		if a.Canvas != "" {
+			if err := recordPointer(artistPath, a.Canvas); err != nil {
+				return err
+			}
+		}
+		if a.Artist != "" {
+			return recordPointer(atelierPath, a.Artist)
+		}
+		return nil
+	case Unmanaged:
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", a.Type)
+	}
+}
+
+// setRemote configures origin and records it in the repository's marker file.
+func setRemote(repoPath, url string) error {
+	if err := gitutil.SetRemote(repoPath, remoteName, url); err != nil {
+		return err
+	}
+	kind, ok := marker.Detect(repoPath)
+	if !ok {
+		return nil
+	}
+	m, err := marker.Read(repoPath, kind)
+	if err != nil {
+		return err
+	}
+	m.Remote = &marker.Remote{Name: remoteName, URL: url}
+	if err := marker.Write(repoPath, m); err != nil {
+		return err
+	}
+	if err := gitutil.AddPaths(repoPath, kind.FileName()); err != nil {
+		return err
+	}
+	return gitutil.Commit(repoPath, fmt.Sprintf("chore: set remote %s to %s", remoteName, url))
+}
+
+// recordPointer commits the current commit of the submodule child in parentPath.
+func recordPointer(parentPath, child string) error {
+	if err := gitutil.AddPaths(parentPath, child); err != nil {
+		return err
+	}
+	staged, err := gitutil.HasStagedChanges(parentPath)
+	if err != nil || !staged {
+		return err
+	}
+	return gitutil.Commit(parentPath, fmt.Sprintf("chore: update %s pointer", child))
+}
@@ -0,0 +1,327 @@
+package marker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+)
+
+// SchemaVersion is the marker schema written by this version of the CLI.
+const SchemaVersion = 1
+
+// Kind identifies which level of the hierarchy a marker describes.
+type Kind string
+
+const (
+	KindAtelier Kind = "atelier"
+	KindArtist  Kind = "artist"
+	KindCanvas  Kind = "canvas"
+)
+
+// Kinds lists the marker kinds from the top of the hierarchy down.
+var Kinds = []Kind{KindAtelier, KindArtist, KindCanvas}
+
+// FileName returns the name of the marker file for the kind, e.g. ".canvas".
+func (k Kind) FileName() string {
+	return "." + string(k)
+}
+
+// Prefix returns the directory name prefix used for the kind, e.g. "canvas-".
+func (k Kind) Prefix() string {
+	return string(k) + "-"
+}
+
+// DirName returns the directory name for a user-supplied name of the kind, with or without the
+// prefix, e.g. "guernica" and "canvas-guernica" both give "canvas-guernica".
+func (k Kind) DirName(name string) string {
+	return k.Prefix() + strings.TrimPrefix(strings.TrimSpace(name), k.Prefix())
+}
+
+// Remote describes where the repository is published.
+type Remote struct {
+	Name     string `json:"name,omitempty"`     // Git remote name, e.g. "origin"
+	URL      string `json:"url,omitempty"`      // Clone URL
+	Provider string `json:"provider,omitempty"` // Hosting provider, e.g. "github"
+}
+
+// Marker is the content of a .atelier, .artist or .canvas file.
+// Names are full directory names (e.g. "atelier-demo", "artist-van-gogh", "canvas-sunflowers").
+type Marker struct {
+	SchemaVersion   int       `json:"schema_version"`
+	Kind            Kind      `json:"kind"`
+	Atelier         string    `json:"atelier"`
+	Artist          string    `json:"artist,omitempty"`
+	Canvas          string    `json:"canvas,omitempty"`
+	Template        string    `json:"template,omitempty"`
+	TemplateVersion string    `json:"template_version,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	Tags            []string  `json:"tags,omitempty"`
+	Remote          *Remote   `json:"remote,omitempty"`
+
+	// Params holds the values of the template parameters the repository was generated with.
+	Params map[string]string `json:"params,omitempty"`
+
+	// Context records the hash of every template section of the generated context files as they
+	// were last written, by file name and section id. It is the base of 'atelier context pull'.
+	Context map[string]map[string]string `json:"context,omitempty"`
+
+	legacy bool
+}
+
+// New returns a marker of the given kind stamped with the current schema version and time.
+func New(kind Kind, atelier, artist, canvas string) *Marker {
+	return &Marker{
+		SchemaVersion: SchemaVersion,
+		Kind:          kind,
+		Atelier:       atelier,
+		Artist:        artist,
+		Canvas:        canvas,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Name returns the directory name the marker describes.
+func (m *Marker) Name() string {
+	switch m.Kind {
+	case KindArtist:
+		return m.Artist
+	case KindCanvas:
+		return m.Canvas
+	default:
+		return m.Atelier
+	}
+}
+
+// Legacy reports whether the marker was parsed from a pre-schema, newline-separated file.
+func (m *Marker) Legacy() bool {
+	return m.legacy
+}
+
+// Path returns the marker file path for kind inside dir.
+func Path(dir string, kind Kind) string {
+	return filepath.Join(dir, kind.FileName())
+}
+
+// Exists reports whether dir contains a marker file of the given kind.
+func Exists(dir string, kind Kind) bool {
+	info, err := os.Stat(Path(dir, kind))
+	return err == nil && !info.IsDir()
+}
+
+// Detect returns the kind of marker present in dir, checking atelier, artist and canvas in that order.
+func Detect(dir string) (Kind, bool) {
+	for _, kind := range Kinds {
+		if Exists(dir, kind) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// Read loads the marker of the given kind from dir.
+// Legacy newline-separated markers are converted in memory; use Migrate to rewrite them on disk.
+func Read(dir string, kind Kind) (*Marker, error) {
+	path := Path(dir, kind)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s file in %s: %w", kind.FileName(), dir, err)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var m Marker
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, fmt.Errorf("invalid %s file in %s: %w", kind.FileName(), dir, err)
+		}
+		if m.SchemaVersion > SchemaVersion {
+			return nil, fmt.Errorf("%s file in %s uses schema version %d; this CLI supports up to %d", kind.FileName(), dir, m.SchemaVersion, SchemaVersion)
+		}
+		if m.Kind != kind {
+			return nil, fmt.Errorf("%s file in %s describes a %s", kind.FileName(), dir, m.Kind)
+		}
+		return &m, nil
+	}
+
+	m, err := parseLegacy(dir, kind, string(trimmed))
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil {
+		m.CreatedAt = info.ModTime().UTC().Truncate(time.Second)
+	}
+	return m, nil
+}
+
+// Write stores m as the marker file in dir using the current schema.
+func Write(dir string, m *Marker) error {
+	m.SchemaVersion = SchemaVersion
+	m.legacy = false
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode %s marker: %w", m.Kind, err)
+	}
+	return fs.WriteFile(Path(dir, m.Kind), append(content, '\n'))
+}
+
+// Migrate rewrites a legacy marker of the given kind in dir using the current schema.
+// It reports whether the file was rewritten.
+func Migrate(dir string, kind Kind) (bool, error) {
+	m, err := Read(dir, kind)
+	if err != nil {
+		return false, err
+	}
+	if !m.Legacy() {
+		return false, nil
+	}
+	if err := Write(dir, m); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MigrateTree migrates the atelier marker at atelierPath and the markers of all of its artists and canvases.
+// It returns the paths of the marker files that were rewritten.
+func MigrateTree(atelierPath string) ([]string, error) {
+	var migrated []string
+	migrate := func(dir string, kind Kind) error {
+		if !Exists(dir, kind) {
+			return nil
+		}
+		changed, err := Migrate(dir, kind)
+		if err != nil {
+			return err
+		}
+		if changed {
+			migrated = append(migrated, Path(dir, kind))
+		}
+		return nil
+	}
+
+	if err := migrate(atelierPath, KindAtelier); err != nil {
+		return migrated, err
+	}
+	artists, err := ChildDirs(atelierPath, KindArtist)
+	if err != nil {
+		return migrated, err
+	}
+	for _, artistPath := range artists {
+		if err := migrate(artistPath, KindArtist); err != nil {
+			return migrated, err
+		}
+		canvases, err := ChildDirs(artistPath, KindCanvas)
+		if err != nil {
+			return migrated, err
+		}
+		for _, canvasPath := range canvases {
+			if err := migrate(canvasPath, KindCanvas); err != nil {
+				return migrated, err
+			}
+		}
+	}
+	return migrated, nil
+}
+
+// ChildDirs returns the subdirectories of dir named with the prefix of kind (e.g. "artist-*"), sorted by name.
+func ChildDirs(dir string, kind Kind) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %w", dir, err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), kind.Prefix()) {
+			dirs = append(dirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// FindRoot walks up from start until it finds a directory containing a .atelier marker.
+func FindRoot(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if Exists(dir, KindAtelier) {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// Reached root directory
+			break
+		}
+		dir = parent
+	}
+
+	return "", fmt.Errorf("could not find atelier root (.atelier file not found)")
+}
+
+// parseLegacy converts the positional marker formats written before schema_version existed:
+//
+//	.atelier: "<atelier>"
+//	.artist:  "<atelier>\n<artist>" or just "<artist>"
+//	.canvas:  "<atelier>\n<artist>\n<canvas>" or just "<canvas>"
+//
+// Missing names are derived from the directory layout.
+func parseLegacy(dir string, kind Kind, content string) (*Marker, error) {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s file in %s is empty", kind.FileName(), dir)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	parentName := filepath.Base(filepath.Dir(abs))
+	grandparentName := filepath.Base(filepath.Dir(filepath.Dir(abs)))
+
+	m := &Marker{SchemaVersion: SchemaVersion, Kind: kind, legacy: true}
+	switch kind {
+	case KindAtelier:
+		m.Atelier = lines[0]
+	case KindArtist:
+		switch len(lines) {
+		case 1:
+			m.Atelier, m.Artist = parentName, lines[0]
+		case 2:
+			m.Atelier, m.Artist = lines[0], lines[1]
+		default:
+			return nil, fmt.Errorf("invalid .artist file format: unexpected number of lines (%d)", len(lines))
+		}
+	case KindCanvas:
+		switch len(lines) {
+		case 1:
+			m.Atelier, m.Artist, m.Canvas = grandparentName, parentName, lines[0]
+		case 3:
+			m.Atelier, m.Artist, m.Canvas = lines[0], lines[1], lines[2]
+		default:
+			return nil, fmt.Errorf("invalid .canvas file format: unexpected number of lines (%d)", len(lines))
+		}
+	default:
+		return nil, fmt.Errorf("unknown marker kind %q", kind)
+	}
+
+	m.Atelier = KindAtelier.DirName(m.Atelier)
+	if m.Artist != "" {
+		m.Artist = KindArtist.DirName(m.Artist)
+	}
+	if m.Canvas != "" {
+		m.Canvas = KindCanvas.DirName(m.Canvas)
+	}
+	return m, nil
+}
@@ -0,0 +1,202 @@
+package marker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirName(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		name string
+		want string
+	}{
+		{KindCanvas, "guernica", "canvas-guernica"},
+		{KindCanvas, "canvas-guernica", "canvas-guernica"},
+		{KindArtist, " picasso ", "artist-picasso"},
+		{KindArtist, "canvas-guernica", "artist-canvas-guernica"},
+		{KindAtelier, "atelier-demo", "atelier-demo"},
+	}
+	for _, tt := range tests {
+		if got := tt.kind.DirName(tt.name); got != tt.want {
+			t.Errorf("%s.DirName(%q) = %q, want %q", tt.kind, tt.name, got, tt.want)
+		}
+	}
+}
+
+// tree creates atelier-demo/artist-picasso/canvas-guernica under a temporary directory and returns
+// the paths of the three levels.
+func tree(t *testing.T) (atelier, artist, canvas string) {
+	t.Helper()
+	atelier = filepath.Join(t.TempDir(), "atelier-demo")
+	artist = filepath.Join(atelier, "artist-picasso")
+	canvas = filepath.Join(artist, "canvas-guernica")
+	if err := os.MkdirAll(canvas, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return atelier, artist, canvas
+}
+
+func writeMarker(t *testing.T, dir string, kind Kind, content string) {
+	t.Helper()
+	if err := os.WriteFile(Path(dir, kind), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    Kind
+		content string
+		want    [3]string // Atelier, artist and canvas
+		err     string
+	}{
+		{"atelier", KindAtelier, "atelier-demo\n", [3]string{"atelier-demo"}, ""},
+		{"atelier without prefix", KindAtelier, "demo", [3]string{"atelier-demo"}, ""},
+		{"artist, two lines", KindArtist, "atelier-demo\nartist-picasso\n", [3]string{"atelier-demo", "artist-picasso"}, ""},
+		{"artist, one line", KindArtist, "picasso\n", [3]string{"atelier-demo", "artist-picasso"}, ""},
+		{"artist, CRLF and blank lines", KindArtist, "\r\nsketches\r\n\r\npicasso\r\n", [3]string{"atelier-sketches", "artist-picasso"}, ""},
+		{"canvas, three lines", KindCanvas, "atelier-demo\nartist-picasso\ncanvas-guernica", [3]string{"atelier-demo", "artist-picasso", "canvas-guernica"}, ""},
+		{"canvas, one line", KindCanvas, "guernica", [3]string{"atelier-demo", "artist-picasso", "canvas-guernica"}, ""},
+		{"canvas, three lines without prefixes", KindCanvas, " demo \n picasso \n dora \n", [3]string{"atelier-demo", "artist-picasso", "canvas-dora"}, ""},
+		{"empty", KindCanvas, " \n\n", [3]string{}, "is empty"},
+		{"artist, three lines", KindArtist, "a\nb\nc", [3]string{}, "unexpected number of lines (3)"},
+		{"canvas, two lines", KindCanvas, "atelier-demo\ncanvas-guernica", [3]string{}, "unexpected number of lines (2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atelier, artist, canvas := tree(t)
+			dir := map[Kind]string{KindAtelier: atelier, KindArtist: artist, KindCanvas: canvas}[tt.kind]
+			writeMarker(t, dir, tt.kind, tt.content)
+
+			m, err := Read(dir, tt.kind)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Read = %+v, %v; want error %q", m, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if got := [3]string{m.Atelier, m.Artist, m.Canvas}; got != tt.want {
+				t.Errorf("Read = %q, want %q", got, tt.want)
+			}
+			if !m.Legacy() || m.Kind != tt.kind || m.SchemaVersion != SchemaVersion {
+				t.Errorf("Read = legacy %v, kind %s, schema %d", m.Legacy(), m.Kind, m.SchemaVersion)
+			}
+			if m.CreatedAt.IsZero() {
+				t.Error("legacy marker has no creation time")
+			}
+		})
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	_, _, canvas := tree(t)
+	m := New(KindCanvas, "atelier-demo", "artist-picasso", "canvas-guernica")
+	m.Template = "go-service"
+	m.TemplateVersion = "1.2.0"
+	m.Tags = []string{"cubism", "1937"}
+	m.Remote = &Remote{Name: "origin", URL: "git@github.com:acme/canvas-guernica.git", Provider: "github"}
+	m.Params = map[string]string{"language": "go"}
+	m.Context = map[string]map[string]string{"AGENTS.md": {"overview": "5d41402a"}}
+	if err := Write(canvas, m); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := Read(canvas, KindCanvas)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("Read after Write = %+v, want %+v", got, m)
+	}
+	if kind, ok := Detect(canvas); !ok || kind != KindCanvas {
+		t.Errorf("Detect = %s, %v", kind, ok)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"newer schema", `{"schema_version": 99, "kind": "canvas", "atelier": "atelier-demo"}`, "uses schema version 99"},
+		{"other kind", `{"schema_version": 1, "kind": "artist", "atelier": "atelier-demo"}`, "describes a artist"},
+		{"invalid JSON", `{"schema_version": 1,`, "invalid .canvas file"},
+	}
+	for _, tt := range tests {
+		_, _, canvas := tree(t)
+		writeMarker(t, canvas, KindCanvas, tt.content)
+		if _, err := Read(canvas, KindCanvas); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Read = %v, want error %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := Read(t.TempDir(), KindCanvas); err == nil {
+		t.Error("Read without a marker file succeeded")
+	}
+}
+
+func TestMigrateTree(t *testing.T) {
+	atelier, artist, canvas := tree(t)
+	current := filepath.Join(artist, "canvas-dora")
+	if err := os.Mkdir(current, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeMarker(t, atelier, KindAtelier, "atelier-demo\n")
+	writeMarker(t, artist, KindArtist, "picasso\n")
+	writeMarker(t, canvas, KindCanvas, "atelier-demo\nartist-picasso\ncanvas-guernica\n")
+	dora := New(KindCanvas, "atelier-demo", "artist-picasso", "canvas-dora")
+	dora.CreatedAt = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := Write(current, dora); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(Path(current, KindCanvas))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := MigrateTree(atelier)
+	if err != nil {
+		t.Fatalf("MigrateTree: %v", err)
+	}
+	want := []string{Path(atelier, KindAtelier), Path(artist, KindArtist), Path(canvas, KindCanvas)}
+	if !slices.Equal(migrated, want) {
+		t.Errorf("MigrateTree rewrote %v, want %v", migrated, want)
+	}
+	for _, level := range []struct {
+		dir  string
+		kind Kind
+		want [3]string
+	}{
+		{atelier, KindAtelier, [3]string{"atelier-demo"}},
+		{artist, KindArtist, [3]string{"atelier-demo", "artist-picasso"}},
+		{canvas, KindCanvas, [3]string{"atelier-demo", "artist-picasso", "canvas-guernica"}},
+	} {
+		m, err := Read(level.dir, level.kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Legacy() || [3]string{m.Atelier, m.Artist, m.Canvas} != level.want {
+			t.Errorf("%s after migration: legacy %v, names %q", level.kind.FileName(), m.Legacy(), [3]string{m.Atelier, m.Artist, m.Canvas})
+		}
+	}
+	if after, _ := os.ReadFile(Path(current, KindCanvas)); string(after) != string(before) {
+		t.Errorf("MigrateTree rewrote a current marker:\n%s", after)
+	}
+
+	if migrated, err := MigrateTree(atelier); err != nil || len(migrated) != 0 {
+		t.Errorf("second MigrateTree = %v, %v; want nothing to migrate", migrated, err)
+	}
+	if root, err := FindRoot(canvas); err != nil || root != atelier {
+		t.Errorf("FindRoot(canvas) = %q, %v; want %s", root, err, atelier)
+	}
+}
@@ -0,0 +1,251 @@
+// Package plugin discovers and runs external commands: every executable named atelier-<name> in
+// the plugins directory of the atelier or on the PATH becomes the command 'atelier <name>'.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+const (
+	// Prefix is the file name prefix of plugin executables.
+	Prefix = "atelier-"
+	// Dir is the plugins directory of an atelier, relative to its root.
+	Dir = "plugins"
+)
+
+// reserved are names that must not become plugins: the CLI's own binary is atelier-cli.
+var reserved = map[string]bool{"cli": true}
+
+// Plugin is an executable providing the command 'atelier <Name>'.
+type Plugin struct {
+	Name   string
+	Path   string
+	Source string // "atelier" for the atelier's plugins directory, "PATH" otherwise
+	// Shadowed lists the paths of executables with the same name that Path takes precedence over.
+	Shadowed []string
+}
+
+// Discover returns the plugins in the plugins directory of the atelier at atelierPath, which take
+// precedence, and on the PATH, in PATH order, sorted by name. atelierPath may be empty.
+func Discover(atelierPath string) []*Plugin {
+	byName := make(map[string]*Plugin)
+	add := func(dir, source string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			if p, found := byName[name]; found {
+				// A directory listed twice on the PATH shadows nothing
+				if p.Path != path && !slices.Contains(p.Shadowed, path) {
+					p.Shadowed = append(p.Shadowed, path)
+				}
+				continue
+			}
+			byName[name] = &Plugin{Name: name, Path: path, Source: source}
+		}
+	}
+
+	if atelierPath != "" {
+		add(filepath.Join(atelierPath, Dir), "atelier")
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		add(dir, "PATH")
+	}
+
+	plugins := make([]*Plugin, 0, len(byName))
+	for _, p := range byName {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// pluginName returns the command name of the executable file name, e.g. "lint" for "atelier-lint".
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, Prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || reserved[name] || strings.HasPrefix(name, "-") {
+		return "", false
+	}
+	return name, true
+}
+
+// isExecutable reports whether path is a regular file the current user may execute.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return info.Mode().Perm()&0111 != 0
+}
+
+// Context is where in an atelier a plugin is run from.
+type Context struct {
+	AtelierPath string
+	ArtistPath  string // Empty outside an artist
+	CanvasPath  string // Empty outside a canvas
+}
+
+// ResolveContext returns the atelier, artist and canvas containing dir. Outside an atelier the
+// context is empty.
+func ResolveContext(dir string) Context {
+	var ctx Context
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ctx
+	}
+	for {
+		switch {
+		case ctx.CanvasPath == "" && ctx.ArtistPath == "" && marker.Exists(dir, marker.KindCanvas):
+			ctx.CanvasPath = dir
+		case ctx.ArtistPath == "" && marker.Exists(dir, marker.KindArtist):
+			ctx.ArtistPath = dir
+		case marker.Exists(dir, marker.KindAtelier):
+			ctx.AtelierPath = dir
+			return ctx
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Context{}
+		}
+		dir = parent
+	}
+}
+
+// envVars are the variables Env sets; inherited values are removed so nested plugins see their own context.
+var envVars = []string{"ATELIER_CLI", "ATELIER_ROOT", "ATELIER_NAME", "ATELIER_ARTIST", "ATELIER_ARTIST_PATH", "ATELIER_CANVAS", "ATELIER_CANVAS_PATH"}
+
+// Env returns the environment of a plugin run in ctx: the current environment plus
+//
+//	ATELIER_CLI          path of the running CLI, to call back into it
+//	ATELIER_ROOT         atelier root directory, and ATELIER_NAME its name, e.g. atelier-demo
+//	ATELIER_ARTIST_PATH  artist directory, and ATELIER_ARTIST its name, e.g. artist-picasso
+//	ATELIER_CANVAS_PATH  canvas directory, and ATELIER_CANVAS its name, e.g. canvas-guernica
+//
+// Variables for levels the context is not inside of are left unset.
+func Env(ctx Context) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(envVars, key) {
+			env = append(env, kv)
+		}
+	}
+	if self, err := os.Executable(); err == nil {
+		env = append(env, "ATELIER_CLI="+self)
+	}
+	for _, level := range []struct{ path, pathVar, nameVar string }{
+		{ctx.AtelierPath, "ATELIER_ROOT", "ATELIER_NAME"},
+		{ctx.ArtistPath, "ATELIER_ARTIST_PATH", "ATELIER_ARTIST"},
+		{ctx.CanvasPath, "ATELIER_CANVAS_PATH", "ATELIER_CANVAS"},
+	} {
+		if level.path != "" {
+			env = append(env, level.pathVar+"="+level.path, level.nameVar+"="+filepath.Base(level.path))
+		}
+	}
+	return env
+}
+
+// Run runs the plugin with args and the environment of ctx, connected to the standard streams.
+// A plugin exiting with a non-zero status is reported as an *exec.ExitError.
+func (p *Plugin) Run(ctx Context, args []string) error {
+	c := exec.Command(p.Path, args...)
+	c.Env = Env(ctx)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+// Install installs the plugin at source into the plugins directory of the atelier at atelierPath
+// as atelier-<name> and returns it. source is an executable file, or a directory holding a
+// Go main package, which is built. An empty name is derived from the file or directory name.
+func Install(atelierPath, source, name string) (*Plugin, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("plugin source %s: %w", source, err)
+	}
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Base(source), Prefix)
+		if !info.IsDir() {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+	}
+	if _, ok := pluginName(Prefix + name); !ok || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid plugin name %q", name)
+	}
+
+	dir := filepath.Join(atelierPath, Dir)
+	if err := fs.CreateDir(dir); err != nil {
+		return nil, err
+	}
+	target := filepath.Join(dir, Prefix+name)
+	if runtime.GOOS == "windows" {
+		// Windows finds executables by extension
+		if info.IsDir() {
+			target += ".exe"
+		} else {
+			target += filepath.Ext(source)
+		}
+	}
+
+	if info.IsDir() {
+		if _, err := os.Stat(filepath.Join(source, "go.mod")); errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s is a directory without a go.mod; pass an executable or a Go module", source)
+		}
+		build := exec.Command("go", "build", "-o", target, ".")
+		build.Dir = source
+		if out, err := build.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to build %s: %s: %w", source, strings.TrimSpace(string(out)), err)
+		}
+		return &Plugin{Name: name, Path: target, Source: "atelier"}, nil
+	}
+
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	if err := os.WriteFile(target, content, 0755); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(target, 0755); err != nil {
+		return nil, err
+	}
+	return &Plugin{Name: name, Path: target, Source: "atelier"}, nil
+}
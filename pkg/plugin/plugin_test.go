@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+func TestPluginName(t *testing.T) {
+	tests := []struct {
+		file string
+		name string
+		ok   bool
+	}{
+		{"atelier-lint", "lint", true},
+		{"atelier-release-notes", "release-notes", true},
+		{"atelier-cli", "", false},
+		{"atelier-", "", false},
+		{"atelier--help", "", false},
+		{"lint", "", false},
+		{"atelierlint", "", false},
+	}
+	for _, tt := range tests {
+		if name, ok := pluginName(tt.file); name != tt.name || ok != tt.ok {
+			t.Errorf("pluginName(%q) = %q, %v; want %q, %v", tt.file, name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+// writeFiles creates the named files in dir with mode; a name ending in / becomes a directory.
+func writeFiles(t *testing.T, dir string, mode os.FileMode, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		var err error
+		if strings.HasSuffix(name, "/") {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = os.WriteFile(path, []byte("#!/bin/sh\n"), mode)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by extension on Windows")
+	}
+	base := t.TempDir()
+	atelier := filepath.Join(base, "atelier-demo")
+	first, second := filepath.Join(base, "bin"), filepath.Join(base, "local")
+	writeFiles(t, filepath.Join(atelier, Dir), 0755, "atelier-lint", "atelier-cli")
+	writeFiles(t, filepath.Join(atelier, Dir), 0644, "atelier-notes")
+	writeFiles(t, first, 0755, "atelier-lint", "atelier-deploy", "atelier-cli", "atelier-dir/", "lint")
+	writeFiles(t, second, 0755, "atelier-deploy", "atelier-notes")
+	// A directory listed twice shadows nothing
+	t.Setenv("PATH", strings.Join([]string{first, second, first}, string(os.PathListSeparator)))
+
+	tests := []struct {
+		name        string
+		atelierPath string
+		want        []Plugin
+	}{
+		{"atelier", atelier, []Plugin{
+			{Name: "deploy", Path: filepath.Join(first, "atelier-deploy"), Source: "PATH", Shadowed: []string{filepath.Join(second, "atelier-deploy")}},
+			{Name: "lint", Path: filepath.Join(atelier, Dir, "atelier-lint"), Source: "atelier", Shadowed: []string{filepath.Join(first, "atelier-lint")}},
+			{Name: "notes", Path: filepath.Join(second, "atelier-notes"), Source: "PATH"},
+		}},
+		{"outside an atelier", "", []Plugin{
+			{Name: "deploy", Path: filepath.Join(first, "atelier-deploy"), Source: "PATH", Shadowed: []string{filepath.Join(second, "atelier-deploy")}},
+			{Name: "lint", Path: filepath.Join(first, "atelier-lint"), Source: "PATH"},
+			{Name: "notes", Path: filepath.Join(second, "atelier-notes"), Source: "PATH"},
+		}},
+	}
+	for _, tt := range tests {
+		var got []Plugin
+		for _, p := range Discover(tt.atelierPath) {
+			got = append(got, *p)
+		}
+		if !slices.EqualFunc(got, tt.want, func(a, b Plugin) bool {
+			return a.Name == b.Name && a.Path == b.Path && a.Source == b.Source && slices.Equal(a.Shadowed, b.Shadowed)
+		}) {
+			t.Errorf("%s: Discover = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveContext(t *testing.T) {
+	base := t.TempDir()
+	atelier := filepath.Join(base, "atelier-demo")
+	artist := filepath.Join(atelier, "artist-picasso")
+	canvas := filepath.Join(artist, "canvas-guernica")
+	for _, m := range []struct {
+		dir  string
+		kind marker.Kind
+	}{{atelier, marker.KindAtelier}, {artist, marker.KindArtist}, {canvas, marker.KindCanvas}} {
+		if err := os.MkdirAll(filepath.Join(m.dir, "docs"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := marker.Write(m.dir, marker.New(m.kind, "atelier-demo", "artist-picasso", "canvas-guernica")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir  string
+		want Context
+	}{
+		{atelier, Context{AtelierPath: atelier}},
+		{filepath.Join(atelier, "docs"), Context{AtelierPath: atelier}},
+		{artist, Context{AtelierPath: atelier, ArtistPath: artist}},
+		{filepath.Join(artist, "docs"), Context{AtelierPath: atelier, ArtistPath: artist}},
+		{canvas, Context{AtelierPath: atelier, ArtistPath: artist, CanvasPath: canvas}},
+		{filepath.Join(canvas, "docs"), Context{AtelierPath: atelier, ArtistPath: artist, CanvasPath: canvas}},
+		{base, Context{}},
+	}
+	for _, tt := range tests {
+		if got := ResolveContext(tt.dir); got != tt.want {
+			t.Errorf("ResolveContext(%s) = %+v, want %+v", tt.dir, got, tt.want)
+		}
+	}
+
+	// A canvas outside any atelier has no context at all
+	stray := filepath.Join(base, "canvas-stray")
+	if err := os.Mkdir(stray, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := marker.Write(stray, marker.New(marker.KindCanvas, "atelier-demo", "artist-picasso", "canvas-stray")); err != nil {
+		t.Fatal(err)
+	}
+	if got := ResolveContext(stray); got != (Context{}) {
+		t.Errorf("ResolveContext of a canvas outside an atelier = %+v", got)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	for _, key := range envVars {
+		t.Setenv(key, "inherited")
+	}
+	t.Setenv("ATELIER_TOKEN", "kept")
+	atelier := filepath.Join("/srv", "atelier-demo")
+	artist := filepath.Join(atelier, "artist-picasso")
+	canvas := filepath.Join(artist, "canvas-guernica")
+
+	tests := []struct {
+		ctx  Context
+		want map[string]string
+	}{
+		{Context{}, map[string]string{}},
+		{Context{AtelierPath: atelier}, map[string]string{
+			"ATELIER_ROOT": atelier, "ATELIER_NAME": "atelier-demo",
+		}},
+		{Context{AtelierPath: atelier, ArtistPath: artist}, map[string]string{
+			"ATELIER_ROOT": atelier, "ATELIER_NAME": "atelier-demo",
+			"ATELIER_ARTIST_PATH": artist, "ATELIER_ARTIST": "artist-picasso",
+		}},
+		{Context{AtelierPath: atelier, ArtistPath: artist, CanvasPath: canvas}, map[string]string{
+			"ATELIER_ROOT": atelier, "ATELIER_NAME": "atelier-demo",
+			"ATELIER_ARTIST_PATH": artist, "ATELIER_ARTIST": "artist-picasso",
+			"ATELIER_CANVAS_PATH": canvas, "ATELIER_CANVAS": "canvas-guernica",
+		}},
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		got := map[string][]string{}
+		for _, kv := range Env(tt.ctx) {
+			key, value, _ := strings.Cut(kv, "=")
+			got[key] = append(got[key], value)
+		}
+		tt.want["ATELIER_CLI"] = self
+		tt.want["ATELIER_TOKEN"] = "kept"
+		for _, key := range append(envVars, "ATELIER_TOKEN") {
+			want, set := tt.want[key]
+			values := got[key]
+			switch {
+			case !set && len(values) != 0:
+				t.Errorf("Env(%+v) sets %s=%v, want it cleared", tt.ctx, key, values)
+			case set && !slices.Equal(values, []string{want}):
+				t.Errorf("Env(%+v) sets %s=%v, want %s", tt.ctx, key, values, want)
+			}
+		}
+	}
+}
@@ -15,16 +15,16 @@
 #   * Atelier repo: 1 commit (atelier test)
 #
 # Non-interactive mode:
-#   We disable confirmation prompts and enable auto-commit so this script can run unattended.
-#   You can override by exporting CONFIRM_PUSH_DEFAULT=true or AUTO_COMMIT_DEFAULT=false before running.
-export CONFIRM_PUSH_DEFAULT=${CONFIRM_PUSH_DEFAULT:-false}
+#   The engine never prompts; auto-commit is enabled so this script can run unattended.
+#   You can override by exporting AUTO_COMMIT_DEFAULT=false before running.
 export AUTO_COMMIT_DEFAULT=${AUTO_COMMIT_DEFAULT:-true}
 export AUTO_COMMIT_MESSAGE=${AUTO_COMMIT_MESSAGE:-"engine(e2e): auto-commit uncommitted changes"}
 
 set -euo pipefail
 
-ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/../.." && pwd)"
-ENGINE_DIR="$ROOT_DIR/util/git"
+# Usage: e2e-git.sh [atelier-root] (defaults to the current directory)
+ROOT_DIR="$(cd "${1:-.}" && pwd)"
+ATELIER_CLI="${ATELIER_CLI:-atelier-cli}"
 
 CANVAS_DIR="$ROOT_DIR/artist-van-gogh/canvas-sunflowers"
 ARTIST_DIR="$ROOT_DIR/artist-van-gogh"
@@ -74,17 +74,17 @@ append_readme_only() {
 
 push_canvas_with_engine() {
   local canvas_dir="$1"
-  ( cd "$canvas_dir" && "$ENGINE_DIR/push-engine.sh" )
+  ( cd "$canvas_dir" && "$ATELIER_CLI" canvas push )
 }
 
 push_artist_with_engine() {
   local artist_dir="$1"
-  ( cd "$artist_dir" && "$ENGINE_DIR/push-engine.sh" )
+  ( cd "$artist_dir" && "$ATELIER_CLI" artist push )
 }
 
 push_atelier_with_engine() {
   # Defaults now recurse into all artists and canvases
-  ( cd "$ROOT_DIR" && "$ENGINE_DIR/push-engine.sh" )
+  ( cd "$ROOT_DIR" && "$ATELIER_CLI" push )
 }
 
 count_delta() {
@@ -182,6 +182,7 @@ test_atelier_root() {
 
 main() {
   require_cmd git
+  require_cmd "$ATELIER_CLI"
   require_cmd awk
   log "Starting E2E Git Engine tests at $(timestamp)"
   log "Root directory: $ROOT_DIR"
@@ -0,0 +1,301 @@
+package pushengine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/guard"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/runner"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+)
+
+// Level identifies where a repository sits in the atelier/artist/canvas hierarchy.
+type Level string
+
+const (
+	LevelAtelier Level = "atelier"
+	LevelArtist  Level = "artist"
+	LevelCanvas  Level = "canvas"
+	LevelUnknown Level = "unknown"
+)
+
+const (
+	// DefaultRemote is the remote every level pushes to.
+	DefaultRemote = "origin"
+	// DefaultCommitMessage is used for the roll-up commit when none is given.
+	DefaultCommitMessage = "engine: auto-commit uncommitted changes"
+)
+
+// ErrNoRemote is returned when a repository has no remote to push to.
+var ErrNoRemote = errors.New("no remote configured")
+
+// Options controls how a push run behaves.
+type Options struct {
+	DryRun        bool      // Report what would happen without committing or pushing
+	Quiet         bool      // Suppress informational output (warnings are still printed)
+	Force         bool      // Push with --force-with-lease
+	AutoCommit    bool      // Stage and commit working tree changes and submodule pointers
+	CommitMessage string    // Message for roll-up commits; DefaultCommitMessage if empty
+	Remote        string    // Remote to push to; DefaultRemote if empty
+	Jobs          int       // Repositories pushed in parallel; 1 pushes them one after another
+	NoGuard       bool      // Skip the secret scan of roll-up changes and unpushed commits
+	Out           io.Writer // Destination for engine logs; os.Stderr if nil
+}
+
+// Failure records a repository that could not be pushed.
+type Failure = runner.Failure
+
+// Result summarises a push run.
+type Result struct {
+	Committed []string  // Repositories that received a roll-up commit
+	Pushed    []string  // Repositories that were pushed
+	Unchanged []string  // Repositories with nothing to push
+	Failed    []Failure // Child repositories that failed; the run continued past them
+}
+
+// DetectLevel determines the hierarchy level of dir from its marker file.
+func DetectLevel(dir string) Level {
+	kind, ok := marker.Detect(dir)
+	if !ok {
+		return LevelUnknown
+	}
+	return Level(kind)
+}
+
+// Push detects the level of dir and rolls up and pushes it together with everything beneath it.
+func Push(dir string, opts Options) (*Result, error) {
+	switch DetectLevel(dir) {
+	case LevelAtelier:
+		return PushAtelier(dir, opts)
+	case LevelArtist:
+		return PushArtist(dir, opts)
+	case LevelCanvas:
+		return PushCanvas(dir, opts)
+	default:
+		return nil, fmt.Errorf("unable to detect atelier/artist/canvas level in %s", dir)
+	}
+}
+
+// PushCanvas commits any working tree changes in a canvas and pushes it.
+func PushCanvas(dir string, opts Options) (*Result, error) {
+	return newEngine(opts).push(dir, marker.KindCanvas)
+}
+
+// PushArtist pushes every canvas of an artist, then makes a single combined artist commit
+// (working tree plus updated canvas pointers) and pushes the artist.
+func PushArtist(dir string, opts Options) (*Result, error) {
+	return newEngine(opts).push(dir, marker.KindArtist)
+}
+
+// PushAtelier pushes every artist (and their canvases), then makes a single combined atelier
+// commit (working tree plus updated artist pointers) and pushes the atelier.
+func PushAtelier(dir string, opts Options) (*Result, error) {
+	return newEngine(opts).push(dir, marker.KindAtelier)
+}
+
+type engine struct {
+	runner.Base // Failed repositories are those that failed or were skipped
+	opts        Options
+	result      *Result
+}
+
+func newEngine(opts Options) *engine {
+	if opts.Remote == "" {
+		opts.Remote = DefaultRemote
+	}
+	if opts.CommitMessage == "" {
+		opts.CommitMessage = DefaultCommitMessage
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stderr
+	}
+	return &engine{Base: runner.Base{Quiet: opts.Quiet}, opts: opts, result: &Result{}}
+}
+
+// push rolls up and pushes dir and everything beneath it. A repository starts only once all of
+// its children are done, so the parent commits the pointers of children that were pushed; sibling
+// subtrees run in parallel. A failing child is recorded and logged but does not stop its siblings.
+// Its ancestors are skipped, as their roll-up would record a commit the remote does not have.
+func (e *engine) push(dir string, level marker.Kind) (*Result, error) {
+	root, err := traverse.Build(dir, level)
+	if err != nil {
+		return e.result, err
+	}
+
+	opts := traverse.Options{Jobs: e.opts.Jobs, Order: traverse.ChildrenFirst, Out: e.opts.Out}
+	results := traverse.Run(root, opts, func(repo *traverse.Repo, out io.Writer) error {
+		if repo.Level != marker.KindCanvas && len(repo.Children) == 0 {
+			e.Infof(out, "No %s* directories found in %s", childKind(repo.Level).Prefix(), repo.Name())
+		}
+		err := e.skipped(repo)
+		if err == nil {
+			err = e.rollUp(out, repo.Path, Level(repo.Level))
+		}
+		if err != nil {
+			e.Fail(repo.Path)
+			if repo != root {
+				e.Warnf(out, "Failed to push %s: %v", repo.Name(), err)
+			}
+		}
+		return err
+	})
+
+	// Report repositories in traversal order, however the workers finished.
+	order := make(map[string]int, len(results))
+	for i, r := range results {
+		order[r.Repo.Path] = i
+		if r.Err != nil && r.Repo != root {
+			e.result.Failed = append(e.result.Failed, Failure{Repo: r.Repo.Path, Err: r.Err})
+		}
+	}
+	for _, list := range [][]string{e.result.Committed, e.result.Pushed, e.result.Unchanged} {
+		sort.SliceStable(list, func(i, j int) bool { return order[list[i]] < order[list[j]] })
+	}
+	return e.result, results[len(results)-1].Err
+}
+
+// skipped returns an error if a child of repo failed, so repo must not record and push its pointer.
+func (e *engine) skipped(repo *traverse.Repo) error {
+	for _, child := range repo.Children {
+		if e.Failed(child.Path) {
+			return fmt.Errorf("%s skipped because %s was not pushed", repo.Name(), child.Name())
+		}
+	}
+	return nil
+}
+
+// childKind returns the level of the repositories directly beneath level.
+func childKind(level marker.Kind) marker.Kind {
+	if level == marker.KindAtelier {
+		return marker.KindArtist
+	}
+	return marker.KindCanvas
+}
+
+// rollUp stages working tree changes and updated submodule pointers in dir,
+// creates a single combined commit if anything is staged, and pushes.
+func (e *engine) rollUp(out io.Writer, dir string, level Level) error {
+	name := filepath.Base(dir)
+	e.Infof(out, "Processing %s: %s", level, name)
+
+	if _, err := gitutil.GitDir(dir); err != nil {
+		return fmt.Errorf("%s is not a git repository: %w", name, err)
+	}
+	if gitutil.RemoteURL(dir, e.opts.Remote) == "" {
+		return fmt.Errorf("%s: %w (remote %q)", name, ErrNoRemote, e.opts.Remote)
+	}
+
+	committed, err := e.commitChanges(out, dir, level)
+	if err != nil {
+		return err
+	}
+
+	branch, err := gitutil.CurrentBranch(dir)
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return fmt.Errorf("%s has a detached HEAD; check out a branch before pushing", name)
+	}
+
+	ahead, _, hasUpstream, err := gitutil.AheadBehind(dir)
+	if err != nil {
+		return err
+	}
+	if hasUpstream && ahead == 0 && !committed {
+		e.Infof(out, "No unpushed commits in %s", name)
+		e.Record(&e.result.Unchanged, dir)
+		return nil
+	}
+
+	if !e.opts.NoGuard {
+		findings, err := guard.ScanUnpushed(dir, e.opts.Remote)
+		if err != nil {
+			return fmt.Errorf("failed to scan %s for secrets: %w", name, err)
+		}
+		if len(findings) > 0 {
+			return &guard.FindingsError{Repo: name, Findings: findings}
+		}
+	}
+
+	if e.opts.DryRun {
+		e.Infof(out, "[DRY RUN] Would push %s: %s (%s/%s)", level, name, e.opts.Remote, branch)
+		return nil
+	}
+
+	e.Infof(out, "Pushing %s: %s", level, name)
+	if err := gitutil.Push(dir, e.opts.Remote, branch, !hasUpstream, e.opts.Force); err != nil {
+		return fmt.Errorf("failed to push %s: %w", name, err)
+	}
+	e.Record(&e.result.Pushed, dir)
+	return nil
+}
+
+// commitChanges creates the roll-up commit for dir. It reports whether a commit was made
+// (or, in dry-run mode, would be made).
+func (e *engine) commitChanges(out io.Writer, dir string, level Level) (bool, error) {
+	name := filepath.Base(dir)
+	dirty, err := gitutil.HasUncommittedChanges(dir)
+	if err != nil {
+		return false, err
+	}
+	if !dirty {
+		return false, nil
+	}
+
+	if !e.opts.AutoCommit && !e.opts.DryRun {
+		return false, fmt.Errorf("%s has uncommitted changes; commit or stash them first", name)
+	}
+	// Scan before staging, so findings leave the index as the user left it.
+	if !e.opts.NoGuard {
+		findings, err := guard.ScanWorkingTree(dir)
+		if err != nil {
+			return false, fmt.Errorf("failed to scan %s for secrets: %w", name, err)
+		}
+		if len(findings) > 0 {
+			return false, &guard.FindingsError{Repo: name, Findings: findings}
+		}
+	}
+	if e.opts.DryRun {
+		e.Warnf(out, "%s has uncommitted changes (dry-run; not committing)", name)
+		return e.opts.AutoCommit, nil
+	}
+
+	var modified []string
+	if level != LevelCanvas {
+		if modified, err = gitutil.ModifiedSubmodules(dir); err != nil {
+			return false, err
+		}
+	}
+
+	e.Infof(out, "Staging working tree changes in %s", name)
+	if err := gitutil.Add(dir); err != nil {
+		return false, err
+	}
+	if len(modified) > 0 {
+		e.Infof(out, "Staging updated submodule pointers in %s: %s", name, strings.Join(modified, ", "))
+		if err := gitutil.AddPaths(dir, modified...); err != nil {
+			return false, err
+		}
+	}
+
+	staged, err := gitutil.HasStagedChanges(dir)
+	if err != nil {
+		return false, err
+	}
+	if !staged {
+		return false, nil
+	}
+	if err := gitutil.Commit(dir, e.opts.CommitMessage); err != nil {
+		return false, err
+	}
+	e.Record(&e.result.Committed, dir)
+	return true, nil
+}
@@ -0,0 +1,126 @@
+package pushengine
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+	"github.com/frquxl/go-atelier/pkg/guard"
+)
+
+func TestPushAtelier(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-dora", "artist-monet/canvas-lilies")
+	artist := filepath.Join(w.Root, "artist-picasso")
+	canvas := filepath.Join(artist, "canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(canvas, "notes.md"), "sketch\n")
+
+	for _, jobs := range []int{1, 4} {
+		result, err := Push(w.Root, Options{AutoCommit: true, Jobs: jobs, Out: io.Discard})
+		if err != nil {
+			t.Fatalf("Push with %d jobs: %v", jobs, err)
+		}
+		if len(result.Failed) > 0 {
+			t.Fatalf("Push with %d jobs failed for %v", jobs, result.Failed)
+		}
+	}
+
+	for _, repo := range []string{canvas, artist, w.Root} {
+		if local, remote := gittest.Head(t, repo), w.RemoteHead(t, filepath.Base(repo)); local != remote {
+			t.Errorf("%s is at %s locally but %s on its remote", filepath.Base(repo), local, remote)
+		}
+	}
+	if pointer := gittest.Run(t, artist, "rev-parse", "HEAD:canvas-guernica"); pointer != gittest.Head(t, canvas) {
+		t.Errorf("artist records canvas-guernica at %s, want %s", pointer, gittest.Head(t, canvas))
+	}
+	if status := gittest.Run(t, w.Root, "status", "--porcelain", "--ignore-submodules=none"); status != "" {
+		t.Errorf("atelier not clean after push:\n%s", status)
+	}
+}
+
+func TestPushDryRun(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	canvas := filepath.Join(w.Root, "artist-picasso", "canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(canvas, "notes.md"), "sketch\n")
+	before := gittest.Head(t, canvas)
+
+	result, err := Push(w.Root, Options{AutoCommit: true, DryRun: true, Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if len(result.Committed)+len(result.Pushed) > 0 {
+		t.Errorf("dry run committed %v and pushed %v", result.Committed, result.Pushed)
+	}
+	if gittest.Head(t, canvas) != before || w.RemoteHead(t, "canvas-guernica") != before {
+		t.Error("dry run moved the canvas or its remote")
+	}
+}
+
+func TestPushWithoutAutoCommit(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	canvas := filepath.Join(w.Root, "artist-picasso", "canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(canvas, "notes.md"), "sketch\n")
+
+	_, err := PushCanvas(canvas, Options{Out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Errorf("PushCanvas = %v, want an error about uncommitted changes", err)
+	}
+}
+
+func TestPushSkipsParentsOfFailedChildren(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-monet/canvas-lilies")
+	picasso := filepath.Join(w.Root, "artist-picasso")
+	guernica := filepath.Join(picasso, "canvas-guernica")
+	lilies := filepath.Join(w.Root, "artist-monet", "canvas-lilies")
+	gittest.WriteFile(t, filepath.Join(guernica, "notes.md"), "sketch\n")
+	gittest.WriteFile(t, filepath.Join(lilies, "notes.md"), "sketch\n")
+	gittest.Run(t, guernica, "remote", "set-url", "origin", filepath.Join(t.TempDir(), "missing.git"))
+	artistBefore, atelierBefore := w.RemoteHead(t, "artist-picasso"), w.RemoteHead(t, "atelier-demo")
+
+	result, err := Push(w.Root, Options{AutoCommit: true, Out: io.Discard})
+	if err == nil {
+		t.Fatal("Push succeeded although a canvas could not be pushed")
+	}
+	var failed []string
+	for _, f := range result.Failed {
+		failed = append(failed, filepath.Base(f.Repo))
+	}
+	if !slices.Equal(failed, []string{"canvas-guernica", "artist-picasso"}) {
+		t.Errorf("failed = %v, want canvas-guernica and artist-picasso", failed)
+	}
+	if w.RemoteHead(t, "artist-picasso") != artistBefore || w.RemoteHead(t, "atelier-demo") != atelierBefore {
+		t.Error("a parent of the failed canvas was pushed")
+	}
+	if gittest.Head(t, picasso) != artistBefore {
+		t.Error("the artist of the failed canvas recorded its pointer")
+	}
+	if gittest.Head(t, lilies) != w.RemoteHead(t, "canvas-lilies") {
+		t.Error("the sibling canvas was not pushed")
+	}
+}
+
+func TestPushStopsAtSecrets(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		w := gittest.New(t, "artist-picasso/canvas-guernica")
+		canvas := filepath.Join(w.Root, "artist-picasso", "canvas-guernica")
+		before := gittest.Head(t, canvas)
+		gittest.WriteFile(t, filepath.Join(canvas, "notes.md"), "staged by the user\n")
+		gittest.Run(t, canvas, "add", "notes.md")
+		gittest.WriteFile(t, filepath.Join(canvas, "config.go"), "const key = \"AKIA"+"ABCDEFGHIJKLMNOP\"\n")
+
+		_, err := PushCanvas(canvas, Options{AutoCommit: true, DryRun: dryRun, Out: io.Discard})
+		var findings *guard.FindingsError
+		if !errors.As(err, &findings) {
+			t.Fatalf("PushCanvas(dry run %v) = %v, want a FindingsError", dryRun, err)
+		}
+		if gittest.Head(t, canvas) != before || w.RemoteHead(t, "canvas-guernica") != before {
+			t.Errorf("dry run %v: the secret was committed or pushed", dryRun)
+		}
+		if staged := gittest.Run(t, canvas, "diff", "--cached", "--name-only"); staged != "notes.md" {
+			t.Errorf("dry run %v: staged changes = %q, want the user's notes.md only", dryRun, staged)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package runner holds what the push and sync engines share while they work through the
+// repositories of an atelier: their log messages, the result lists their parallel workers fill
+// and the repositories that failed.
+package runner
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Failure records a repository that could not be processed.
+type Failure struct {
+	Repo string
+	Err  error
+}
+
+// Base is the state an engine embeds. Its zero value is ready to use, and its methods are safe
+// for use by parallel workers.
+type Base struct {
+	Quiet  bool       // Suppress informational messages (warnings are still printed)
+	mu     sync.Mutex // Guards the result lists and failed
+	failed map[string]bool
+}
+
+// Infof writes an informational message to out unless the engine is quiet.
+func (b *Base) Infof(out io.Writer, format string, args ...any) {
+	if !b.Quiet {
+		fmt.Fprintf(out, "[INFO] "+format+"\n", args...)
+	}
+}
+
+// Warnf writes a warning to out.
+func (b *Base) Warnf(out io.Writer, format string, args ...any) {
+	fmt.Fprintf(out, "[WARN] "+format+"\n", args...)
+}
+
+// Record appends dir to list, one of the engine's result lists.
+func (b *Base) Record(list *[]string, dir string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	*list = append(*list, dir)
+}
+
+// Fail marks the repository at dir as failed.
+func (b *Base) Fail(dir string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed == nil {
+		b.failed = make(map[string]bool)
+	}
+	b.failed[dir] = true
+}
+
+// Failed reports whether the repository at dir was marked as failed.
+func (b *Base) Failed(dir string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failed[dir]
+}
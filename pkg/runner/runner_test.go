@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBase(t *testing.T) {
+	var b Base
+	var list []string
+	var wg sync.WaitGroup
+	for _, dir := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Record(&list, dir)
+			if dir == "b" {
+				b.Fail(dir)
+			}
+		}()
+	}
+	wg.Wait()
+	slices.Sort(list)
+	if !slices.Equal(list, []string{"a", "b", "c"}) {
+		t.Errorf("recorded %v", list)
+	}
+	if !b.Failed("b") || b.Failed("a") {
+		t.Errorf("Failed(b) = %v, Failed(a) = %v", b.Failed("b"), b.Failed("a"))
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	var out strings.Builder
+	b := Base{Quiet: true}
+	b.Infof(&out, "pushing %s", "a")
+	b.Warnf(&out, "skipping %s", "b")
+	if got := out.String(); got != "[WARN] skipping b\n" {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -0,0 +1,209 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+)
+
+// Remote is the remote whose presence is checked for every repository.
+const Remote = "origin"
+
+// RepoStatus describes the state of one repository in the atelier hierarchy.
+type RepoStatus struct {
+	Name           string        `json:"name"`
+	Level          marker.Kind   `json:"level"`
+	Path           string        `json:"path"` // Relative to the atelier root
+	Branch         string        `json:"branch,omitempty"`
+	Detached       bool          `json:"detached"`
+	Head           string        `json:"head,omitempty"`
+	DirtyFiles     []string      `json:"dirty_files,omitempty"` // `git status --porcelain` lines
+	HasUpstream    bool          `json:"has_upstream"`
+	Ahead          int           `json:"ahead"`
+	Behind         int           `json:"behind"`
+	RemoteURL      string        `json:"remote_url,omitempty"`
+	MissingRemote  bool          `json:"missing_remote"`
+	RecordedCommit string        `json:"recorded_commit,omitempty"` // Commit the parent records for this submodule
+	PointerDrift   bool          `json:"pointer_drift"`             // HEAD differs from RecordedCommit
+	Uninitialized  bool          `json:"uninitialized"`             // Recorded by the parent but not checked out
+	Error          string        `json:"error,omitempty"`
+	Children       []*RepoStatus `json:"children,omitempty"`
+}
+
+// Clean reports whether the repository needs no attention.
+func (s *RepoStatus) Clean() bool {
+	return s.Error == "" && !s.Detached && len(s.DirtyFiles) == 0 && s.Ahead == 0 && s.Behind == 0 &&
+		!s.MissingRemote && !s.PointerDrift && !s.Uninitialized
+}
+
+// Collect gathers the status of the atelier at atelierPath, its artists and their canvases,
+// checking up to jobs repositories in parallel.
+func Collect(atelierPath string, jobs int) (*RepoStatus, error) {
+	tree, err := traverse.Build(atelierPath, marker.KindAtelier)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	statuses := make(map[*traverse.Repo]*RepoStatus)
+	traverse.Run(tree, traverse.Options{Jobs: jobs}, func(repo *traverse.Repo, _ io.Writer) error {
+		parentDir := ""
+		if repo.Parent != nil {
+			parentDir = repo.Parent.Path
+		}
+		s := Repo(repo.Path, parentDir, repo.Level)
+		s.Path = relPath(atelierPath, repo.Path)
+		mu.Lock()
+		statuses[repo] = s
+		mu.Unlock()
+		return nil
+	})
+
+	for _, repo := range traverse.Walk(tree, traverse.TopDown) {
+		if repo.Parent != nil {
+			parent := statuses[repo.Parent]
+			parent.Children = append(parent.Children, statuses[repo])
+		}
+	}
+	return statuses[tree], nil
+}
+
+// Repo gathers the status of the single repository at dir. parentDir is the repository that
+// records dir as a submodule; pass an empty string for the atelier root.
+func Repo(dir, parentDir string, level marker.Kind) *RepoStatus {
+	s := &RepoStatus{Name: filepath.Base(dir), Level: level, Path: dir}
+
+	// The empty directory of a submodule that is not checked out would report the parent's state
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil && parentDir != "" {
+		s.Uninitialized = true
+		if s.RecordedCommit, err = gitutil.SubmoduleCommit(parentDir, filepath.Base(dir)); err != nil {
+			s.Error = err.Error()
+		}
+		return s
+	}
+
+	head, err := gitutil.HeadCommit(dir)
+	if err != nil {
+		s.Error = fmt.Sprintf("not a git repository with commits: %v", err)
+		return s
+	}
+	s.Head = head
+
+	if s.Branch, err = gitutil.CurrentBranch(dir); err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	s.Detached = s.Branch == ""
+
+	if s.DirtyFiles, err = gitutil.StatusPorcelain(dir); err != nil {
+		s.Error = err.Error()
+		return s
+	}
+
+	s.RemoteURL = gitutil.RemoteURL(dir, Remote)
+	s.MissingRemote = s.RemoteURL == ""
+
+	if !s.Detached {
+		if s.Ahead, s.Behind, s.HasUpstream, err = gitutil.AheadBehind(dir); err != nil {
+			s.Error = err.Error()
+			return s
+		}
+	}
+
+	if parentDir != "" {
+		recorded, err := gitutil.SubmoduleCommit(parentDir, filepath.Base(dir))
+		if err != nil {
+			s.Error = err.Error()
+			return s
+		}
+		s.RecordedCommit = recorded
+		s.PointerDrift = recorded != head
+	}
+	return s
+}
+
+// PrintTree writes a human-readable tree of the hierarchy rooted at root.
+func PrintTree(w io.Writer, root *RepoStatus) {
+	fmt.Fprintf(w, "%s %s\n", root.Name, summary(root))
+	printDirtyFiles(w, root, "")
+	printChildren(w, root.Children, "")
+}
+
+func printChildren(w io.Writer, children []*RepoStatus, indent string) {
+	for i, child := range children {
+		branch, next := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s %s\n", indent, branch, child.Name, summary(child))
+		printDirtyFiles(w, child, indent+next)
+		printChildren(w, child.Children, indent+next)
+	}
+}
+
+func printDirtyFiles(w io.Writer, s *RepoStatus, indent string) {
+	for _, line := range s.DirtyFiles {
+		fmt.Fprintf(w, "%s      %s\n", indent, line)
+	}
+}
+
+// summary renders the one-line description shown next to a repository name.
+func summary(s *RepoStatus) string {
+	if s.Error != "" {
+		return "error: " + s.Error
+	}
+	if s.Uninitialized {
+		return "not checked out (parent records " + shortSHA(s.RecordedCommit) + ")"
+	}
+
+	ref := "[" + s.Branch + "]"
+	if s.Detached {
+		ref = "[detached @" + shortSHA(s.Head) + "]"
+	}
+
+	var notes []string
+	if n := len(s.DirtyFiles); n > 0 {
+		notes = append(notes, fmt.Sprintf("%d dirty", n))
+	}
+	if s.Ahead > 0 || s.Behind > 0 {
+		notes = append(notes, fmt.Sprintf("ahead %d, behind %d", s.Ahead, s.Behind))
+	}
+	if s.MissingRemote {
+		notes = append(notes, "no remote")
+	} else if !s.Detached && !s.HasUpstream {
+		notes = append(notes, "no upstream")
+	}
+	if s.PointerDrift {
+		if s.RecordedCommit == "" {
+			notes = append(notes, "not recorded in parent")
+		} else {
+			notes = append(notes, "pointer drift (parent records "+shortSHA(s.RecordedCommit)+")")
+		}
+	}
+	if len(notes) == 0 {
+		notes = append(notes, "clean")
+	}
+	return ref + " " + strings.Join(notes, ", ")
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func relPath(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
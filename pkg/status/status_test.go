@@ -0,0 +1,89 @@
+package status
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+func TestCollect(t *testing.T) {
+	w := gittest.New(t, "artist-monet/canvas-lilies", "artist-picasso/canvas-guernica", "artist-picasso/canvas-weeping", "artist-vincent/canvas-sunflowers")
+	picasso := filepath.Join(w.Root, "artist-picasso")
+	gittest.WriteFile(t, filepath.Join(picasso, "canvas-guernica", "sketch.md"), "sketch\n")
+	weeping := gittest.Run(t, picasso, "rev-parse", "HEAD:canvas-weeping")
+	gittest.Run(t, picasso, "submodule", "deinit", "--quiet", "--force", "canvas-weeping")
+	lilies := filepath.Join(w.Root, "artist-monet", "canvas-lilies")
+	gittest.Run(t, lilies, "commit", "--quiet", "--allow-empty", "-m", "Water")
+
+	root, err := Collect(w.Root, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]*RepoStatus{}
+	var walk func(s *RepoStatus)
+	walk = func(s *RepoStatus) {
+		statuses[s.Path] = s
+		for _, child := range s.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	tests := []struct {
+		path          string
+		level         marker.Kind
+		dirty         []string
+		ahead         int
+		drift         bool
+		uninitialized bool
+	}{
+		{".", marker.KindAtelier, []string{" M artist-monet", " M artist-picasso"}, 0, false, false},
+		{"artist-monet", marker.KindArtist, []string{" M canvas-lilies"}, 0, false, false},
+		{"artist-monet/canvas-lilies", marker.KindCanvas, nil, 1, true, false},
+		{"artist-picasso", marker.KindArtist, []string{" M canvas-guernica"}, 0, false, false},
+		{"artist-picasso/canvas-guernica", marker.KindCanvas, []string{"?? sketch.md"}, 0, false, false},
+		{"artist-picasso/canvas-weeping", marker.KindCanvas, nil, 0, false, true},
+		{"artist-vincent", marker.KindArtist, nil, 0, false, false},
+		{"artist-vincent/canvas-sunflowers", marker.KindCanvas, nil, 0, false, false},
+	}
+	if len(statuses) != len(tests) {
+		t.Errorf("Collect reported %d repositories, want %d", len(statuses), len(tests))
+	}
+	for _, tt := range tests {
+		s := statuses[tt.path]
+		if s == nil {
+			t.Errorf("%s missing from the status", tt.path)
+			continue
+		}
+		if s.Error != "" || s.Level != tt.level || !slices.Equal(s.DirtyFiles, tt.dirty) || s.Ahead != tt.ahead ||
+			s.Behind != 0 || s.PointerDrift != tt.drift || s.Uninitialized != tt.uninitialized {
+			t.Errorf("%s: %+v", tt.path, *s)
+		}
+		clean := tt.dirty == nil && tt.ahead == 0 && !tt.drift && !tt.uninitialized
+		if s.Clean() != clean {
+			t.Errorf("%s: Clean() = %v, want %v", tt.path, s.Clean(), clean)
+		}
+	}
+	if s := statuses["artist-picasso/canvas-weeping"]; s != nil && (s.RecordedCommit != weeping || s.Head != "" || s.Branch != "") {
+		t.Errorf("canvas that is not checked out reports head %q on %q, recorded %s; want only the recorded %s", s.Head, s.Branch, s.RecordedCommit, weeping)
+	}
+
+	var out bytes.Buffer
+	PrintTree(&out, root)
+	for _, line := range []string{
+		"atelier-demo [main] 2 dirty\n",
+		"└── canvas-lilies [main] ahead 1, behind 0, pointer drift (parent records ",
+		"?? sketch.md\n",
+		"└── canvas-weeping not checked out (parent records " + weeping[:7] + ")\n",
+		"└── canvas-sunflowers [main] clean\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("tree lacks %q:\n%s", line, out.String())
+		}
+	}
+}
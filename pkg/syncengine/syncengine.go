@@ -0,0 +1,388 @@
+// Package syncengine brings an atelier, artist or canvas and everything beneath it up to date with
+// their remotes. It is the inverse of pushengine.
+package syncengine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"github.com/frquxl/go-atelier/pkg/runner"
+	"github.com/frquxl/go-atelier/pkg/traverse"
+)
+
+const (
+	// DefaultRemote is the remote every level is synced from.
+	DefaultRemote = "origin"
+	// DefaultCommitMessage is used for the commits that record updated submodule pointers.
+	DefaultCommitMessage = "sync: update submodule pointers"
+)
+
+// Options controls how a sync run behaves.
+type Options struct {
+	Rebase        bool      // Rebase local commits onto the upstream when a fast-forward is not possible
+	Quiet         bool      // Suppress informational output (warnings are still printed)
+	CommitMessage string    // Message for pointer commits; DefaultCommitMessage if empty
+	Remote        string    // Remote to fetch from; DefaultRemote if empty
+	Jobs          int       // Repositories synced in parallel; 1 syncs them one after another
+	Out           io.Writer // Destination for engine logs; os.Stderr if nil
+}
+
+// Failure records a repository that could not be synced.
+type Failure = runner.Failure
+
+// Result summarises a sync run.
+type Result struct {
+	Updated   []string  // Repositories that moved to another commit or were checked out on a branch
+	UpToDate  []string  // Repositories that were already up to date
+	Committed []string  // Repositories that recorded updated submodule pointers
+	Conflicts []Failure // Repositories that could not be synced; the run continued past them
+}
+
+// Sync detects the level of dir and syncs it together with everything beneath it.
+//
+// Every repository is fetched and its branch fast-forwarded to its upstream (or rebased onto it
+// with Options.Rebase). Repositories on a detached HEAD are first checked out on their branch, and
+// submodules added upstream are initialized. Levels are updated from the top down so that new
+// submodules exist before their turn comes; afterwards every parent commits the pointers of the
+// children that moved, from the bottom up. A repository that cannot be synced is reported in
+// Result.Conflicts and left as it was; the others carry on.
+func Sync(dir string, opts Options) (*Result, error) {
+	level, ok := marker.Detect(dir)
+	if !ok {
+		return nil, fmt.Errorf("unable to detect atelier/artist/canvas level in %s", dir)
+	}
+	e := newEngine(opts)
+	err := e.sync(dir, level)
+	sort.Strings(e.result.Updated)
+	sort.Strings(e.result.UpToDate)
+	sort.Strings(e.result.Committed)
+	return e.result, err
+}
+
+type engine struct {
+	runner.Base // Failed repositories are those in result.Conflicts
+	opts        Options
+	result      *Result
+}
+
+func newEngine(opts Options) *engine {
+	if opts.Remote == "" {
+		opts.Remote = DefaultRemote
+	}
+	if opts.CommitMessage == "" {
+		opts.CommitMessage = DefaultCommitMessage
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stderr
+	}
+	return &engine{Base: runner.Base{Quiet: opts.Quiet}, opts: opts, result: &Result{}}
+}
+
+func (e *engine) sync(dir string, level marker.Kind) error {
+	// Rebuild the tree for every level: updating a parent may check out new submodules.
+	for depth := 0; ; depth++ {
+		tree, err := traverse.Build(dir, level)
+		if err != nil {
+			return err
+		}
+		if !hasDepth(tree, depth) {
+			break
+		}
+		e.run(tree, traverse.TopDown, func(repo *traverse.Repo, out io.Writer) error {
+			if depthOf(repo) != depth {
+				return nil
+			}
+			return e.update(repo, out)
+		})
+	}
+
+	tree, err := traverse.Build(dir, level)
+	if err != nil {
+		return err
+	}
+	e.run(tree, traverse.ChildrenFirst, func(repo *traverse.Repo, out io.Writer) error {
+		if repo.Level == marker.KindCanvas || e.Failed(repo.Path) {
+			return nil
+		}
+		return e.recordPointers(repo.Path, out)
+	})
+	return nil
+}
+
+// run calls fn for every repository in tree and records the ones that fail as conflicts.
+func (e *engine) run(tree *traverse.Repo, order traverse.Order, fn traverse.Func) {
+	opts := traverse.Options{Jobs: e.opts.Jobs, Order: order, Out: e.opts.Out}
+	results := traverse.Run(tree, opts, func(repo *traverse.Repo, out io.Writer) error {
+		err := fn(repo, out)
+		if err != nil {
+			e.Warnf(out, "Could not sync %s: %v", repo.Name(), err)
+		}
+		return err
+	})
+	for _, r := range results {
+		if r.Err != nil {
+			e.result.Conflicts = append(e.result.Conflicts, Failure{Repo: r.Repo.Path, Err: r.Err})
+			e.Fail(r.Repo.Path)
+		}
+	}
+}
+
+// update fetches the repository, checks out its branch if HEAD is detached, integrates the
+// upstream and initializes submodules that are not checked out yet.
+func (e *engine) update(repo *traverse.Repo, out io.Writer) error {
+	dir, name := repo.Path, repo.Name()
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return fmt.Errorf("%s is not checked out; its parent could not be synced", name)
+	}
+	e.Infof(out, "Syncing %s: %s", repo.Level, name)
+
+	before, err := gitutil.HeadCommit(dir)
+	if err != nil {
+		return fmt.Errorf("%s is not a git repository with commits: %w", name, err)
+	}
+
+	if gitutil.RemoteURL(dir, e.opts.Remote) == "" {
+		e.Infof(out, "No remote %q in %s; syncing local state only", e.opts.Remote, name)
+	} else if err := gitutil.Fetch(dir, e.opts.Remote); err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+
+	branch, err := gitutil.CurrentBranch(dir)
+	if err != nil {
+		return err
+	}
+	attached := branch == ""
+	if attached {
+		if branch, err = e.attach(repo, out); err != nil {
+			return err
+		}
+	}
+
+	if err := e.integrate(dir, branch, out); err != nil {
+		return err
+	}
+
+	if repo.Level != marker.KindCanvas {
+		if err := e.initSubmodules(dir, out); err != nil {
+			return err
+		}
+	}
+
+	after, err := gitutil.HeadCommit(dir)
+	if err != nil {
+		return err
+	}
+	if attached || after != before {
+		e.Record(&e.result.Updated, dir)
+	} else {
+		e.Record(&e.result.UpToDate, dir)
+	}
+	return nil
+}
+
+// attach checks out the branch a detached repository belongs to, as long as that does not lose
+// the commit HEAD points at. It returns the branch name.
+func (e *engine) attach(repo *traverse.Repo, out io.Writer) (string, error) {
+	dir, name := repo.Path, repo.Name()
+	head, err := gitutil.HeadCommit(dir)
+	if err != nil {
+		return "", err
+	}
+	branch := e.trackedBranch(repo)
+
+	switch {
+	case !gitutil.RefExists(dir, "refs/heads/"+branch):
+		err = gitutil.RunGitCommand(dir, "checkout", "-q", "-b", branch)
+	case gitutil.IsAncestor(dir, head, branch):
+		err = gitutil.RunGitCommand(dir, "checkout", "-q", branch)
+	case gitutil.IsAncestor(dir, branch, head):
+		if err = gitutil.RunGitCommand(dir, "checkout", "-q", branch); err == nil {
+			err = gitutil.RunGitCommand(dir, "merge", "-q", "--ff-only", head)
+		}
+	default:
+		return "", fmt.Errorf("the detached HEAD of %s has diverged from branch %s; check out a branch manually", name, branch)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check out branch %s in %s: %w", branch, name, err)
+	}
+	e.Infof(out, "Checked out branch %s in %s (was detached at %s)", branch, name, head[:7])
+	return branch, nil
+}
+
+// trackedBranch determines the branch of a detached repository: the branch configured in the
+// parent's .gitmodules, the only local branch at HEAD, the remote's default branch, or main/master.
+func (e *engine) trackedBranch(repo *traverse.Repo) string {
+	dir := repo.Path
+	if repo.Level != marker.KindAtelier {
+		parentDir := filepath.Dir(dir)
+		if submodule, err := gitutil.SubmoduleName(parentDir, repo.Name()); err == nil {
+			out, err := gitutil.RunGitCommandOutput(parentDir, "config", "-f", ".gitmodules", "submodule."+submodule+".branch")
+			if branch := strings.TrimSpace(out); err == nil && branch != "" && branch != "." {
+				return branch
+			}
+		}
+	}
+
+	if out, err := gitutil.RunGitCommandOutput(dir, "branch", "--points-at", "HEAD", "--format=%(refname:short)"); err == nil {
+		if branches := strings.Fields(out); len(branches) == 1 {
+			return branches[0]
+		}
+	}
+
+	if out, err := gitutil.RunGitCommandOutput(dir, "symbolic-ref", "--short", "refs/remotes/"+e.opts.Remote+"/HEAD"); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(out), e.opts.Remote+"/")
+	}
+
+	for _, branch := range []string{"main", "master"} {
+		if gitutil.RefExists(dir, "refs/heads/"+branch) || gitutil.RefExists(dir, "refs/remotes/"+e.opts.Remote+"/"+branch) {
+			return branch
+		}
+	}
+	return "main"
+}
+
+// integrate brings branch up to date with its upstream, setting the upstream to the remote
+// branch of the same name if none is configured yet.
+func (e *engine) integrate(dir, branch string, out io.Writer) error {
+	name := filepath.Base(dir)
+	if _, err := gitutil.RunGitCommandOutput(dir, "rev-parse", "--verify", "--quiet", "@{upstream}"); err != nil {
+		remoteBranch := e.opts.Remote + "/" + branch
+		if !gitutil.RefExists(dir, "refs/remotes/"+remoteBranch) {
+			e.Infof(out, "Branch %s of %s has no upstream; nothing to integrate", branch, name)
+			return nil
+		}
+		if err := gitutil.RunGitCommand(dir, "branch", "-q", "--set-upstream-to="+remoteBranch); err != nil {
+			return err
+		}
+		e.Infof(out, "Branch %s of %s now tracks %s", branch, name, remoteBranch)
+	}
+
+	ahead, behind, _, err := gitutil.AheadBehind(dir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case behind == 0:
+		e.Infof(out, "%s is up to date with its upstream", name)
+	case ahead == 0:
+		if err := gitutil.RunGitCommand(dir, "merge", "-q", "--ff-only", "--autostash", "@{upstream}"); err != nil {
+			return fmt.Errorf("failed to fast-forward %s: %w", name, err)
+		}
+		e.Infof(out, "Fast-forwarded %s by %d commits", name, behind)
+	case !e.opts.Rebase:
+		return fmt.Errorf("%s has diverged from its upstream (ahead %d, behind %d); sync with --rebase or merge it manually", name, ahead, behind)
+	default:
+		if err := rebase(dir); err != nil {
+			gitutil.RunGitCommand(dir, "rebase", "--abort")
+			return fmt.Errorf("rebasing %s onto its upstream failed and was aborted: %w", name, err)
+		}
+		e.Infof(out, "Rebased %d local commits of %s onto %d upstream commits", ahead, name, behind)
+	}
+	return nil
+}
+
+// rebase rebases the current branch of dir onto its upstream. Conflicts that only involve submodule
+// pointers are resolved in favour of the upstream: the pointers are recorded again once the
+// submodules themselves are synced. Any other conflict is returned with the rebase in progress.
+func rebase(dir string) error {
+	err := gitutil.RunGitCommand(dir, "rebase", "-q", "--autostash", "--empty=drop", "@{upstream}")
+	for err != nil {
+		out, lsErr := gitutil.RunGitCommandOutput(dir, "ls-files", "--unmerged")
+		if lsErr != nil || strings.TrimSpace(out) == "" {
+			return err
+		}
+		// Format: "<mode> <sha> <stage>\t<path>"; stage 2 is the upstream side during a rebase
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[0] != "160000" {
+				return err
+			}
+			if fields[2] != "2" {
+				continue
+			}
+			path := strings.SplitN(line, "\t", 2)[1]
+			if updateErr := gitutil.RunGitCommand(dir, "update-index", "--cacheinfo", "160000,"+fields[1]+","+path); updateErr != nil {
+				return updateErr
+			}
+		}
+		err = gitutil.RunGitCommand(dir, "-c", "core.editor=true", "rebase", "--continue")
+	}
+	return nil
+}
+
+// initSubmodules checks out the submodules recorded in dir that are not checked out yet,
+// typically ones a teammate added.
+func (e *engine) initSubmodules(dir string, out io.Writer) error {
+	paths, err := gitutil.UninitializedSubmodules(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := gitutil.InitSubmodule(dir, path); err != nil {
+			return fmt.Errorf("failed to check out new submodule %s in %s: %w", path, filepath.Base(dir), err)
+		}
+		e.Infof(out, "Checked out new submodule %s in %s", path, filepath.Base(dir))
+	}
+	return nil
+}
+
+// recordPointers commits the submodules of dir whose checked-out commit differs from the recorded
+// one, leaving out children that could not be synced.
+func (e *engine) recordPointers(dir string, out io.Writer) error {
+	name := filepath.Base(dir)
+	modified, err := gitutil.ModifiedSubmodules(dir)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, path := range modified {
+		if !e.Failed(filepath.Join(dir, path)) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	staged, err := gitutil.HasStagedChanges(dir)
+	if err != nil {
+		return err
+	}
+	if staged {
+		e.Warnf(out, "%s has staged changes; not recording the updated pointers of %s", name, strings.Join(paths, ", "))
+		return nil
+	}
+	if err := gitutil.AddPaths(dir, paths...); err != nil {
+		return err
+	}
+	if err := gitutil.Commit(dir, e.opts.CommitMessage); err != nil {
+		return err
+	}
+	e.Infof(out, "Recorded updated submodule pointers in %s: %s", name, strings.Join(paths, ", "))
+	e.Record(&e.result.Committed, dir)
+	return nil
+}
+
+// depthOf returns how far repo sits below the root of its tree.
+func depthOf(repo *traverse.Repo) int {
+	depth := 0
+	for p := repo.Parent; p != nil; p = p.Parent {
+		depth++
+	}
+	return depth
+}
+
+func hasDepth(tree *traverse.Repo, depth int) bool {
+	for _, repo := range traverse.Walk(tree, traverse.TopDown) {
+		if depthOf(repo) == depth {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package syncengine
+
+import (
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil/gittest"
+)
+
+// commitUpstream commits a new file to the canvas of a second checkout of the atelier and pushes
+// it. With parents, the artist and the atelier record and push the new pointers too.
+func commitUpstream(t *testing.T, w *gittest.Workspace, file string, parents bool) {
+	t.Helper()
+	clone := w.Clone(t)
+	artist := filepath.Join(clone, "artist-picasso")
+	canvas := filepath.Join(artist, "canvas-guernica")
+	gittest.WriteFile(t, filepath.Join(canvas, file), file+"\n")
+	gittest.Run(t, canvas, "add", file)
+	gittest.Run(t, canvas, "commit", "-m", "Add "+file)
+	gittest.Run(t, canvas, "push", "origin", "main")
+	if parents {
+		for _, dir := range []string{artist, clone} {
+			gittest.Run(t, dir, "commit", "-am", "Update pointers")
+			gittest.Run(t, dir, "push", "origin", "main")
+		}
+	}
+}
+
+func names(paths []string) []string {
+	var names []string
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
+
+func TestSyncFastForward(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica", "artist-picasso/canvas-dora")
+	commitUpstream(t, w, "notes.md", true)
+
+	result, err := Sync(w.Root, Options{Jobs: 4, Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Conflicts) > 0 {
+		t.Fatalf("conflicts: %v", result.Conflicts)
+	}
+	if updated := names(result.Updated); !slices.Equal(updated, []string{"atelier-demo", "artist-picasso", "canvas-guernica"}) {
+		t.Errorf("updated = %v", updated)
+	}
+	if len(result.Committed) > 0 {
+		t.Errorf("recorded pointers the upstream already has in %v", result.Committed)
+	}
+	for _, dir := range result.Updated {
+		if name := filepath.Base(dir); gittest.Head(t, dir) != w.RemoteHead(t, name) {
+			t.Errorf("%s is not at its upstream", name)
+		}
+	}
+}
+
+func TestSyncRecordsPointers(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	commitUpstream(t, w, "notes.md", false)
+	artist := filepath.Join(w.Root, "artist-picasso")
+
+	result, err := Sync(w.Root, Options{Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if committed := names(result.Committed); !slices.Equal(committed, []string{"atelier-demo", "artist-picasso"}) {
+		t.Errorf("committed = %v, want the atelier and the artist", committed)
+	}
+	if pointer := gittest.Run(t, artist, "rev-parse", "HEAD:canvas-guernica"); pointer != w.RemoteHead(t, "canvas-guernica") {
+		t.Errorf("artist records %s, want the synced canvas", pointer)
+	}
+}
+
+func TestSyncAttachesDetachedHead(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	canvas := filepath.Join(w.Root, "artist-picasso", "canvas-guernica")
+	gittest.Run(t, canvas, "checkout", "--quiet", "--detach")
+
+	if _, err := Sync(w.Root, Options{Out: io.Discard}); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if branch := gittest.Run(t, canvas, "branch", "--show-current"); branch != "main" {
+		t.Errorf("canvas is on %q, want main", branch)
+	}
+}
+
+func TestSyncDiverged(t *testing.T) {
+	w := gittest.New(t, "artist-picasso/canvas-guernica")
+	canvas := filepath.Join(w.Root, "artist-picasso", "canvas-guernica")
+	commitUpstream(t, w, "theirs.md", false)
+	gittest.WriteFile(t, filepath.Join(canvas, "ours.md"), "ours\n")
+	gittest.Run(t, canvas, "add", "ours.md")
+	gittest.Run(t, canvas, "commit", "-m", "Add ours.md")
+	ours := gittest.Head(t, canvas)
+
+	result, err := Sync(w.Root, Options{Out: io.Discard})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Conflicts) != 1 || !strings.Contains(result.Conflicts[0].Err.Error(), "diverged") {
+		t.Fatalf("conflicts = %v, want the diverged canvas", result.Conflicts)
+	}
+	if gittest.Head(t, canvas) != ours {
+		t.Error("the diverged canvas was changed")
+	}
+	if len(result.Committed) > 0 {
+		t.Errorf("recorded the pointer of the diverged canvas in %v", result.Committed)
+	}
+
+	result, err = Sync(w.Root, Options{Rebase: true, Out: io.Discard})
+	if err != nil || len(result.Conflicts) > 0 {
+		t.Fatalf("Sync with rebase: %v %v", err, result.Conflicts)
+	}
+	if upstream := w.RemoteHead(t, "canvas-guernica"); gittest.Run(t, canvas, "rev-parse", "HEAD~1") != upstream {
+		t.Error("local commit was not rebased onto the upstream")
+	}
+}
@@ -0,0 +1,233 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// maxExtends bounds the depth of extends chains.
+const maxExtends = 16
+
+// chain returns the pack followed by the packs it extends, nearest first.
+func (p *Pack) chain() ([]*Pack, error) {
+	packs, _, err := p.layers()
+	return packs, err
+}
+
+// layers returns the pack and the packs it extends, nearest first, with their own manifests.
+func (p *Pack) layers() ([]*Pack, []*Manifest, error) {
+	var packs []*Pack
+	var manifests []*Manifest
+	for pack := p; ; {
+		m, err := pack.ownManifest()
+		if err != nil {
+			return nil, nil, err
+		}
+		packs, manifests = append(packs, pack), append(manifests, m)
+		if m.Extends == "" {
+			return packs, manifests, nil
+		}
+		if len(packs) == maxExtends || (m.Extends != pack.Name && slices.ContainsFunc(packs, func(q *Pack) bool { return q.Name == m.Extends })) {
+			names := make([]string, 0, len(packs)+1)
+			for _, q := range packs {
+				names = append(names, q.Name)
+			}
+			return nil, nil, fmt.Errorf("template %s extends itself: %s", p.Name, strings.Join(append(names, m.Extends), " -> "))
+		}
+		base, err := pack.base(m.Extends)
+		if err != nil {
+			return nil, nil, fmt.Errorf("template %s extends %s: %w", pack.Name, m.Extends, err)
+		}
+		pack = base
+	}
+}
+
+// base returns the pack named name that the pack extends. A pack may extend the pack it replaces,
+// e.g. templates/canvas extending the embedded canvas.
+func (p *Pack) base(name string) (*Pack, error) {
+	if name != p.Name {
+		return Find(p.root, name)
+	}
+	switch p.Source {
+	case SourceAtelier:
+		for _, d := range searchPath("") {
+			dir := filepath.Join(d.dir, name)
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				return &Pack{Name: name, Source: d.source, Dir: dir, files: os.DirFS(dir)}, nil
+			}
+		}
+		fallthrough
+	case SourceUser:
+		if Exists(name) {
+			return embedded(name), nil
+		}
+	}
+	return nil, fmt.Errorf("no template %q below %s", name, p.Source)
+}
+
+// Manifest returns the manifest of the pack merged with those of the packs it extends: parameters
+// and fragments add to the base's, a parameter of the same name replacing the base's, files replace
+// the base's list when given, and post-create commands run after the base's.
+func (p *Pack) Manifest() (*Manifest, error) {
+	_, manifests, err := p.layers()
+	if err != nil {
+		return nil, err
+	}
+	merged := &Manifest{}
+	for i := len(manifests) - 1; i >= 0; i-- {
+		m := manifests[i]
+		if m.Description != "" {
+			merged.Description = m.Description
+		}
+		merged.Extends = m.Extends
+		for _, param := range m.Params {
+			if j := slices.IndexFunc(merged.Params, func(q Param) bool { return q.Name == param.Name }); j >= 0 {
+				merged.Params[j] = param
+			} else {
+				merged.Params = append(merged.Params, param)
+			}
+		}
+		for _, name := range m.Fragments {
+			if !slices.Contains(merged.Fragments, name) {
+				merged.Fragments = append(merged.Fragments, name)
+			}
+		}
+		if len(m.Files) > 0 {
+			merged.Files = m.Files
+		}
+		merged.Post = append(merged.Post, m.Post...)
+	}
+	return merged, nil
+}
+
+// fragment returns the files of the fragment named name: a directory in the fragments directory of
+// the atelier's or the user's templates, or embedded in the CLI.
+func (p *Pack) fragment(name string) (iofs.FS, error) {
+	for _, d := range searchPath(p.root) {
+		dir := filepath.Join(d.dir, FragmentsDir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return os.DirFS(dir), nil
+		}
+	}
+	if info, err := iofs.Stat(TemplatesFS, FragmentsDir+"/"+name); err == nil && info.IsDir() {
+		return iofs.Sub(TemplatesFS, FragmentsDir+"/"+name)
+	}
+	return nil, fmt.Errorf("template %s: unknown fragment %q", p.Name, name)
+}
+
+// fragmentNames renders the fragment names of a manifest with v, so a pack can pick a fragment by
+// a parameter, e.g. {{ .Params.language }}. Names that render empty are left out.
+func fragmentNames(names []string, v Values) ([]string, error) {
+	var rendered []string
+	for _, name := range names {
+		name, err := render("fragments", name, v)
+		if err != nil {
+			return nil, err
+		}
+		if name = strings.TrimSpace(name); name == "" || slices.Contains(rendered, name) {
+			continue
+		}
+		if !validFragmentName(name) {
+			return nil, fmt.Errorf("invalid fragment name %q", name)
+		}
+		rendered = append(rendered, name)
+	}
+	return rendered, nil
+}
+
+// content returns the file src of the pack for a repository of kind followed by the pack's
+// fragments of it, each rendered with v if rendered is set. It fails with an iofs.ErrNotExist error
+// when neither the pack nor a fragment has the file.
+func (p *Pack) content(kind marker.Kind, src string, rendered bool, v Values) ([]byte, error) {
+	manifest, err := p.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	var parts []string
+	add := func(name string, content []byte) error {
+		text := string(content)
+		if rendered {
+			var err error
+			if text, err = render(name, text, v); err != nil {
+				return fmt.Errorf("template %s: %w", p.Name, err)
+			}
+		}
+		parts = append(parts, text)
+		return nil
+	}
+
+	fragments, err := fragmentNames(manifest.Fragments, v)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", p.Name, err)
+	}
+	content, fromDefault, err := p.file(kind, src)
+	if err == nil {
+		if err := add(src, content); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, iofs.ErrNotExist) {
+		return nil, err
+	}
+	if err != nil || fromDefault {
+		// The file comes from the default template of kind, so the default's fragments apply too
+		if defaults, err := embedded(Default(kind)).Manifest(); err == nil {
+			names, err := fragmentNames(defaults.Fragments, v)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", Default(kind), err)
+			}
+			for _, name := range slices.Backward(names) {
+				if !slices.Contains(fragments, name) {
+					fragments = append([]string{name}, fragments...)
+				}
+			}
+		}
+	}
+	for _, name := range fragments {
+		files, err := p.fragment(name)
+		if err != nil {
+			return nil, err
+		}
+		fragment, err := iofs.ReadFile(files, src)
+		if errors.Is(err, iofs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fragment %s of %s: %w", src, name, err)
+		}
+		if err := add(name+"/"+src, fragment); err != nil {
+			return nil, err
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return nil, fmt.Errorf("no template %s for a %s in %s: %w", src, kind, p.Name, iofs.ErrNotExist)
+	case 1:
+		return []byte(parts[0]), nil
+	}
+	// Fragments are separated by a blank line
+	for i := range parts {
+		parts[i] = strings.TrimRight(parts[i], "\n")
+	}
+	return []byte(strings.Join(parts, "\n\n") + "\n"), nil
+}
+
+// dirOwner returns the pack of the chain holding src as a directory, if any.
+func (p *Pack) dirOwner(src string) (*Pack, bool) {
+	chain, err := p.chain()
+	if err != nil {
+		return nil, false
+	}
+	for _, pack := range chain {
+		if info, err := iofs.Stat(pack.files, src); err == nil && info.IsDir() {
+			return pack, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,250 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// FileSpec is a file or directory of a template pack that is written to new repositories.
+type FileSpec struct {
+	Src      string `yaml:"src"`                // Path in the pack; a directory is copied with everything below it
+	Dest     string `yaml:"dest,omitempty"`     // Path in the repository, may use the template variables; Src when empty
+	Mode     string `yaml:"mode,omitempty"`     // Octal mode of the files, e.g. "0755"; 0644 when empty
+	Optional bool   `yaml:"optional,omitempty"` // Skipped when neither the pack nor the default template has it
+	Render   *bool  `yaml:"render,omitempty"`   // Whether to render with text/template; true when unset
+}
+
+// DefaultFiles are the files of a template whose manifest lists none.
+var DefaultFiles = []FileSpec{
+	{Src: "README.md"},
+	{Src: "AGENTS.md"},
+	{Src: "Makefile"},
+	{Src: "gitignore", Dest: ".gitignore"},
+	{Src: "geminiignore", Dest: ".geminiignore"},
+}
+
+// rendered reports whether the file is rendered with text/template.
+func (f FileSpec) rendered() bool {
+	return f.Render == nil || *f.Render
+}
+
+// mode returns the permissions the file is written with.
+func (f FileSpec) mode() (os.FileMode, error) {
+	if f.Mode == "" {
+		return 0644, nil
+	}
+	mode, err := strconv.ParseUint(f.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q of %s; expected octal permissions such as \"0755\"", f.Mode, f.Src)
+	}
+	return os.FileMode(mode), nil
+}
+
+// validate checks the paths and mode of the file spec, as far as they do not depend on the values.
+func (f FileSpec) validate() error {
+	if f.Src == "" || !iofs.ValidPath(f.Src) || f.Src == "." || f.Src == ManifestFile {
+		return fmt.Errorf("invalid file %q; expected a path in the pack other than %s", f.Src, ManifestFile)
+	}
+	_, err := f.mode()
+	return err
+}
+
+// files returns the files of the pack, in the order they are written.
+func (m *Manifest) files() []FileSpec {
+	if len(m.Files) == 0 {
+		return DefaultFiles
+	}
+	return m.Files
+}
+
+// destination renders the path the file is written to and checks it stays inside the repository
+// and away from git's and the CLI's own files.
+func (f FileSpec) destination(v Values) (string, error) {
+	dest := f.Dest
+	if dest == "" {
+		dest = f.Src
+	}
+	dest, err := render("destination of "+f.Src, dest, v)
+	if err != nil {
+		return "", err
+	}
+	dest = path.Clean(dest)
+	top, _, _ := strings.Cut(dest, "/")
+	if !iofs.ValidPath(dest) || dest == "." || top == ".git" || isMarkerFile(top) {
+		return "", fmt.Errorf("invalid destination %q of %s", dest, f.Src)
+	}
+	return dest, nil
+}
+
+func isMarkerFile(name string) bool {
+	for _, kind := range marker.Kinds {
+		if name == kind.FileName() {
+			return true
+		}
+	}
+	return false
+}
+
+// Output is a file generated from a template pack.
+type Output struct {
+	Path    string // Path in the repository, with forward slashes
+	Content []byte
+	Mode    os.FileMode
+}
+
+// Generate returns the files of the pack for a repository of kind rendered with v, in the order
+// they are written.
+func (p *Pack) Generate(kind marker.Kind, v Values) ([]Output, error) {
+	manifest, err := p.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	var outputs []Output
+	for _, file := range manifest.files() {
+		dest, err := file.destination(v)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", p.Name, err)
+		}
+		mode, _ := file.mode()
+		if owner, ok := p.dirOwner(file.Src); ok {
+			dir, err := owner.generateDir(file, dest, mode, v)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, dir...)
+			continue
+		}
+		content, err := p.content(kind, file.Src, file.rendered(), v)
+		if errors.Is(err, iofs.ErrNotExist) && file.Optional {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, Output{Path: dest, Content: content, Mode: mode})
+	}
+	return outputs, nil
+}
+
+// generateDir returns the files below the directory of the pack that file names, written to dest.
+func (p *Pack) generateDir(file FileSpec, dest string, mode os.FileMode, v Values) ([]Output, error) {
+	var outputs []Output
+	err := iofs.WalkDir(p.files, file.Src, func(name string, entry iofs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := iofs.ReadFile(p.files, name)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s of %s: %w", name, p.Name, err)
+		}
+		if file.rendered() {
+			text, err := render(name, string(content), v)
+			if err != nil {
+				return fmt.Errorf("template %s: %w", p.Name, err)
+			}
+			content = []byte(text)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, file.Src), "/")
+		outputs = append(outputs, Output{Path: path.Join(dest, rel), Content: content, Mode: mode})
+		return nil
+	})
+	return outputs, err
+}
+
+// PostCommands returns the post-create commands of the pack rendered with v.
+func (p *Pack) PostCommands(v Values) ([]string, error) {
+	manifest, err := p.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	commands := make([]string, 0, len(manifest.Post))
+	for _, command := range manifest.Post {
+		line, err := render("post-create command", command, v)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", p.Name, err)
+		}
+		commands = append(commands, line)
+	}
+	return commands, nil
+}
+
+// Create writes the files of the pack for a repository of kind to basePath, rendered with v, and
+// runs the pack's post-create commands there. It returns the paths to stage for the initial commit;
+// after post-create commands, whose output is not known, that is ".".
+func (p *Pack) Create(basePath string, kind marker.Kind, v Values) ([]string, error) {
+	outputs, err := p.Generate(kind, v)
+	if err != nil {
+		return nil, err
+	}
+	commands, err := p.PostCommands(v)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, out := range outputs {
+		if err := writeFile(filepath.Join(basePath, filepath.FromSlash(out.Path)), out.Content, out.Mode); err != nil {
+			return nil, err
+		}
+		written = append(written, out.Path)
+	}
+	for _, command := range commands {
+		if err := runPost(basePath, command); err != nil {
+			return nil, fmt.Errorf("post-create command of template %s failed: %s: %w", p.Name, command, err)
+		}
+	}
+	if len(commands) > 0 {
+		return []string{"."}, nil
+	}
+	return written, nil
+}
+
+// Content returns what the pack writes to the file dest of a new repository of kind rendered with
+// v, e.g. the Makefile. A file the manifest does not list is looked up in the pack by that name.
+func (p *Pack) Content(kind marker.Kind, dest string, v Values) ([]byte, error) {
+	manifest, err := p.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.files() {
+		if name, err := file.destination(v); err == nil && name == dest {
+			return p.content(kind, file.Src, file.rendered(), v)
+		}
+	}
+	return p.Render(kind, dest, v)
+}
+
+// writeFile writes content to path with mode, creating the parent directories.
+func writeFile(path string, content []byte, mode os.FileMode) error {
+	if err := fs.CreateDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, mode)
+}
+
+// runPost runs a post-create command with the shell in dir, connected to the standard streams.
+func runPost(dir, command string) error {
+	c := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	}
+	c.Dir = dir
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
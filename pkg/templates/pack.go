@@ -0,0 +1,179 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// AtelierDir is the directory at the root of an atelier holding the atelier's own template packs.
+const AtelierDir = "templates"
+
+// FragmentsDir is the directory next to the template packs holding the fragments packs compose;
+// it is not a pack itself.
+const FragmentsDir = "fragments"
+
+// Sources of template packs, in order of precedence.
+const (
+	SourceAtelier  = "atelier"
+	SourceUser     = "user"
+	SourceEmbedded = "embedded"
+)
+
+// defaults are the templates of each kind used when no template is chosen, and for files a pack
+// does not provide.
+var defaults = map[marker.Kind]string{
+	marker.KindAtelier: "atelier",
+	marker.KindArtist:  "artist-default",
+	marker.KindCanvas:  "canvas",
+}
+
+// Default returns the name of the default template of kind.
+func Default(kind marker.Kind) string {
+	return defaults[kind]
+}
+
+// Pack is a named set of template files: a directory in the atelier's templates directory, in the
+// user's template directory, or embedded in the CLI.
+type Pack struct {
+	Name   string
+	Source string // SourceAtelier, SourceUser or SourceEmbedded
+	Dir    string // Directory of the pack on disk; empty for embedded packs
+
+	files iofs.FS
+	root  string // Atelier the pack was found from, for resolving its base and fragments; may be empty
+}
+
+// UserDir returns the directory of the user's template packs: $XDG_CONFIG_HOME/atelier/templates,
+// or ~/.config/atelier/templates.
+func UserDir() (string, error) {
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		config = filepath.Join(home, ".config")
+	}
+	return filepath.Join(config, "atelier", "templates"), nil
+}
+
+// packDir is a directory holding template packs.
+type packDir struct {
+	dir    string
+	source string
+}
+
+// searchPath returns the directories holding template packs on disk, in order of precedence.
+// atelierPath may be empty, e.g. before the atelier exists.
+func searchPath(atelierPath string) []packDir {
+	var dirs []packDir
+	if atelierPath != "" {
+		dirs = append(dirs, packDir{filepath.Join(atelierPath, AtelierDir), SourceAtelier})
+	}
+	if dir, err := UserDir(); err == nil {
+		dirs = append(dirs, packDir{dir, SourceUser})
+	}
+	return dirs
+}
+
+// Find returns the template pack named name, looking in the templates directory of the atelier at
+// atelierPath, then in the user's template directory, then among the embedded packs.
+func Find(atelierPath, name string) (*Pack, error) {
+	if name == "" || name == "." || name == ".." || name == FragmentsDir || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	for _, d := range searchPath(atelierPath) {
+		dir := filepath.Join(d.dir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return &Pack{Name: name, Source: d.source, Dir: dir, files: os.DirFS(dir), root: atelierPath}, nil
+		}
+	}
+	if Exists(name) {
+		pack := embedded(name)
+		pack.root = atelierPath
+		return pack, nil
+	}
+	return nil, fmt.Errorf("unknown template %q; see 'atelier template list'", name)
+}
+
+// List returns the template packs available in the atelier at atelierPath, sorted by name. A pack
+// shadowed by one of the same name with higher precedence is left out.
+func List(atelierPath string) ([]*Pack, error) {
+	seen := make(map[string]bool)
+	var packs []*Pack
+	for _, d := range searchPath(atelierPath) {
+		entries, err := os.ReadDir(d.dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read template directory %s: %w", d.dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Name() != FragmentsDir && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				dir := filepath.Join(d.dir, entry.Name())
+				packs = append(packs, &Pack{Name: entry.Name(), Source: d.source, Dir: dir, files: os.DirFS(dir), root: atelierPath})
+			}
+		}
+	}
+	entries, err := TemplatesFS.ReadDir("assets")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !seen[entry.Name()] {
+			seen[entry.Name()] = true
+			pack := embedded(entry.Name())
+			pack.root = atelierPath
+			packs = append(packs, pack)
+		}
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	return packs, nil
+}
+
+func embedded(name string) *Pack {
+	files, _ := iofs.Sub(TemplatesFS, "assets/"+name)
+	return &Pack{Name: name, Source: SourceEmbedded, files: files}
+}
+
+// file returns the content of the template file name, e.g. "AGENTS.md" or "gitignore", of a
+// pack used for a repository of kind. Files the pack does not provide come from the pack it
+// extends, and finally from the embedded default template of kind; fromDefault reports the latter.
+func (p *Pack) file(kind marker.Kind, name string) (content []byte, fromDefault bool, err error) {
+	chain, err := p.chain()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, pack := range chain {
+		if content, err := pack.read(name); err == nil {
+			return content, false, nil
+		} else if !errors.Is(err, iofs.ErrNotExist) {
+			return nil, false, fmt.Errorf("failed to read template %s of %s: %w", name, pack.Name, err)
+		}
+	}
+	content, err = embedded(Default(kind)).read(name)
+	if err != nil {
+		return nil, false, fmt.Errorf("no template %s for a %s in %s: %w", name, kind, p.Name, err)
+	}
+	return content, true, nil
+}
+
+// read returns the file name of the pack. Packs on disk may name the dotfiles with their dot,
+// e.g. ".gitignore" for "gitignore".
+func (p *Pack) read(name string) ([]byte, error) {
+	content, err := iofs.ReadFile(p.files, name)
+	if errors.Is(err, iofs.ErrNotExist) && p.Dir != "" {
+		if dotted, err := iofs.ReadFile(p.files, "."+name); err == nil {
+			return dotted, nil
+		}
+	}
+	return content, err
+}
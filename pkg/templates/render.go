@@ -0,0 +1,212 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/frquxl/go-atelier/pkg/gitutil"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the file of a template pack that describes it and declares its parameters.
+const ManifestFile = "template.yaml"
+
+// Manifest is the content of a template pack's template.yaml.
+type Manifest struct {
+	Description string     `yaml:"description,omitempty"`
+	Extends     string     `yaml:"extends,omitempty"`   // Pack this one is based on
+	Fragments   []string   `yaml:"fragments,omitempty"` // Fragments appended to the files, e.g. go; may use the template variables
+	Params      []Param    `yaml:"params,omitempty"`
+	Files       []FileSpec `yaml:"files,omitempty"` // Files written to new repositories; DefaultFiles when empty
+	Post        []string   `yaml:"post,omitempty"`  // Shell commands run in a new repository after its files are written
+}
+
+// Param is a value a template asks for when a repository is generated from it. Templates refer to
+// it as {{ .Params.<name> }}.
+type Param struct {
+	Name     string   `yaml:"name"`
+	Prompt   string   `yaml:"prompt,omitempty"`   // Question asked interactively; the name when empty
+	Default  string   `yaml:"default,omitempty"`  // Used when no value is given; may use the built-in variables
+	Choices  []string `yaml:"choices,omitempty"`  // Allowed values, if restricted
+	Required bool     `yaml:"required,omitempty"` // The value must not be empty
+}
+
+// Question returns the text asking for the parameter.
+func (p Param) Question() string {
+	if p.Prompt != "" {
+		return p.Prompt
+	}
+	return p.Name
+}
+
+var paramName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ownManifest returns the template.yaml of the pack itself; a pack without one has an empty manifest.
+func (p *Pack) ownManifest() (*Manifest, error) {
+	var m Manifest
+	content, err := iofs.ReadFile(p.files, ManifestFile)
+	if errors.Is(err, iofs.ErrNotExist) {
+		return &m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of template %s: %w", ManifestFile, p.Name, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid %s of template %s: %w", ManifestFile, p.Name, err)
+	}
+	for _, param := range m.Params {
+		if !paramName.MatchString(param.Name) {
+			return nil, fmt.Errorf("invalid %s of template %s: parameter name %q is not an identifier", ManifestFile, p.Name, param.Name)
+		}
+	}
+	for _, file := range m.Files {
+		if err := file.validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s of template %s: %w", ManifestFile, p.Name, err)
+		}
+	}
+	for _, name := range m.Fragments {
+		if !validFragmentName(name) {
+			return nil, fmt.Errorf("invalid %s of template %s: invalid fragment name %q", ManifestFile, p.Name, name)
+		}
+	}
+	return &m, nil
+}
+
+// validFragmentName reports whether name names a directory in the fragments directory.
+func validFragmentName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
+
+// Values are the variables templates are rendered with.
+type Values struct {
+	Atelier string // Directory names, e.g. atelier-demo, artist-picasso, canvas-guernica; empty below the level
+	Artist  string
+	Canvas  string
+	Name    string // Name of the repository without its prefix, e.g. guernica
+	Kind    string // atelier, artist or canvas
+	Author  string // Git user.name
+	Email   string // Git user.email
+	Date    string // Creation date, e.g. 2025-06-01
+	Year    int
+	Params  map[string]string // Template parameters by name
+}
+
+// ValuesFor returns the values for rendering the templates of the repository described by m. The
+// author is read from the git configuration seen from dir.
+func ValuesFor(m *marker.Marker, dir string) Values {
+	author, _ := gitutil.RunGitCommandOutput(dir, "config", "--get", "user.name")
+	email, _ := gitutil.RunGitCommandOutput(dir, "config", "--get", "user.email")
+	params := make(map[string]string, len(m.Params))
+	for k, v := range m.Params {
+		params[k] = v
+	}
+	return Values{
+		Atelier: m.Atelier,
+		Artist:  m.Artist,
+		Canvas:  m.Canvas,
+		Name:    strings.TrimPrefix(m.Name(), m.Kind.Prefix()),
+		Kind:    string(m.Kind),
+		Author:  strings.TrimSpace(author),
+		Email:   strings.TrimSpace(email),
+		Date:    m.CreatedAt.Format("2006-01-02"),
+		Year:    m.CreatedAt.Year(),
+		Params:  params,
+	}
+}
+
+// Prompter asks for the value of a parameter, offering def as the default answer.
+type Prompter func(p Param, def string) (string, error)
+
+// ResolveParams returns the values of the parameters the pack declares: the given value, else the
+// answer of prompt, else the default. prompt may be nil. A given value for a parameter the pack does
+// not declare is an error.
+func (p *Pack) ResolveParams(given map[string]string, v Values, prompt Prompter) (map[string]string, error) {
+	manifest, err := p.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	undeclared, err := Undeclared(given, p)
+	if err != nil {
+		return nil, err
+	}
+	if len(undeclared) > 0 {
+		return nil, fmt.Errorf("template %s has no parameter %s", p.Name, strings.Join(undeclared, ", "))
+	}
+	params := make(map[string]string, len(manifest.Params))
+	for _, param := range manifest.Params {
+		value, ok := given[param.Name]
+		if !ok {
+			def, err := render("default of "+param.Name, param.Default, v)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", p.Name, err)
+			}
+			value = def
+			if prompt != nil {
+				if value, err = prompt(param, def); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(param.Choices) > 0 && !slices.Contains(param.Choices, value) {
+			return nil, fmt.Errorf("template %s: parameter %s must be one of %s, not %q", p.Name, param.Name, strings.Join(param.Choices, ", "), value)
+		}
+		if param.Required && value == "" {
+			return nil, fmt.Errorf("template %s needs a value for parameter %s; pass --set %s=<value>", p.Name, param.Name, param.Name)
+		}
+		params[param.Name] = value
+	}
+	return params, nil
+}
+
+// Undeclared returns the names in given, sorted, of the parameters that none of packs declares.
+func Undeclared(given map[string]string, packs ...*Pack) ([]string, error) {
+	declared := make(map[string]bool)
+	for _, p := range packs {
+		manifest, err := p.Manifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, param := range manifest.Params {
+			declared[param.Name] = true
+		}
+	}
+	var names []string
+	for name := range given {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Render returns the template file name of the pack for a repository of kind, followed by the
+// pack's fragments of the file, rendered with v.
+func (p *Pack) Render(kind marker.Kind, name string, v Values) ([]byte, error) {
+	return p.content(kind, name, true, v)
+}
+
+// render executes the template text named name with v. Parameters the pack does not declare render
+// as empty strings, as files a pack takes from the default template of its kind may refer to the
+// default's parameters.
+func render(name, text string, v Values) (string, error) {
+	t, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, v); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
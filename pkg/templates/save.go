@@ -0,0 +1,177 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/marker"
+	"gopkg.in/yaml.v3"
+)
+
+// SaveOptions controls how Save turns a repository into a template pack.
+type SaveOptions struct {
+	Force    bool // Replace an existing pack of the same name
+	BareName bool // Also replace the repository's name without prefix, e.g. guernica, with {{ .Name }}
+}
+
+// Save stores files, paths relative to dir, as the user's template pack name and returns it. The
+// directory names of the repository described by m and of its parents become template variables:
+// {{ .Canvas }}, {{ .Artist }} and {{ .Atelier }}, and with opts.BareName its name without prefix
+// becomes {{ .Name }}. Actions already in the files are escaped, and binary files are copied as they
+// are. An existing pack of the same name is only replaced with opts.Force.
+func Save(dir string, files []string, m *marker.Marker, name string, opts SaveOptions) (*Pack, error) {
+	if name == "" || name == "." || name == ".." || name == FragmentsDir || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	userDir, err := UserDir()
+	if err != nil {
+		return nil, err
+	}
+	target := filepath.Join(userDir, name)
+	if _, err := os.Stat(target); err == nil && !opts.Force {
+		return nil, fmt.Errorf("template %s already exists in %s; use --force to replace it", name, userDir)
+	}
+	if err := fs.CreateDir(userDir); err != nil {
+		return nil, err
+	}
+	// Build the pack next to its destination and move it there once complete
+	tmp, err := os.MkdirTemp(userDir, "."+name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create template directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	names := newTemplatizer(m, opts.BareName)
+	manifest := Manifest{Description: fmt.Sprintf("Saved from %s", strings.Trim(m.Artist+"/"+m.Name(), "/"))}
+	sort.Strings(files)
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+
+		spec := FileSpec{Src: file}
+		if file == ManifestFile {
+			spec.Src = ManifestFile + ".file"
+		}
+		if dest := names.replace(file); dest != file || spec.Src != file {
+			spec.Dest = dest
+		}
+		if info.Mode().Perm()&0111 != 0 {
+			spec.Mode = "0755"
+		}
+		if binary(content) {
+			render := false
+			spec.Render = &render
+		} else {
+			content = []byte(names.replace(string(content)))
+		}
+		if err := writeFile(filepath.Join(tmp, filepath.FromSlash(spec.Src)), content, 0644); err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, spec)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("%s has no files to save", dir)
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := fs.WriteFile(filepath.Join(tmp, ManifestFile), out.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("could not replace template %s: %w", target, err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return nil, fmt.Errorf("could not save template %s: %w", target, err)
+	}
+	return &Pack{Name: name, Source: SourceUser, Dir: target, files: os.DirFS(target)}, nil
+}
+
+// templatizer turns the literal names of a repository into template variables.
+type templatizer struct {
+	pattern *regexp.Regexp
+	vars    map[string]string
+}
+
+func newTemplatizer(m *marker.Marker, bareName bool) *templatizer {
+	t := &templatizer{vars: map[string]string{"{{": `{{ "{{" }}`}}
+	add := func(literal, variable string) {
+		if _, taken := t.vars[literal]; literal != "" && !taken {
+			t.vars[literal] = variable
+		}
+	}
+	add(m.Canvas, "{{ .Canvas }}")
+	add(m.Artist, "{{ .Artist }}")
+	add(m.Atelier, "{{ .Atelier }}")
+	if bareName {
+		add(strings.TrimPrefix(m.Name(), m.Kind.Prefix()), "{{ .Name }}")
+	}
+
+	// Longer names first, so canvas-guernica is not taken for guernica
+	var literals []string
+	for literal := range t.vars {
+		if literal != "{{" {
+			literals = append(literals, regexp.QuoteMeta(literal))
+		}
+	}
+	sort.Slice(literals, func(i, j int) bool { return len(literals[i]) > len(literals[j]) })
+	t.pattern = regexp.MustCompile(`\{\{|` + strings.Join(literals, "|"))
+	return t
+}
+
+// replace returns text with the names replaced by their variables and its own actions escaped.
+// A name is only replaced where it stands on its own: canvas-guernica-v2 and guernica_test keep
+// their literal text.
+func (t *templatizer) replace(text string) string {
+	var b strings.Builder
+	start := 0
+	for next := 0; next < len(text); {
+		loc := t.pattern.FindStringIndex(text[next:])
+		if loc == nil {
+			break
+		}
+		from, to := next+loc[0], next+loc[1]
+		match := text[from:to]
+		if match != "{{" && (from > 0 && isNameByte(text[from-1]) || to < len(text) && isNameByte(text[to])) {
+			next = from + 1 // Part of a longer name
+			continue
+		}
+		b.WriteString(text[start:from])
+		b.WriteString(t.vars[match])
+		start, next = to, to
+	}
+	b.WriteString(text[start:])
+	return b.String()
+}
+
+// isNameByte reports whether c can be part of a name, so a name next to it is part of a longer one.
+func isNameByte(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+// binary reports whether content looks like a binary file rather than text.
+func binary(content []byte) bool {
+	head := content[:min(len(content), 8000)]
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(content)
+}
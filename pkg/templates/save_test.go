@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+func TestTemplatizer(t *testing.T) {
+	m := marker.New(marker.KindCanvas, "atelier-demo", "artist-picasso", "canvas-guernica")
+	tests := []struct {
+		text     string
+		want     string
+		bareName string // Result with the bare name replaced too; want if empty
+	}{
+		{"# canvas-guernica", "# {{ .Canvas }}", ""},
+		{"cd atelier-demo/artist-picasso", "cd {{ .Atelier }}/{{ .Artist }}", ""},
+		{"run: ${{ secrets.TOKEN }}", `run: ${{ "{{" }} secrets.TOKEN }}`, ""},
+		{"artist-picassos", "artist-picassos", ""},
+		{"canvas-guernica-v2", "canvas-guernica-v2", ""},
+		{"v2-canvas-guernica", "v2-canvas-guernica", ""},
+		{"canvas-guernica_test.go", "canvas-guernica_test.go", ""},
+		{"cmd/canvas-guernica/main.go", "cmd/{{ .Canvas }}/main.go", ""},
+		{"canvas-guernica.md, canvas-guernica", "{{ .Canvas }}.md, {{ .Canvas }}", ""},
+		{"Guernica, a guernica study", "Guernica, a guernica study", "Guernica, a {{ .Name }} study"},
+		{"guernica-v2 and guernicas", "guernica-v2 and guernicas", ""},
+		{"canvas-guernica/guernica.go", "{{ .Canvas }}/guernica.go", "{{ .Canvas }}/{{ .Name }}.go"},
+	}
+	names, bare := newTemplatizer(m, false), newTemplatizer(m, true)
+	for _, tt := range tests {
+		if got := names.replace(tt.text); got != tt.want {
+			t.Errorf("replace(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		want := tt.bareName
+		if want == "" {
+			want = tt.want
+		}
+		if got := bare.replace(tt.text); got != want {
+			t.Errorf("replace(%q) with the bare name = %q, want %q", tt.text, got, want)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	tests := []struct {
+		content []byte
+		want    bool
+	}{
+		{[]byte("plain text\n"), false},
+		{[]byte("caf\xc3\xa9\n"), false},
+		{[]byte{0x89, 'P', 'N', 'G', 0, 0}, true},
+		{[]byte{0xff, 0xfe, 'a'}, true},
+	}
+	for _, tt := range tests {
+		if got := binary(tt.content); got != tt.want {
+			t.Errorf("binary(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
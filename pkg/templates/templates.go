@@ -3,42 +3,67 @@ package templates
 import (
 	"embed"
 	"fmt"
-	"path/filepath"
+	iofs "io/fs"
+	"sort"
+	"strings"
 
-	"github.com/frquxl/go-atelier/pkg/fs"
+	"github.com/frquxl/go-atelier/pkg/marker"
 )
 
-//go:embed assets/*
+//go:embed assets/* fragments/*
 var TemplatesFS embed.FS
 
-// CreateBoilerplate generates standard project files from embedded templates.
-func CreateBoilerplate(basePath, projectType string) error {
-	templateDir := fmt.Sprintf("assets/%s", projectType)
+// Version identifies the revision of the embedded templates. It is recorded in marker files
+// so generated files can later be compared against the templates they came from.
+const Version = "5"
 
-	// Define files to be copied and potentially renamed
-	files := map[string]string{
-		"README.md":    "README.md",
-		"AGENTS.md":    "AGENTS.md",
-		"Makefile":     "Makefile",
-		"gitignore":    ".gitignore",
-		"geminiignore": ".geminiignore",
-	}
+// Exists reports whether an embedded template of the given type exists.
+func Exists(projectType string) bool {
+	info, err := iofs.Stat(TemplatesFS, fmt.Sprintf("assets/%s", projectType))
+	return err == nil && info.IsDir()
+}
 
-	for src, dest := range files {
-		path := fmt.Sprintf("%s/%s", templateDir, src)
-		content, err := TemplatesFS.ReadFile(path)
+// MissingAssets returns the embedded files that templates need but this build does not contain,
+// e.g. because an asset was not present when the binary was built.
+func MissingAssets() []string {
+	var missing []string
+	entries, err := TemplatesFS.ReadDir("assets")
+	if err != nil {
+		return []string{"assets"}
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pack := embedded(entry.Name())
+		manifest, err := pack.Manifest()
 		if err != nil {
-			// Some project types might not have all files (e.g., no Makefile), so we can ignore not found errors
-			// if os.IsNotExist(err) {
-			// 	continue
-			// }
-			return fmt.Errorf("failed to read embedded template %s: %w", path, err)
+			missing = append(missing, fmt.Sprintf("assets/%s/%s", entry.Name(), ManifestFile))
+			continue
 		}
-
-		if err := fs.WriteFile(filepath.Join(basePath, dest), content); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", dest, err)
+		for _, file := range manifest.files() {
+			if file.Optional {
+				continue
+			}
+			if _, ok := pack.dirOwner(file.Src); ok {
+				continue
+			}
+			if _, err := pack.content(KindOf(entry.Name()), file.Src, false, Values{}); err != nil {
+				missing = append(missing, fmt.Sprintf("assets/%s/%s", entry.Name(), file.Src))
+			}
 		}
 	}
+	sort.Strings(missing)
+	return missing
+}
 
-	return nil
+// KindOf returns the kind of repository the template named name is meant for, by its prefix: an
+// atelier for atelier or atelier-*, an artist for artist-*, and a canvas otherwise.
+func KindOf(name string) marker.Kind {
+	for _, kind := range []marker.Kind{marker.KindAtelier, marker.KindArtist} {
+		if name == string(kind) || strings.HasPrefix(name, kind.Prefix()) {
+			return kind
+		}
+	}
+	return marker.KindCanvas
 }
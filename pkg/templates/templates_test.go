@@ -0,0 +1,327 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// atelierWithPacks returns an atelier directory whose templates directory holds the given files,
+// by path below it, and points the user's template directory at an empty directory.
+func atelierWithPacks(t *testing.T, files map[string]string) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	atelierPath := t.TempDir()
+	for path, content := range files {
+		path = filepath.Join(atelierPath, AtelierDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return atelierPath
+}
+
+func canvasValues() Values {
+	return Values{Atelier: "atelier-demo", Artist: "artist-picasso", Canvas: "canvas-guernica", Name: "guernica", Kind: "canvas"}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := map[string]marker.Kind{
+		"atelier":           marker.KindAtelier,
+		"atelier-team":      marker.KindAtelier,
+		"artist-default":    marker.KindArtist,
+		"canvas":            marker.KindCanvas,
+		"canvas-sunflowers": marker.KindCanvas,
+		"python-service":    marker.KindCanvas,
+	}
+	for name, want := range tests {
+		if got := KindOf(name); got != want {
+			t.Errorf("KindOf(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	atelierPath := atelierWithPacks(t, map[string]string{"canvas/README.md": "# ours\n"})
+	tests := []struct {
+		name   string
+		source string
+		err    bool
+	}{
+		{"canvas", SourceAtelier, false},
+		{"artist-default", SourceEmbedded, false},
+		{"missing", "", true},
+		{"../canvas", "", true},
+		{FragmentsDir, "", true},
+	}
+	for _, tt := range tests {
+		pack, err := Find(atelierPath, tt.name)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Find(%q) succeeded, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Find(%q): %v", tt.name, err)
+		}
+		if pack.Source != tt.source {
+			t.Errorf("Find(%q) from %s, want %s", tt.name, pack.Source, tt.source)
+		}
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	atelierPath := atelierWithPacks(t, map[string]string{
+		"service/template.yaml": `params:
+  - name: module
+    default: example.com/{{ .Name }}
+  - name: license
+    choices: [MIT, Apache-2.0]
+    default: MIT
+  - name: owner
+    required: true
+    default: "{{ .Author }}"
+`,
+	})
+	pack, err := Find(atelierPath, "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		given  map[string]string
+		prompt Prompter
+		want   map[string]string
+		err    string
+	}{
+		{
+			name:  "defaults",
+			given: map[string]string{"owner": "ada"},
+			want:  map[string]string{"module": "example.com/guernica", "license": "MIT", "owner": "ada"},
+		},
+		{
+			name:  "given values",
+			given: map[string]string{"module": "m", "license": "Apache-2.0", "owner": "ada"},
+			want:  map[string]string{"module": "m", "license": "Apache-2.0", "owner": "ada"},
+		},
+		{
+			name:   "prompted values",
+			given:  map[string]string{"owner": "ada"},
+			prompt: func(p Param, def string) (string, error) { return strings.ToUpper(def), nil },
+			want:   map[string]string{"module": "EXAMPLE.COM/GUERNICA", "license": "MIT", "owner": "ada"},
+		},
+		{
+			name:  "value outside the choices",
+			given: map[string]string{"license": "GPL", "owner": "ada"},
+			err:   "must be one of MIT, Apache-2.0",
+		},
+		{
+			name: "required value missing",
+			err:  "needs a value for parameter owner",
+		},
+		{
+			name:  "undeclared parameters",
+			given: map[string]string{"owner": "ada", "licence": "MIT", "language": "go"},
+			err:   "template service has no parameter language, licence",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pack.ResolveParams(tt.given, canvasValues(), tt.prompt)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ResolveParams = %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveParams: %v", err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ResolveParams = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUndeclared(t *testing.T) {
+	atelierPath := atelierWithPacks(t, map[string]string{
+		"go-service/template.yaml": "params:\n  - name: module\n",
+		"web/template.yaml":        "params:\n  - name: port\n",
+	})
+	var packs []*Pack
+	for _, name := range []string{"go-service", "web"} {
+		pack, err := Find(atelierPath, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packs = append(packs, pack)
+	}
+	given := map[string]string{"module": "m", "port": "80", "prot": "80", "language": "go"}
+	undeclared, err := Undeclared(given, packs...)
+	if err != nil || !slices.Equal(undeclared, []string{"language", "prot"}) {
+		t.Errorf("Undeclared = %v, %v; want [language prot]", undeclared, err)
+	}
+	if undeclared, _ := Undeclared(given, packs[0]); !slices.Equal(undeclared, []string{"language", "port", "prot"}) {
+		t.Errorf("Undeclared by go-service = %v", undeclared)
+	}
+}
+
+func TestRenderUndeclaredParam(t *testing.T) {
+	v := canvasValues()
+	tests := []struct {
+		params map[string]string
+		text   string
+		want   string
+	}{
+		{map[string]string{"module": "m"}, "[{{ .Params.module }}]", "[m]"},
+		{map[string]string{"module": "m"}, "[{{ .Params.licence }}]", "[]"},
+		{nil, `[{{ if eq .Params.language "go" }}go{{ end }}]`, "[]"},
+	}
+	for _, tt := range tests {
+		v.Params = tt.params
+		if got, err := render("README.md", tt.text, v); err != nil || got != tt.want {
+			t.Errorf("render(%q) with %v = %q, %v; want %q", tt.text, tt.params, got, err, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	atelierPath := atelierWithPacks(t, map[string]string{
+		"base/template.yaml": `files:
+  - src: README.md
+  - src: run.sh
+    dest: bin/{{ .Name }}.sh
+    mode: "0755"
+  - src: gitignore
+    dest: .gitignore
+  - src: missing.txt
+    optional: true
+post:
+  - echo {{ .Canvas }}
+`,
+		"base/README.md":           "# {{ .Name }} of {{ .Artist }}\n",
+		"base/run.sh":              "#!/bin/sh\n",
+		"base/gitignore":           "*.log\n",
+		"web/template.yaml":        "extends: base\nfragments: [node]\n",
+		"web/README.md":            "# {{ .Name }} on the web\n",
+		"fragments/node/gitignore": "node_modules/\n",
+		"fragments/node/README.md": "Run npm install.\n",
+	})
+	pack, err := Find(atelierPath, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := pack.Generate(marker.KindCanvas, canvasValues())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := []Output{
+		{Path: "README.md", Content: []byte("# guernica on the web\n\nRun npm install.\n"), Mode: 0644},
+		{Path: "bin/guernica.sh", Content: []byte("#!/bin/sh\n"), Mode: 0755},
+		{Path: ".gitignore", Content: []byte("*.log\n\nnode_modules/\n"), Mode: 0644},
+	}
+	if len(outputs) != len(want) {
+		t.Fatalf("Generate returned %d files, want %d: %v", len(outputs), len(want), outputs)
+	}
+	for i, out := range outputs {
+		if out.Path != want[i].Path || string(out.Content) != string(want[i].Content) || out.Mode != want[i].Mode {
+			t.Errorf("file %d = %s %04o %q, want %s %04o %q", i, out.Path, out.Mode, out.Content, want[i].Path, want[i].Mode, want[i].Content)
+		}
+	}
+
+	commands, err := pack.PostCommands(canvasValues())
+	if err != nil || !slices.Equal(commands, []string{"echo canvas-guernica"}) {
+		t.Errorf("PostCommands = %v, %v", commands, err)
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":    "colour: blue\n",
+		"bad param name":   "params:\n  - name: my-param\n",
+		"bad mode":         "files:\n  - src: a\n    mode: rwx\n",
+		"escaping file":    "files:\n  - src: ../a\n",
+		"bad fragment":     "fragments: [../go]\n",
+		"extends itself":   "extends: pack\n",
+		"unknown base":     "extends: missing\n",
+		"unknown fragment": "fragments: [cobol]\n",
+	}
+	for name, manifest := range tests {
+		t.Run(name, func(t *testing.T) {
+			atelierPath := atelierWithPacks(t, map[string]string{"pack/template.yaml": manifest})
+			pack, err := Find(atelierPath, "pack")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pack.Generate(marker.KindCanvas, canvasValues()); err == nil {
+				t.Errorf("Generate succeeded with the manifest %q", manifest)
+			}
+		})
+	}
+}
+
+func TestCanvasLanguage(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pack, err := Find("", "canvas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	makefile := func(language string) string {
+		v := canvasValues()
+		v.Params = map[string]string{"language": language}
+		content, err := pack.Render(marker.KindCanvas, "Makefile", v)
+		if err != nil {
+			t.Fatalf("Render(Makefile) with language %s: %v", language, err)
+		}
+		return string(content)
+	}
+	if got := makefile("go"); strings.Count(got, "\nbuild:") != 1 || !strings.Contains(got, "\tgo build ./...") {
+		t.Errorf("Makefile for go has no single build target running go build:\n%s", got)
+	}
+	if got := makefile("none"); strings.Count(got, "\nbuild:") != 1 || strings.Contains(got, "# Go toolchain") {
+		t.Errorf("Makefile without a language has no single generic build target:\n%s", got)
+	}
+}
+
+func TestFragmentNames(t *testing.T) {
+	v := canvasValues()
+	v.Params = map[string]string{"language": "go"}
+	got, err := fragmentNames([]string{"common", "{{ .Params.language }}", "{{ .Params.missing }}", "go"}, v)
+	if err != nil || !slices.Equal(got, []string{"common", "go"}) {
+		t.Errorf("fragmentNames = %v, %v; want [common go]", got, err)
+	}
+	v.Params["language"] = "../go"
+	if _, err := fragmentNames([]string{"{{ .Params.language }}"}, v); err == nil {
+		t.Error("fragmentNames accepted a name rendering to ../go")
+	}
+}
+
+func TestEmbeddedPacks(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if missing := MissingAssets(); len(missing) > 0 {
+		t.Errorf("MissingAssets = %v", missing)
+	}
+	for _, name := range []string{"atelier", "artist-default", "artist-gallery", "artist-sketch", "canvas", "canvas-sunflowers"} {
+		pack, err := Find("", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pack.Generate(KindOf(name), canvasValues()); err != nil {
+			t.Errorf("Generate(%s): %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package traverse runs work on the repositories of an atelier hierarchy with a pool of workers.
+package traverse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// DefaultJobs is the number of workers used when none is configured.
+var DefaultJobs = runtime.NumCPU()
+
+// Repo is a repository in the atelier/artist/canvas hierarchy.
+type Repo struct {
+	Path     string
+	Level    marker.Kind
+	Parent   *Repo
+	Children []*Repo
+}
+
+// Name returns the directory name of the repository, e.g. canvas-guernica.
+func (r *Repo) Name() string {
+	return filepath.Base(r.Path)
+}
+
+// Order determines when a repository may start and the order its output is written in.
+type Order int
+
+const (
+	// TopDown starts repositories independently of each other; output follows the tree from the top down.
+	TopDown Order = iota
+	// ChildrenFirst starts a repository only once all of its children have finished, so canvases are done
+	// before their artist records the new pointers. Output follows the tree from the bottom up.
+	ChildrenFirst
+)
+
+// Options controls a Run.
+type Options struct {
+	Jobs  int       // Number of repositories processed at once; 1 runs them one after another
+	Order Order     // Dependency order between parents and children
+	Out   io.Writer // Receives each repository's output as one block, in tree order; discarded if nil
+}
+
+// Func does the work for one repository. Everything it writes to out is kept together in the output.
+type Func func(repo *Repo, out io.Writer) error
+
+// Result is the outcome of a Func for one repository.
+type Result struct {
+	Repo *Repo
+	Err  error
+}
+
+// Discover builds the hierarchy below dir, which must hold an atelier, artist or canvas marker.
+func Discover(dir string) (*Repo, error) {
+	kind, ok := marker.Detect(dir)
+	if !ok {
+		return nil, fmt.Errorf("unable to detect atelier/artist/canvas level in %s", dir)
+	}
+	return Build(dir, kind)
+}
+
+// Build builds the hierarchy below dir, which is a repository of the given level.
+func Build(dir string, level marker.Kind) (*Repo, error) {
+	root := &Repo{Path: dir, Level: level}
+	return root, discoverChildren(root)
+}
+
+func discoverChildren(repo *Repo) error {
+	var kind marker.Kind
+	switch repo.Level {
+	case marker.KindAtelier:
+		kind = marker.KindArtist
+	case marker.KindArtist:
+		kind = marker.KindCanvas
+	default:
+		return nil
+	}
+	dirs, err := marker.ChildDirs(repo.Path, kind)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		child := &Repo{Path: dir, Level: kind, Parent: repo}
+		if err := discoverChildren(child); err != nil {
+			return err
+		}
+		repo.Children = append(repo.Children, child)
+	}
+	return nil
+}
+
+// Walk lists root and the repositories below it: parents before children for TopDown, children
+// before parents for ChildrenFirst. Siblings keep their name order.
+func Walk(root *Repo, order Order) []*Repo {
+	var repos []*Repo
+	var visit func(*Repo)
+	visit = func(repo *Repo) {
+		if order == TopDown {
+			repos = append(repos, repo)
+		}
+		for _, child := range repo.Children {
+			visit(child)
+		}
+		if order == ChildrenFirst {
+			repos = append(repos, repo)
+		}
+	}
+	visit(root)
+	return repos
+}
+
+// Run calls fn for root and every repository below it, with up to opts.Jobs calls at a time. Output is
+// buffered per repository and written as soon as the repositories before it in Walk order are done.
+// A failing repository does not stop the others. Results are returned in Walk order.
+func Run(root *Repo, opts Options, fn Func) []Result {
+	repos := Walk(root, opts.Order)
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	results := make([]Result, len(repos))
+
+	if opts.Jobs <= 1 {
+		for i, repo := range repos {
+			results[i] = Result{Repo: repo, Err: fn(repo, out)}
+		}
+		return results
+	}
+
+	index := make(map[*Repo]int, len(repos))
+	done := make([]chan struct{}, len(repos))
+	buffers := make([]bytes.Buffer, len(repos))
+	for i, repo := range repos {
+		index[repo] = i
+		done[i] = make(chan struct{})
+	}
+
+	workers := make(chan struct{}, opts.Jobs)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+			if opts.Order == ChildrenFirst {
+				for _, child := range repo.Children {
+					<-done[index[child]]
+				}
+			}
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			results[i] = Result{Repo: repo, Err: fn(repo, &buffers[i])}
+		}()
+	}
+
+	for i := range repos {
+		<-done[i]
+		out.Write(buffers[i].Bytes())
+	}
+	wg.Wait()
+	return results
+}
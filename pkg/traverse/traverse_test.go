@@ -0,0 +1,105 @@
+package traverse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/frquxl/go-atelier/pkg/marker"
+)
+
+// tree creates an atelier with two artists of two canvases each and returns its hierarchy.
+func tree(t *testing.T) *Repo {
+	t.Helper()
+	dir := t.TempDir()
+	for _, path := range []string{
+		"artist-a/canvas-1", "artist-a/canvas-2", "artist-b/canvas-3", "artist-b/canvas-4", "artist-b/notes",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := Build(dir, marker.KindAtelier)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return root
+}
+
+func names(repos []*Repo) []string {
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.Name())
+	}
+	return names
+}
+
+func TestWalk(t *testing.T) {
+	root := tree(t)
+	atelier := root.Name()
+	tests := []struct {
+		order Order
+		want  []string
+	}{
+		{TopDown, []string{atelier, "artist-a", "canvas-1", "canvas-2", "artist-b", "canvas-3", "canvas-4"}},
+		{ChildrenFirst, []string{"canvas-1", "canvas-2", "artist-a", "canvas-3", "canvas-4", "artist-b", atelier}},
+	}
+	for _, tt := range tests {
+		if got := names(Walk(root, tt.order)); !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(%d) = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := tree(t)
+	for _, jobs := range []int{1, 4} {
+		for _, order := range []Order{TopDown, ChildrenFirst} {
+			t.Run(fmt.Sprintf("jobs=%d order=%d", jobs, order), func(t *testing.T) {
+				var mu sync.Mutex
+				finished := map[*Repo]bool{}
+				var out strings.Builder
+				results := Run(root, Options{Jobs: jobs, Order: order, Out: &out}, func(repo *Repo, w io.Writer) error {
+					mu.Lock()
+					defer mu.Unlock()
+					if order == ChildrenFirst {
+						for _, child := range repo.Children {
+							if !finished[child] {
+								t.Errorf("%s started before its child %s finished", repo.Name(), child.Name())
+							}
+						}
+					}
+					finished[repo] = true
+					fmt.Fprintf(w, "%s\n", repo.Name())
+					if repo.Name() == "canvas-3" {
+						return errors.New("failed")
+					}
+					return nil
+				})
+
+				want := names(Walk(root, order))
+				var repos []*Repo
+				for _, r := range results {
+					repos = append(repos, r.Repo)
+				}
+				if got := names(repos); !slices.Equal(got, want) {
+					t.Errorf("results in order %v, want %v", got, want)
+				}
+				if got := strings.Fields(out.String()); !slices.Equal(got, want) {
+					t.Errorf("output in order %v, want %v", got, want)
+				}
+				for _, r := range results {
+					if (r.Err != nil) != (r.Repo.Name() == "canvas-3") {
+						t.Errorf("%s: error %v", r.Repo.Name(), r.Err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -2,25 +2,40 @@ package util
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+
+	"golang.org/x/term"
 )
 
-// Confirm prompts the user with a message and waits for a 'yes' or 'no' answer.
-// It returns true if the user confirms, false otherwise.
-func Confirm(message string) bool {
-	reader := bufio.NewReader(os.Stdin)
+// stdin is shared by the prompts, so input buffered by one is not lost to the next.
+var stdin = bufio.NewReader(os.Stdin)
+
+// IsInteractive reports whether the standard input is a terminal a user can answer prompts on.
+// Character devices such as /dev/null are not terminals.
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
 
+// Confirm prompts the user with a message and waits for a 'yes' or 'no' answer, which may come
+// from a terminal or a pipe. It returns true if the user confirms, false otherwise, and io.EOF if
+// the input ends before the user answered.
+func Confirm(message string) (bool, error) {
 	for {
 		fmt.Printf("%s [yes/no]: ", message)
-		input, _ := reader.ReadString('\n')
+		input, err := stdin.ReadString('\n')
 		input = strings.ToLower(strings.TrimSpace(input))
 
 		if input == "yes" || input == "y" {
-			return true
+			return true, nil
 		} else if input == "no" || input == "n" {
-			return false
+			return false, nil
+		} else if err != nil {
+			fmt.Println()
+			return false, err
 		} else {
 			fmt.Println("Please answer 'yes' or 'no'.")
 		}
@@ -28,9 +43,16 @@ func Confirm(message string) bool {
 }
 
 // Prompt prompts the user with a message and returns their input as a string.
-func Prompt(message string) string {
-	reader := bufio.NewReader(os.Stdin)
+// It returns io.EOF if the input ends before the user answered.
+func Prompt(message string) (string, error) {
 	fmt.Printf("%s: ", message)
-	input, _ := reader.ReadString('\n')
-	return strings.TrimSpace(input)
+	input, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) && input != "" {
+		err = nil // Last line without a newline
+	}
+	if err != nil {
+		fmt.Println()
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
 }
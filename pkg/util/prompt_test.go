@@ -0,0 +1,60 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPrompt(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string // Answers until io.EOF
+	}{
+		{"", nil},
+		{"guernica\n", []string{"guernica"}},
+		{" guernica \nmural", []string{"guernica", "mural"}},
+		{"\n", []string{""}},
+	}
+	for _, tt := range tests {
+		stdin = bufio.NewReader(strings.NewReader(tt.input))
+		var got []string
+		for {
+			answer, err := Prompt("Name")
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Prompt: %v", err)
+			}
+			got = append(got, answer)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("Prompt answers for %q = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+		err   error
+	}{
+		{"yes\n", true, nil},
+		{"maybe\nY\n", true, nil},
+		{"n\n", false, nil},
+		{"yes", true, nil},
+		{"", false, io.EOF},
+		{"maybe", false, io.EOF},
+		{"maybe\nmaybe", false, io.EOF},
+	}
+	for _, tt := range tests {
+		stdin = bufio.NewReader(strings.NewReader(tt.input))
+		if got, err := Confirm("Delete"); got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Confirm with input %q = %v, %v; want %v, %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}